@@ -0,0 +1,127 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"os"
+	"strings"
+	"time"
+
+	"Base_node/pki"
+	"Base_node/utils"
+)
+
+const caUsage = `Usage:
+  base ca init  [--dir DIR] [--name NAME]
+  base ca issue [--dir DIR] --name NAME --role base|super|peer --out PREFIX [--pubkey FILE] [--ip IPS] [--dns NAMES] [--days N]
+
+init creates the network CA. issue writes PREFIX.crt signed by the CA. With
+--pubkey (a node's .keys/public.key) the certificate binds that identity key;
+otherwise a new key is generated and written to PREFIX.key.`
+
+// runCA implements the "ca" subcommand that manages the network CA used for
+// mutual TLS between all nodes.
+func runCA(args []string) {
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, caUsage)
+		os.Exit(2)
+	}
+
+	switch args[0] {
+	case "init":
+		fs := flag.NewFlagSet("ca init", flag.ExitOnError)
+		dir := fs.String("dir", ".keys/ca", "Directory for the CA certificate and key")
+		name := fs.String("name", "dvpn network CA", "CA common name")
+		fs.Parse(args[1:])
+
+		if err := pki.InitCA(*dir, *name); err != nil {
+			log.Fatalf("❌ Failed to create CA: %v", err)
+		}
+		log.Printf("🔏 Network CA created in %s", *dir)
+
+	case "issue":
+		fs := flag.NewFlagSet("ca issue", flag.ExitOnError)
+		dir := fs.String("dir", ".keys/ca", "Directory of the CA certificate and key")
+		name := fs.String("name", "", "Certificate common name, e.g. the node ID")
+		role := fs.String("role", "", "Node role: base, super or peer")
+		out := fs.String("out", "", "Output path prefix for PREFIX.crt (and PREFIX.key)")
+		pubKeyPath := fs.String("pubkey", "", "File with the node's base64 ed25519 public key (optional)")
+		ips := fs.String("ip", "", "Comma-separated IP addresses to include")
+		dns := fs.String("dns", "", "Comma-separated DNS names to include")
+		days := fs.Int("days", 365, "Certificate validity in days")
+		fs.Parse(args[1:])
+
+		if *name == "" || *out == "" || (*role != utils.RoleBase && *role != utils.RoleSuper && *role != utils.RolePeer) {
+			fmt.Fprintln(os.Stderr, caUsage)
+			os.Exit(2)
+		}
+
+		req := pki.IssueRequest{
+			Name:     *name,
+			Role:     *role,
+			DNSNames: splitList(*dns),
+			Validity: time.Duration(*days) * 24 * time.Hour,
+		}
+		for _, s := range splitList(*ips) {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				log.Fatalf("❌ Invalid IP address %q", s)
+			}
+			req.IPs = append(req.IPs, ip)
+		}
+		if *pubKeyPath != "" {
+			pub, err := readPublicKey(*pubKeyPath)
+			if err != nil {
+				log.Fatalf("❌ Failed to read public key: %v", err)
+			}
+			req.PublicKey = pub
+		}
+
+		der, key, err := pki.Issue(*dir, req)
+		if err != nil {
+			log.Fatalf("❌ Failed to issue certificate: %v", err)
+		}
+		if err := pki.WriteCert(*out+".crt", der); err != nil {
+			log.Fatalf("❌ Failed to write certificate: %v", err)
+		}
+		if key != nil {
+			if err := pki.WriteKey(*out+".key", key); err != nil {
+				log.Fatalf("❌ Failed to write key: %v", err)
+			}
+		}
+		log.Printf("📜 Issued certificate for %s to %s.crt", *name, *out)
+
+	default:
+		fmt.Fprintln(os.Stderr, caUsage)
+		os.Exit(2)
+	}
+}
+
+func readPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, err
+	}
+	if len(pub) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("expected %d byte ed25519 key, got %d", ed25519.PublicKeySize, len(pub))
+	}
+	return pub, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, part := range strings.Split(s, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			out = append(out, part)
+		}
+	}
+	return out
+}
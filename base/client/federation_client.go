@@ -2,14 +2,15 @@ package client
 
 import (
 	"Base_node/pb"
+	"Base_node/utils"
 	"context"
 	"time"
 
 	"google.golang.org/grpc"
 )
 
-func FetchRemoteSupers(addr string, region string, count int32, minBW float32, maxLatency float32) ([]*pb.SuperNodeInfo, error) {
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+func FetchRemoteSupers(addr string, req *pb.RemoteSuperRequest) (*pb.RemoteSuperResponse, error) {
+	conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleBase))
 	if err != nil {
 		return nil, err
 	}
@@ -20,15 +21,21 @@ func FetchRemoteSupers(addr string, region string, count int32, minBW float32, m
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	resp, err := client.RequestRemoteSuperNodes(ctx, &pb.RemoteSuperRequest{
-		TargetRegion:          region,
-		Count:                 count,
-		RequiredBandWidthMbps: minBW,
-		MaxLatencyMs:          maxLatency,
-	})
+	return client.RequestRemoteSuperNodes(ctx, req)
+}
+
+// Gossip exchanges membership digests with the base node at addr.
+func Gossip(addr string, msg *pb.GossipMessage) (*pb.GossipMessage, error) {
+	conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleBase))
 	if err != nil {
 		return nil, err
 	}
+	defer conn.Close()
+
+	client := pb.NewBaseFederationServiceClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
 
-	return resp.SuperNodes, nil
+	return client.Gossip(ctx, msg)
 }
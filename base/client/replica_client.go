@@ -0,0 +1,96 @@
+package client
+
+import (
+	"Base_node/pb"
+	"Base_node/utils"
+	"context"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// LeaderClient forwards writes to the replication leader of the region over
+// one connection, which it replaces when the leader changes.
+type LeaderClient struct {
+	mu   sync.Mutex
+	addr string
+	conn *grpc.ClientConn
+}
+
+func NewLeaderClient() *LeaderClient {
+	return &LeaderClient{}
+}
+
+// client returns a client of the leader at addr, dialing it if the cached
+// connection is to another address.
+func (c *LeaderClient) client(addr string) (pb.BaseNodeServiceClient, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil || c.addr != addr {
+		conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleBase))
+		if err != nil {
+			return nil, err
+		}
+		if c.conn != nil {
+			c.conn.Close()
+		}
+		c.addr, c.conn = addr, conn
+	}
+	return pb.NewBaseNodeServiceClient(c.conn), nil
+}
+
+// Close closes the cached connection. A later call dials again.
+func (c *LeaderClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.addr, c.conn = "", nil
+	return err
+}
+
+// ForwardRegister relays a super node registration to the replication
+// leader at addr.
+func (c *LeaderClient) ForwardRegister(ctx context.Context, addr string, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
+	client, err := c.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return client.RegisterSuperNode(ctx, req)
+}
+
+// ForwardHeartbeat relays a super node heartbeat to the replication leader
+// at addr.
+func (c *LeaderClient) ForwardHeartbeat(ctx context.Context, addr string, req *pb.HeartbeatRequest) (*pb.Ack, error) {
+	client, err := c.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return client.SuperNodeHeartbeat(ctx, req)
+}
+
+// ForwardPeerIdentity relays a peer identity registration to the
+// replication leader at addr.
+func (c *LeaderClient) ForwardPeerIdentity(ctx context.Context, addr string, req *pb.PeerIdentity) (*pb.Ack, error) {
+	client, err := c.client(addr)
+	if err != nil {
+		return nil, err
+	}
+	return client.RegisterPeerIdentity(ctx, req)
+}
+
+// ForwardPeerIdentity relays a peer identity registration to the base at
+// addr, which owns the peer's region, over a connection of its own.
+func ForwardPeerIdentity(ctx context.Context, addr string, req *pb.PeerIdentity) (*pb.Ack, error) {
+	conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleBase))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	return pb.NewBaseNodeServiceClient(conn).RegisterPeerIdentity(ctx, req)
+}
@@ -0,0 +1,40 @@
+package client
+
+import (
+	"testing"
+
+	"google.golang.org/grpc/connectivity"
+)
+
+func TestLeaderClientKeepsOneConnectionPerLeader(t *testing.T) {
+	c := NewLeaderClient()
+	defer c.Close()
+
+	// Dialing is lazy, so no server has to listen at these addresses
+	if _, err := c.client("127.0.0.1:1"); err != nil {
+		t.Fatal(err)
+	}
+	first := c.conn
+	if _, err := c.client("127.0.0.1:1"); err != nil {
+		t.Fatal(err)
+	}
+	if c.conn != first {
+		t.Fatal("second write to the same leader dialed again")
+	}
+
+	// A new leader replaces the connection to the old one
+	if _, err := c.client("127.0.0.1:2"); err != nil {
+		t.Fatal(err)
+	}
+	if c.conn == first || c.addr != "127.0.0.1:2" {
+		t.Fatalf("still connected to %s after the leader changed", c.addr)
+	}
+	if state := first.GetState(); state != connectivity.Shutdown {
+		t.Fatalf("connection to the old leader is %s, want it closed", state)
+	}
+
+	c.Close()
+	if c.conn != nil {
+		t.Fatal("connection kept after Close")
+	}
+}
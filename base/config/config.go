@@ -0,0 +1,111 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// envPrefix starts every environment override, e.g. DVPN_BASE_REGION.
+const envPrefix = "DVPN_BASE"
+
+// Config holds every setting of a base node.
+type Config struct {
+	Region      string            `yaml:"region"`
+	Port        string            `yaml:"port"`
+	ID          string            `yaml:"id"`
+	Federation  FederationConfig  `yaml:"federation"`
+	Replication ReplicationConfig `yaml:"replication"`
+	Storage     StorageConfig     `yaml:"storage"`
+	GeoIP       GeoIPConfig       `yaml:"geoip"`
+	Security    SecurityConfig    `yaml:"security"`
+	TLS         TLSConfig         `yaml:"tls"`
+}
+
+type FederationConfig struct {
+	Seeds          List   `yaml:"seeds"`
+	Key            string `yaml:"key"`
+	TrustedRegions string `yaml:"trusted_regions"`
+	// Insecure accepts federation messages without verifying them.
+	Insecure bool `yaml:"insecure"`
+}
+
+type ReplicationConfig struct {
+	Replicas List `yaml:"replicas"`
+}
+
+type StorageConfig struct {
+	DataDir  string   `yaml:"data_dir"`
+	StaleTTL Duration `yaml:"stale_ttl"`
+	DeadTTL  Duration `yaml:"dead_ttl"`
+}
+
+type GeoIPConfig struct {
+	DB              string `yaml:"db"`
+	RegionOverrides string `yaml:"region_overrides"`
+}
+
+type SecurityConfig struct {
+	SuperAllowlist string `yaml:"super_allowlist"`
+}
+
+type TLSConfig struct {
+	CA       string `yaml:"ca"`
+	Cert     string `yaml:"cert"`
+	Key      string `yaml:"key"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Port: "50051",
+		Federation: FederationConfig{
+			Key: ".keys/federation.key",
+		},
+		Storage: StorageConfig{
+			DataDir:  ".data",
+			StaleTTL: Duration(2 * time.Minute),
+			DeadTTL:  Duration(10 * time.Minute),
+		},
+		TLS: TLSConfig{
+			CA:   ".keys/ca.crt",
+			Cert: ".keys/node.crt",
+			Key:  ".keys/node.key",
+		},
+	}
+}
+
+// Validate reports the first setting that cannot work.
+func (c *Config) Validate() error {
+	if c.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	if err := validPort(c.Port); err != nil {
+		return fmt.Errorf("port: %w", err)
+	}
+	if c.Storage.StaleTTL <= 0 || c.Storage.DeadTTL <= c.Storage.StaleTTL {
+		return fmt.Errorf("storage: need 0 < stale_ttl < dead_ttl, got %s and %s", c.Storage.StaleTTL, c.Storage.DeadTTL)
+	}
+	if len(c.Federation.Seeds) == 0 {
+		return fmt.Errorf("federation.seeds is required (list this base's own address to run it alone)")
+	}
+	if c.Federation.Key == "" {
+		return fmt.Errorf("federation.key is required")
+	}
+	if !c.Federation.Insecure && c.Federation.TrustedRegions == "" {
+		return fmt.Errorf("federation.trusted_regions is required unless federation.insecure is set")
+	}
+	if !c.TLS.Insecure && (c.TLS.CA == "" || c.TLS.Cert == "" || c.TLS.Key == "") {
+		return fmt.Errorf("tls: ca, cert and key are required unless insecure is set")
+	}
+	return nil
+}
+
+func validPort(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q is not a valid port", s)
+	}
+	return nil
+}
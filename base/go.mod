@@ -5,8 +5,11 @@ go 1.23.0
 toolchain go1.23.10
 
 require (
+	Dvpn_common v0.0.0
+	github.com/oschwald/maxminddb-golang v1.13.1
 	google.golang.org/grpc v1.73.0
 	google.golang.org/protobuf v1.36.6
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -15,3 +18,5 @@ require (
 	golang.org/x/text v0.23.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20250324211829-b45e905df463 // indirect
 )
+
+replace Dvpn_common => ../common
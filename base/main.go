@@ -5,7 +5,12 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"Base_node/config"
 	pb "Base_node/pb"
 	"Base_node/server"
 	"Base_node/utils"
@@ -13,30 +18,233 @@ import (
 	"google.golang.org/grpc"
 )
 
+// shutdownTimeout is how long RPCs in progress may take to finish once a
+// shutdown signal arrives.
+const shutdownTimeout = 10 * time.Second
+
 func main() {
-	region := flag.String("region", "", "Region of the base node")
-	port := flag.String("port", "50051", "Port for Base Node Server")
-	// peerBaseIP := flag.String("base-ip", "", "Optional IP:port of another base node to ping")
+	if len(os.Args) > 1 && os.Args[1] == "ca" {
+		runCA(os.Args[2:])
+		return
+	}
+
+	cfg := config.Default()
+	configPath := flag.String("config", "", "Path to a YAML config file")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration and exit")
+	flag.StringVar(&cfg.Region, "region", cfg.Region, "Region of the base node")
+	flag.StringVar(&cfg.Port, "port", cfg.Port, "Port for Base Node Server")
+	flag.Var(&cfg.Federation.Seeds, "seeds", "Comma-separated IP:port list of federation seed base nodes")
+	flag.Var(&cfg.Replication.Replicas, "replicas", "Comma-separated IP:port list of every base replica in this region, including this one (empty runs a single base)")
+	flag.StringVar(&cfg.ID, "id", cfg.ID, "Federation member ID (optional, derived from region and address)")
+	flag.StringVar(&cfg.Storage.DataDir, "data-dir", cfg.Storage.DataDir, "Directory for the persistent super node registry (empty keeps it in memory)")
+	exportPath := flag.String("export-snapshot", "", "Write a registry snapshot to this file and exit")
+	importPath := flag.String("import-snapshot", "", "Load a registry snapshot from this file before serving")
+	flag.Var(&cfg.Storage.StaleTTL, "stale-ttl", "Heartbeat age after which a Super Node is marked stale")
+	flag.Var(&cfg.Storage.DeadTTL, "dead-ttl", "Heartbeat age after which a Super Node is evicted")
+	flag.StringVar(&cfg.GeoIP.DB, "geoip-db", cfg.GeoIP.DB, "Path to a MaxMind-format (mmdb) country database for client region assignment")
+	flag.StringVar(&cfg.GeoIP.RegionOverrides, "region-overrides", cfg.GeoIP.RegionOverrides, "Path to a file of CIDR/country to region overrides")
+	flag.StringVar(&cfg.Federation.Key, "federation-key", cfg.Federation.Key, "This region's federation identity key (created if missing)")
+	flag.StringVar(&cfg.Federation.TrustedRegions, "trusted-regions", cfg.Federation.TrustedRegions, "Path to a file of \"<region> <public key>\" federation keys to trust")
+	flag.BoolVar(&cfg.Federation.Insecure, "insecure-federation", cfg.Federation.Insecure, "Accept federation messages without verifying them (development only)")
+	flag.StringVar(&cfg.Security.SuperAllowlist, "super-allowlist", cfg.Security.SuperAllowlist, "Path to a file of permitted Super Node public keys (empty allows any key)")
+	flag.StringVar(&cfg.TLS.CA, "tls-ca", cfg.TLS.CA, "Network CA certificate for mutual TLS")
+	flag.StringVar(&cfg.TLS.Cert, "tls-cert", cfg.TLS.Cert, "This node's TLS certificate")
+	flag.StringVar(&cfg.TLS.Key, "tls-key", cfg.TLS.Key, "This node's TLS private key")
+	flag.BoolVar(&cfg.TLS.Insecure, "insecure", cfg.TLS.Insecure, "Disable mutual TLS on all gRPC links (development only)")
 	flag.Parse()
 
+	if err := config.Resolve(flag.CommandLine, *configPath, &cfg); err != nil {
+		log.Fatalf("invalid configuration: %v", err)
+	}
+	if *printConfig {
+		if err := config.Print(os.Stdout, &cfg); err != nil {
+			log.Fatalf("failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	if cfg.TLS.Insecure {
+		log.Println("⚠️ Mutual TLS disabled, gRPC traffic is unencrypted")
+	} else if err := utils.LoadMutualTLSFiles(cfg.TLS.CA, cfg.TLS.Cert, cfg.TLS.Key); err != nil {
+		log.Fatalf("failed to load TLS credentials (use --insecure to run without TLS): %v", err)
+	}
+
+	var store server.RegistryStore = server.NewMemoryStore()
+	if cfg.Storage.DataDir != "" {
+		fileStore, err := server.NewFileStore(cfg.Storage.DataDir)
+		if err != nil {
+			log.Fatalf("failed to open registry store: %v", err)
+		}
+		store = fileStore
+	}
+	defer store.Close()
+	// log.Fatalf skips deferred calls, so the store is closed before it
+	fatalf := func(format string, args ...any) {
+		if err := store.Close(); err != nil {
+			log.Printf("⚠️ Failed to close registry store: %v", err)
+		}
+		log.Fatalf(format, args...)
+	}
+
+	registry, err := server.NewSuperNodeRegistry(store, time.Duration(cfg.Storage.StaleTTL), time.Duration(cfg.Storage.DeadTTL))
+	if err != nil {
+		fatalf("failed to start base node: %v", err)
+	}
+	baseNodeServer := server.NewBaseNodeServer(cfg.Region, registry)
+
+	pins, err := server.NewKeyPins(cfg.Storage.DataDir)
+	if err != nil {
+		fatalf("failed to load key pins: %v", err)
+	}
+	baseNodeServer.SetKeyPins(pins)
+
+	if cfg.Security.SuperAllowlist != "" {
+		allowed, err := server.LoadKeyAllowlist(cfg.Security.SuperAllowlist)
+		if err != nil {
+			fatalf("failed to load super node allowlist: %v", err)
+		}
+		log.Printf("🔑 Loaded %d permitted Super Node keys", len(allowed))
+		baseNodeServer.SetKeyAllowlist(allowed)
+	}
+
+	if cfg.GeoIP.DB != "" || cfg.GeoIP.RegionOverrides != "" {
+		locator, err := server.NewRegionLocator(cfg.GeoIP.DB, cfg.GeoIP.RegionOverrides)
+		if err != nil {
+			fatalf("failed to load region locator: %v", err)
+		}
+		defer locator.Close()
+		baseNodeServer.SetRegionLocator(locator)
+	}
+
+	if *exportPath != "" {
+		if err := exportSnapshot(registry, *exportPath); err != nil {
+			fatalf("failed to export snapshot: %v", err)
+		}
+		log.Printf("📤 Registry snapshot written to %s", *exportPath)
+		return
+	}
+
+	if *importPath != "" {
+		f, err := os.Open(*importPath)
+		if err != nil {
+			fatalf("failed to open snapshot: %v", err)
+		}
+		err = registry.Import(f)
+		f.Close()
+		if err != nil {
+			fatalf("failed to import snapshot: %v", err)
+		}
+	}
+
 	ip := utils.GetLocalIP()
-	addr := fmt.Sprintf("%s:%s", ip, *port)
+	addr := fmt.Sprintf("%s:%s", ip, cfg.Port)
 
 	lis, err := net.Listen("tcp", addr)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		fatalf("failed to listen: %v", err)
 	}
 
-	baseNodeServer := server.NewBaseNodeServer(*region)
 	baseNodeServer.StartSuperNodeMonitoring()
-	federationServer := server.NewFederationServer(*region, baseNodeServer)
 
-	grpcServer := grpc.NewServer()
+	memberID := cfg.ID
+	if memberID == "" {
+		memberID = fmt.Sprintf("base-%s-%s", cfg.Region, addr)
+	}
+	fedKey, err := server.LoadOrCreateFederationKey(cfg.Federation.Key)
+	if err != nil {
+		fatalf("failed to load federation key: %v", err)
+	}
+	var keys *server.FederationKeys
+	if cfg.Federation.Insecure {
+		log.Println("⚠️ Federation verification disabled, messages from any base are accepted")
+		keys = server.NewInsecureFederationKeys(cfg.Region, fedKey)
+	} else {
+		trusted, err := server.LoadTrustedRegions(cfg.Federation.TrustedRegions)
+		if err != nil {
+			fatalf("failed to load trusted region keys (use --insecure-federation to run without them): %v", err)
+		}
+		log.Printf("🔑 Trusting federation keys for %d regions", len(trusted))
+		keys = server.NewFederationKeys(cfg.Region, fedKey, trusted)
+	}
+	log.Printf("🔑 Federation key for region %s: %s", cfg.Region, keys.PublicKey())
+
+	membership := server.NewMembership(memberID, addr, cfg.Region, cfg.Federation.Seeds, keys)
+	membership.Start()
+	baseNodeServer.SetFederation(membership)
+
+	federationServer := server.NewFederationServer(cfg.Region, baseNodeServer, membership)
+
+	var replica *server.RaftNode
+	if len(cfg.Replication.Replicas) > 0 {
+		replica, err = server.NewRaftNode(addr, cfg.Replication.Replicas, cfg.Storage.DataDir, baseNodeServer.StateMachine())
+		if err != nil {
+			fatalf("failed to start replication: %v", err)
+		}
+		baseNodeServer.SetReplica(replica)
+	}
+
+	grpcServer := grpc.NewServer(
+		utils.ServerOption(),
+		grpc.UnaryInterceptor(utils.RequireRole(utils.RoleBase, "dvpn.BaseFederationService", "dvpn.BaseReplicationService")),
+	)
 	pb.RegisterBaseNodeServiceServer(grpcServer, baseNodeServer)
 	pb.RegisterBaseFederationServiceServer(grpcServer, federationServer)
+	if replica != nil {
+		pb.RegisterBaseReplicationServiceServer(grpcServer, replica)
+		replica.Start()
+	}
 
-	log.Printf("%s Base Node Server is listening on %s", *region, addr)
-	if err := grpcServer.Serve(lis); err != nil {
-		log.Fatalf("failed to serve: %v", err)
+	// Stop serving on SIGINT or SIGTERM, so the store is closed on the way out
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-stop
+		log.Println("🛑 Shutdown signal received, stopping Base Node...")
+
+		// Watch streams only end with their client, so they are ended first
+		baseNodeServer.Stop()
+		drained := make(chan struct{})
+		go func() {
+			grpcServer.GracefulStop()
+			close(drained)
+		}()
+		select {
+		case <-drained:
+		case <-time.After(shutdownTimeout):
+			log.Printf("⚠️ RPCs still running after %s, closing them", shutdownTimeout)
+			grpcServer.Stop()
+		}
+	}()
+
+	log.Printf("%s Base Node Server is listening on %s", cfg.Region, addr)
+	err = grpcServer.Serve(lis)
+	if err == nil {
+		// Serve returns as soon as the listener closes; wait for the RPCs
+		<-stopped
+	}
+
+	// Nothing may write to the store once it is closed
+	if replica != nil {
+		replica.Stop()
+	}
+	membership.Stop()
+	baseNodeServer.Stop()
+	baseNodeServer.Close()
+	if err != nil {
+		fatalf("failed to serve: %v", err)
+	}
+}
+
+func exportSnapshot(r *server.SuperNodeRegistry, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := r.Export(f); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
 }
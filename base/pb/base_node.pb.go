@@ -22,6 +22,61 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type SuperNodeEvent_Type int32
+
+const (
+	SuperNodeEvent_SNAPSHOT SuperNodeEvent_Type = 0
+	SuperNodeEvent_ADDED    SuperNodeEvent_Type = 1
+	SuperNodeEvent_UPDATED  SuperNodeEvent_Type = 2
+	SuperNodeEvent_STALE    SuperNodeEvent_Type = 3
+	SuperNodeEvent_REMOVED  SuperNodeEvent_Type = 4
+)
+
+// Enum value maps for SuperNodeEvent_Type.
+var (
+	SuperNodeEvent_Type_name = map[int32]string{
+		0: "SNAPSHOT",
+		1: "ADDED",
+		2: "UPDATED",
+		3: "STALE",
+		4: "REMOVED",
+	}
+	SuperNodeEvent_Type_value = map[string]int32{
+		"SNAPSHOT": 0,
+		"ADDED":    1,
+		"UPDATED":  2,
+		"STALE":    3,
+		"REMOVED":  4,
+	}
+)
+
+func (x SuperNodeEvent_Type) Enum() *SuperNodeEvent_Type {
+	p := new(SuperNodeEvent_Type)
+	*p = x
+	return p
+}
+
+func (x SuperNodeEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (SuperNodeEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_base_node_proto_enumTypes[0].Descriptor()
+}
+
+func (SuperNodeEvent_Type) Type() protoreflect.EnumType {
+	return &file_base_node_proto_enumTypes[0]
+}
+
+func (x SuperNodeEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use SuperNodeEvent_Type.Descriptor instead.
+func (SuperNodeEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_base_node_proto_rawDescGZIP(), []int{6, 0}
+}
+
 type RegisterRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -34,6 +89,7 @@ type RegisterRequest struct {
 	Version       string                 `protobuf:"bytes,8,opt,name=version,proto3" json:"version,omitempty"`
 	StartupTime   string                 `protobuf:"bytes,9,opt,name=startup_time,json=startupTime,proto3" json:"startup_time,omitempty"`
 	Port          string                 `protobuf:"bytes,10,opt,name=port,proto3" json:"port,omitempty"`
+	SignedAt      int64                  `protobuf:"varint,11,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -138,12 +194,22 @@ func (x *RegisterRequest) GetPort() string {
 	return ""
 }
 
+func (x *RegisterRequest) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
 type RegisterResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
-	AssignedId    string                 `protobuf:"bytes,3,opt,name=assigned_id,json=assignedId,proto3" json:"assigned_id,omitempty"`
-	RegisteredAt  string                 `protobuf:"bytes,4,opt,name=registered_at,json=registeredAt,proto3" json:"registered_at,omitempty"`
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Success      bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message      string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	AssignedId   string                 `protobuf:"bytes,3,opt,name=assigned_id,json=assignedId,proto3" json:"assigned_id,omitempty"`
+	RegisteredAt string                 `protobuf:"bytes,4,opt,name=registered_at,json=registeredAt,proto3" json:"registered_at,omitempty"`
+	// Set by a super node that is at capacity: a less loaded super of the
+	// same region to register with instead.
+	Redirect      *SuperNode `protobuf:"bytes,5,opt,name=redirect,proto3" json:"redirect,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -206,6 +272,13 @@ func (x *RegisterResponse) GetRegisteredAt() string {
 	return ""
 }
 
+func (x *RegisterResponse) GetRedirect() *SuperNode {
+	if x != nil {
+		return x.Redirect
+	}
+	return nil
+}
+
 type HeartbeatRequest struct {
 	state              protoimpl.MessageState `protogen:"open.v1"`
 	NodeId             string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -214,8 +287,18 @@ type HeartbeatRequest struct {
 	AvgLatencyMs       float32                `protobuf:"fixed32,4,opt,name=avg_latency_ms,json=avgLatencyMs,proto3" json:"avg_latency_ms,omitempty"`
 	BandwidthUsageMbps float32                `protobuf:"fixed32,5,opt,name=bandwidth_usage_mbps,json=bandwidthUsageMbps,proto3" json:"bandwidth_usage_mbps,omitempty"`
 	Timestamp          string                 `protobuf:"bytes,6,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	unknownFields      protoimpl.UnknownFields
-	sizeCache          protoimpl.SizeCache
+	// Resource usage of the super node process.
+	CpuPercent float32 `protobuf:"fixed32,7,opt,name=cpu_percent,json=cpuPercent,proto3" json:"cpu_percent,omitempty"`
+	MemoryMb   float32 `protobuf:"fixed32,8,opt,name=memory_mb,json=memoryMb,proto3" json:"memory_mb,omitempty"`
+	// Most bandwidth one of the super's exits can still grant, or -1 when
+	// unknown because an exit advertised no capacity and takes any rate.
+	ExitBandwidthMbps float32 `protobuf:"fixed32,11,opt,name=exit_bandwidth_mbps,json=exitBandwidthMbps,proto3" json:"exit_bandwidth_mbps,omitempty"`
+	// Signed with the super's identity key, so a base relaying the
+	// heartbeat to its replication leader cannot alter it.
+	SignedAt      int64  `protobuf:"varint,9,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	Signature     string `protobuf:"bytes,10,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *HeartbeatRequest) Reset() {
@@ -290,6 +373,41 @@ func (x *HeartbeatRequest) GetTimestamp() string {
 	return ""
 }
 
+func (x *HeartbeatRequest) GetCpuPercent() float32 {
+	if x != nil {
+		return x.CpuPercent
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetMemoryMb() float32 {
+	if x != nil {
+		return x.MemoryMb
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetExitBandwidthMbps() float32 {
+	if x != nil {
+		return x.ExitBandwidthMbps
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
+func (x *HeartbeatRequest) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
 type Ack struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Received      bool                   `protobuf:"varint,1,opt,name=received,proto3" json:"received,omitempty"`
@@ -353,6 +471,8 @@ type SuperNode struct {
 	Port            string                 `protobuf:"bytes,7,opt,name=port,proto3" json:"port,omitempty"`
 	AvgLatencyMs    float32                `protobuf:"fixed32,8,opt,name=avg_latency_ms,json=avgLatencyMs,proto3" json:"avg_latency_ms,omitempty"`
 	BandwidthMbps   float32                `protobuf:"fixed32,9,opt,name=bandwidth_mbps,json=bandwidthMbps,proto3" json:"bandwidth_mbps,omitempty"`
+	ActivePeers     int32                  `protobuf:"varint,10,opt,name=active_peers,json=activePeers,proto3" json:"active_peers,omitempty"`
+	MaxPeers        int32                  `protobuf:"varint,11,opt,name=max_peers,json=maxPeers,proto3" json:"max_peers,omitempty"`
 	unknownFields   protoimpl.UnknownFields
 	sizeCache       protoimpl.SizeCache
 }
@@ -450,6 +570,20 @@ func (x *SuperNode) GetBandwidthMbps() float32 {
 	return 0
 }
 
+func (x *SuperNode) GetActivePeers() int32 {
+	if x != nil {
+		return x.ActivePeers
+	}
+	return 0
+}
+
+func (x *SuperNode) GetMaxPeers() int32 {
+	if x != nil {
+		return x.MaxPeers
+	}
+	return 0
+}
+
 type SuperNodeList struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Nodes         []*SuperNode           `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
@@ -494,6 +628,76 @@ func (x *SuperNodeList) GetNodes() []*SuperNode {
 	return nil
 }
 
+// SuperNodeEvent is streamed by WatchSuperNodes. The first event is a
+// SNAPSHOT of every registered node; later events carry a single node.
+type SuperNodeEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          SuperNodeEvent_Type    `protobuf:"varint,1,opt,name=type,proto3,enum=dvpn.SuperNodeEvent_Type" json:"type,omitempty"`
+	Node          *SuperNode             `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	Snapshot      []*SuperNode           `protobuf:"bytes,3,rep,name=snapshot,proto3" json:"snapshot,omitempty"`
+	At            string                 `protobuf:"bytes,4,opt,name=at,proto3" json:"at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuperNodeEvent) Reset() {
+	*x = SuperNodeEvent{}
+	mi := &file_base_node_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuperNodeEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuperNodeEvent) ProtoMessage() {}
+
+func (x *SuperNodeEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_base_node_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuperNodeEvent.ProtoReflect.Descriptor instead.
+func (*SuperNodeEvent) Descriptor() ([]byte, []int) {
+	return file_base_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *SuperNodeEvent) GetType() SuperNodeEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return SuperNodeEvent_SNAPSHOT
+}
+
+func (x *SuperNodeEvent) GetNode() *SuperNode {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *SuperNodeEvent) GetSnapshot() []*SuperNode {
+	if x != nil {
+		return x.Snapshot
+	}
+	return nil
+}
+
+func (x *SuperNodeEvent) GetAt() string {
+	if x != nil {
+		return x.At
+	}
+	return ""
+}
+
 type ExitRegionRequest struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
 	DesiredRegion    string                 `protobuf:"bytes,1,opt,name=desired_region,json=desiredRegion,proto3" json:"desired_region,omitempty"`
@@ -506,7 +710,7 @@ type ExitRegionRequest struct {
 
 func (x *ExitRegionRequest) Reset() {
 	*x = ExitRegionRequest{}
-	mi := &file_base_node_proto_msgTypes[6]
+	mi := &file_base_node_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -518,7 +722,7 @@ func (x *ExitRegionRequest) String() string {
 func (*ExitRegionRequest) ProtoMessage() {}
 
 func (x *ExitRegionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_base_node_proto_msgTypes[6]
+	mi := &file_base_node_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -531,7 +735,7 @@ func (x *ExitRegionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use ExitRegionRequest.ProtoReflect.Descriptor instead.
 func (*ExitRegionRequest) Descriptor() ([]byte, []int) {
-	return file_base_node_proto_rawDescGZIP(), []int{6}
+	return file_base_node_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *ExitRegionRequest) GetDesiredRegion() string {
@@ -562,11 +766,305 @@ func (x *ExitRegionRequest) GetCount() int32 {
 	return 0
 }
 
+type DiscoverRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Os            string                 `protobuf:"bytes,3,opt,name=os,proto3" json:"os,omitempty"`
+	Version       string                 `protobuf:"bytes,4,opt,name=version,proto3" json:"version,omitempty"`
+	NatType       string                 `protobuf:"bytes,5,opt,name=nat_type,json=natType,proto3" json:"nat_type,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiscoverRequest) Reset() {
+	*x = DiscoverRequest{}
+	mi := &file_base_node_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiscoverRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiscoverRequest) ProtoMessage() {}
+
+func (x *DiscoverRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_base_node_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiscoverRequest.ProtoReflect.Descriptor instead.
+func (*DiscoverRequest) Descriptor() ([]byte, []int) {
+	return file_base_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DiscoverRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *DiscoverRequest) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *DiscoverRequest) GetOs() string {
+	if x != nil {
+		return x.Os
+	}
+	return ""
+}
+
+func (x *DiscoverRequest) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *DiscoverRequest) GetNatType() string {
+	if x != nil {
+		return x.NatType
+	}
+	return ""
+}
+
+type DiscoveryResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accepted      bool                   `protobuf:"varint,1,opt,name=accepted,proto3" json:"accepted,omitempty"`
+	Region        string                 `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	Message       string                 `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	Nodes         []*SuperNode           `protobuf:"bytes,4,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DiscoveryResponse) Reset() {
+	*x = DiscoveryResponse{}
+	mi := &file_base_node_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DiscoveryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DiscoveryResponse) ProtoMessage() {}
+
+func (x *DiscoveryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_base_node_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DiscoveryResponse.ProtoReflect.Descriptor instead.
+func (*DiscoveryResponse) Descriptor() ([]byte, []int) {
+	return file_base_node_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *DiscoveryResponse) GetAccepted() bool {
+	if x != nil {
+		return x.Accepted
+	}
+	return false
+}
+
+func (x *DiscoveryResponse) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *DiscoveryResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *DiscoveryResponse) GetNodes() []*SuperNode {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+// SuperNodeIdentity asks whether a super node is registered with the base of
+// its region, under the given key. Bases ask other regions over federation.
+type SuperNodeIdentity struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	NodeId        string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	Region        string                 `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SuperNodeIdentity) Reset() {
+	*x = SuperNodeIdentity{}
+	mi := &file_base_node_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SuperNodeIdentity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SuperNodeIdentity) ProtoMessage() {}
+
+func (x *SuperNodeIdentity) ProtoReflect() protoreflect.Message {
+	mi := &file_base_node_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SuperNodeIdentity.ProtoReflect.Descriptor instead.
+func (*SuperNodeIdentity) Descriptor() ([]byte, []int) {
+	return file_base_node_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SuperNodeIdentity) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *SuperNodeIdentity) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *SuperNodeIdentity) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+// PeerIdentity is the identity key a client or exit peer registers with the
+// base of its region, signed with that key, so other peers can check it
+// without trusting the supers in between. VerifyPeer only reads peer_id,
+// region and public_key.
+type PeerIdentity struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	Region        string                 `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	Nonce         string                 `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	SignedAt      int64                  `protobuf:"varint,5,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	Signature     string                 `protobuf:"bytes,6,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerIdentity) Reset() {
+	*x = PeerIdentity{}
+	mi := &file_base_node_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerIdentity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerIdentity) ProtoMessage() {}
+
+func (x *PeerIdentity) ProtoReflect() protoreflect.Message {
+	mi := &file_base_node_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerIdentity.ProtoReflect.Descriptor instead.
+func (*PeerIdentity) Descriptor() ([]byte, []int) {
+	return file_base_node_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *PeerIdentity) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *PeerIdentity) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *PeerIdentity) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *PeerIdentity) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *PeerIdentity) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
+func (x *PeerIdentity) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
 var File_base_node_proto protoreflect.FileDescriptor
 
 const file_base_node_proto_rawDesc = "" +
 	"\n" +
-	"\x0fbase_node.proto\x12\x04dvpn\x1a\x1bgoogle/protobuf/empty.proto\"\x93\x02\n" +
+	"\x0fbase_node.proto\x12\x04dvpn\x1a\x1bgoogle/protobuf/empty.proto\"\xb0\x02\n" +
 	"\x0fRegisterRequest\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
 	"\x06region\x18\x02 \x01(\tR\x06region\x12\x0e\n" +
@@ -579,23 +1077,32 @@ const file_base_node_proto_rawDesc = "" +
 	"\aversion\x18\b \x01(\tR\aversion\x12!\n" +
 	"\fstartup_time\x18\t \x01(\tR\vstartupTime\x12\x12\n" +
 	"\x04port\x18\n" +
-	" \x01(\tR\x04port\"\x8c\x01\n" +
+	" \x01(\tR\x04port\x12\x1b\n" +
+	"\tsigned_at\x18\v \x01(\x03R\bsignedAt\"\xb9\x01\n" +
 	"\x10RegisterResponse\x12\x18\n" +
 	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x18\n" +
 	"\amessage\x18\x02 \x01(\tR\amessage\x12\x1f\n" +
 	"\vassigned_id\x18\x03 \x01(\tR\n" +
 	"assignedId\x12#\n" +
-	"\rregistered_at\x18\x04 \x01(\tR\fregisteredAt\"\xf6\x01\n" +
+	"\rregistered_at\x18\x04 \x01(\tR\fregisteredAt\x12+\n" +
+	"\bredirect\x18\x05 \x01(\v2\x0f.dvpn.SuperNodeR\bredirect\"\x9f\x03\n" +
 	"\x10HeartbeatRequest\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12!\n" +
 	"\factive_peers\x18\x02 \x01(\x05R\vactivePeers\x120\n" +
 	"\x14exit_peers_available\x18\x03 \x01(\x05R\x12exitPeersAvailable\x12$\n" +
 	"\x0eavg_latency_ms\x18\x04 \x01(\x02R\favgLatencyMs\x120\n" +
 	"\x14bandwidth_usage_mbps\x18\x05 \x01(\x02R\x12bandwidthUsageMbps\x12\x1c\n" +
-	"\ttimestamp\x18\x06 \x01(\tR\ttimestamp\";\n" +
+	"\ttimestamp\x18\x06 \x01(\tR\ttimestamp\x12\x1f\n" +
+	"\vcpu_percent\x18\a \x01(\x02R\n" +
+	"cpuPercent\x12\x1b\n" +
+	"\tmemory_mb\x18\b \x01(\x02R\bmemoryMb\x12.\n" +
+	"\x13exit_bandwidth_mbps\x18\v \x01(\x02R\x11exitBandwidthMbps\x12\x1b\n" +
+	"\tsigned_at\x18\t \x01(\x03R\bsignedAt\x12\x1c\n" +
+	"\tsignature\x18\n" +
+	" \x01(\tR\tsignature\";\n" +
 	"\x03Ack\x12\x1a\n" +
 	"\breceived\x18\x01 \x01(\bR\breceived\x12\x18\n" +
-	"\amessage\x18\x02 \x01(\tR\amessage\"\x8d\x02\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\"\xcd\x02\n" +
 	"\tSuperNode\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
 	"\x06region\x18\x02 \x01(\tR\x06region\x12\x0e\n" +
@@ -605,19 +1112,64 @@ const file_base_node_proto_rawDesc = "" +
 	"\bis_alive\x18\x06 \x01(\bR\aisAlive\x12\x12\n" +
 	"\x04port\x18\a \x01(\tR\x04port\x12$\n" +
 	"\x0eavg_latency_ms\x18\b \x01(\x02R\favgLatencyMs\x12%\n" +
-	"\x0ebandwidth_mbps\x18\t \x01(\x02R\rbandwidthMbps\"6\n" +
+	"\x0ebandwidth_mbps\x18\t \x01(\x02R\rbandwidthMbps\x12!\n" +
+	"\factive_peers\x18\n" +
+	" \x01(\x05R\vactivePeers\x12\x1b\n" +
+	"\tmax_peers\x18\v \x01(\x05R\bmaxPeers\"6\n" +
 	"\rSuperNodeList\x12%\n" +
-	"\x05nodes\x18\x01 \x03(\v2\x0f.dvpn.SuperNodeR\x05nodes\"\xa4\x01\n" +
+	"\x05nodes\x18\x01 \x03(\v2\x0f.dvpn.SuperNodeR\x05nodes\"\xe7\x01\n" +
+	"\x0eSuperNodeEvent\x12-\n" +
+	"\x04type\x18\x01 \x01(\x0e2\x19.dvpn.SuperNodeEvent.TypeR\x04type\x12#\n" +
+	"\x04node\x18\x02 \x01(\v2\x0f.dvpn.SuperNodeR\x04node\x12+\n" +
+	"\bsnapshot\x18\x03 \x03(\v2\x0f.dvpn.SuperNodeR\bsnapshot\x12\x0e\n" +
+	"\x02at\x18\x04 \x01(\tR\x02at\"D\n" +
+	"\x04Type\x12\f\n" +
+	"\bSNAPSHOT\x10\x00\x12\t\n" +
+	"\x05ADDED\x10\x01\x12\v\n" +
+	"\aUPDATED\x10\x02\x12\t\n" +
+	"\x05STALE\x10\x03\x12\v\n" +
+	"\aREMOVED\x10\x04\"\xa4\x01\n" +
 	"\x11ExitRegionRequest\x12%\n" +
 	"\x0edesired_region\x18\x01 \x01(\tR\rdesiredRegion\x12,\n" +
 	"\x12min_bandwidth_mbps\x18\x02 \x01(\x02R\x10minBandwidthMbps\x12$\n" +
 	"\x0emax_latency_ms\x18\x03 \x01(\x02R\fmaxLatencyMs\x12\x14\n" +
-	"\x05count\x18\x04 \x01(\x05R\x05count2\x95\x02\n" +
+	"\x05count\x18\x04 \x01(\x05R\x05count\"\x8e\x01\n" +
+	"\x0fDiscoverRequest\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x0e\n" +
+	"\x02os\x18\x03 \x01(\tR\x02os\x12\x18\n" +
+	"\aversion\x18\x04 \x01(\tR\aversion\x12\x19\n" +
+	"\bnat_type\x18\x05 \x01(\tR\anatType\"\x88\x01\n" +
+	"\x11DiscoveryResponse\x12\x1a\n" +
+	"\baccepted\x18\x01 \x01(\bR\baccepted\x12\x16\n" +
+	"\x06region\x18\x02 \x01(\tR\x06region\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\x12%\n" +
+	"\x05nodes\x18\x04 \x03(\v2\x0f.dvpn.SuperNodeR\x05nodes\"c\n" +
+	"\x11SuperNodeIdentity\x12\x17\n" +
+	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x16\n" +
+	"\x06region\x18\x02 \x01(\tR\x06region\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x03 \x01(\tR\tpublicKey\"\xaf\x01\n" +
+	"\fPeerIdentity\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x16\n" +
+	"\x06region\x18\x02 \x01(\tR\x06region\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x03 \x01(\tR\tpublicKey\x12\x14\n" +
+	"\x05nonce\x18\x04 \x01(\tR\x05nonce\x12\x1b\n" +
+	"\tsigned_at\x18\x05 \x01(\x03R\bsignedAt\x12\x1c\n" +
+	"\tsignature\x18\x06 \x01(\tR\tsignature2\xbb\x04\n" +
 	"\x0fBaseNodeService\x12B\n" +
 	"\x11RegisterSuperNode\x12\x15.dvpn.RegisterRequest\x1a\x16.dvpn.RegisterResponse\x127\n" +
 	"\x12SuperNodeHeartbeat\x12\x16.dvpn.HeartbeatRequest\x1a\t.dvpn.Ack\x12B\n" +
 	"\x13GetActiveSuperNodes\x12\x16.google.protobuf.Empty\x1a\x13.dvpn.SuperNodeList\x12A\n" +
-	"\x11RequestExitRegion\x12\x17.dvpn.ExitRegionRequest\x1a\x13.dvpn.SuperNodeListB\x0eZ\fBase_node/pbb\x06proto3"
+	"\x11RequestExitRegion\x12\x17.dvpn.ExitRegionRequest\x1a\x13.dvpn.SuperNodeList\x12F\n" +
+	"\x14DiscoverClientRegion\x12\x15.dvpn.DiscoverRequest\x1a\x17.dvpn.DiscoveryResponse\x12A\n" +
+	"\x0fWatchSuperNodes\x12\x16.google.protobuf.Empty\x1a\x14.dvpn.SuperNodeEvent0\x01\x125\n" +
+	"\x0fVerifySuperNode\x12\x17.dvpn.SuperNodeIdentity\x1a\t.dvpn.Ack\x125\n" +
+	"\x14RegisterPeerIdentity\x12\x12.dvpn.PeerIdentity\x1a\t.dvpn.Ack\x12+\n" +
+	"\n" +
+	"VerifyPeer\x12\x12.dvpn.PeerIdentity\x1a\t.dvpn.AckB\x05Z\x03/pbb\x06proto3"
 
 var (
 	file_base_node_proto_rawDescOnce sync.Once
@@ -631,32 +1183,54 @@ func file_base_node_proto_rawDescGZIP() []byte {
 	return file_base_node_proto_rawDescData
 }
 
-var file_base_node_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_base_node_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_base_node_proto_msgTypes = make([]protoimpl.MessageInfo, 12)
 var file_base_node_proto_goTypes = []any{
-	(*RegisterRequest)(nil),   // 0: dvpn.RegisterRequest
-	(*RegisterResponse)(nil),  // 1: dvpn.RegisterResponse
-	(*HeartbeatRequest)(nil),  // 2: dvpn.HeartbeatRequest
-	(*Ack)(nil),               // 3: dvpn.Ack
-	(*SuperNode)(nil),         // 4: dvpn.SuperNode
-	(*SuperNodeList)(nil),     // 5: dvpn.SuperNodeList
-	(*ExitRegionRequest)(nil), // 6: dvpn.ExitRegionRequest
-	(*emptypb.Empty)(nil),     // 7: google.protobuf.Empty
+	(SuperNodeEvent_Type)(0),  // 0: dvpn.SuperNodeEvent.Type
+	(*RegisterRequest)(nil),   // 1: dvpn.RegisterRequest
+	(*RegisterResponse)(nil),  // 2: dvpn.RegisterResponse
+	(*HeartbeatRequest)(nil),  // 3: dvpn.HeartbeatRequest
+	(*Ack)(nil),               // 4: dvpn.Ack
+	(*SuperNode)(nil),         // 5: dvpn.SuperNode
+	(*SuperNodeList)(nil),     // 6: dvpn.SuperNodeList
+	(*SuperNodeEvent)(nil),    // 7: dvpn.SuperNodeEvent
+	(*ExitRegionRequest)(nil), // 8: dvpn.ExitRegionRequest
+	(*DiscoverRequest)(nil),   // 9: dvpn.DiscoverRequest
+	(*DiscoveryResponse)(nil), // 10: dvpn.DiscoveryResponse
+	(*SuperNodeIdentity)(nil), // 11: dvpn.SuperNodeIdentity
+	(*PeerIdentity)(nil),      // 12: dvpn.PeerIdentity
+	(*emptypb.Empty)(nil),     // 13: google.protobuf.Empty
 }
 var file_base_node_proto_depIdxs = []int32{
-	4, // 0: dvpn.SuperNodeList.nodes:type_name -> dvpn.SuperNode
-	0, // 1: dvpn.BaseNodeService.RegisterSuperNode:input_type -> dvpn.RegisterRequest
-	2, // 2: dvpn.BaseNodeService.SuperNodeHeartbeat:input_type -> dvpn.HeartbeatRequest
-	7, // 3: dvpn.BaseNodeService.GetActiveSuperNodes:input_type -> google.protobuf.Empty
-	6, // 4: dvpn.BaseNodeService.RequestExitRegion:input_type -> dvpn.ExitRegionRequest
-	1, // 5: dvpn.BaseNodeService.RegisterSuperNode:output_type -> dvpn.RegisterResponse
-	3, // 6: dvpn.BaseNodeService.SuperNodeHeartbeat:output_type -> dvpn.Ack
-	5, // 7: dvpn.BaseNodeService.GetActiveSuperNodes:output_type -> dvpn.SuperNodeList
-	5, // 8: dvpn.BaseNodeService.RequestExitRegion:output_type -> dvpn.SuperNodeList
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	5,  // 0: dvpn.RegisterResponse.redirect:type_name -> dvpn.SuperNode
+	5,  // 1: dvpn.SuperNodeList.nodes:type_name -> dvpn.SuperNode
+	0,  // 2: dvpn.SuperNodeEvent.type:type_name -> dvpn.SuperNodeEvent.Type
+	5,  // 3: dvpn.SuperNodeEvent.node:type_name -> dvpn.SuperNode
+	5,  // 4: dvpn.SuperNodeEvent.snapshot:type_name -> dvpn.SuperNode
+	5,  // 5: dvpn.DiscoveryResponse.nodes:type_name -> dvpn.SuperNode
+	1,  // 6: dvpn.BaseNodeService.RegisterSuperNode:input_type -> dvpn.RegisterRequest
+	3,  // 7: dvpn.BaseNodeService.SuperNodeHeartbeat:input_type -> dvpn.HeartbeatRequest
+	13, // 8: dvpn.BaseNodeService.GetActiveSuperNodes:input_type -> google.protobuf.Empty
+	8,  // 9: dvpn.BaseNodeService.RequestExitRegion:input_type -> dvpn.ExitRegionRequest
+	9,  // 10: dvpn.BaseNodeService.DiscoverClientRegion:input_type -> dvpn.DiscoverRequest
+	13, // 11: dvpn.BaseNodeService.WatchSuperNodes:input_type -> google.protobuf.Empty
+	11, // 12: dvpn.BaseNodeService.VerifySuperNode:input_type -> dvpn.SuperNodeIdentity
+	12, // 13: dvpn.BaseNodeService.RegisterPeerIdentity:input_type -> dvpn.PeerIdentity
+	12, // 14: dvpn.BaseNodeService.VerifyPeer:input_type -> dvpn.PeerIdentity
+	2,  // 15: dvpn.BaseNodeService.RegisterSuperNode:output_type -> dvpn.RegisterResponse
+	4,  // 16: dvpn.BaseNodeService.SuperNodeHeartbeat:output_type -> dvpn.Ack
+	6,  // 17: dvpn.BaseNodeService.GetActiveSuperNodes:output_type -> dvpn.SuperNodeList
+	6,  // 18: dvpn.BaseNodeService.RequestExitRegion:output_type -> dvpn.SuperNodeList
+	10, // 19: dvpn.BaseNodeService.DiscoverClientRegion:output_type -> dvpn.DiscoveryResponse
+	7,  // 20: dvpn.BaseNodeService.WatchSuperNodes:output_type -> dvpn.SuperNodeEvent
+	4,  // 21: dvpn.BaseNodeService.VerifySuperNode:output_type -> dvpn.Ack
+	4,  // 22: dvpn.BaseNodeService.RegisterPeerIdentity:output_type -> dvpn.Ack
+	4,  // 23: dvpn.BaseNodeService.VerifyPeer:output_type -> dvpn.Ack
+	15, // [15:24] is the sub-list for method output_type
+	6,  // [6:15] is the sub-list for method input_type
+	6,  // [6:6] is the sub-list for extension type_name
+	6,  // [6:6] is the sub-list for extension extendee
+	0,  // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_base_node_proto_init() }
@@ -669,13 +1243,14 @@ func file_base_node_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_base_node_proto_rawDesc), len(file_base_node_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   7,
+			NumEnums:      1,
+			NumMessages:   12,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_base_node_proto_goTypes,
 		DependencyIndexes: file_base_node_proto_depIdxs,
+		EnumInfos:         file_base_node_proto_enumTypes,
 		MessageInfos:      file_base_node_proto_msgTypes,
 	}.Build()
 	File_base_node_proto = out.File
@@ -27,8 +27,16 @@ type RemoteSuperRequest struct {
 	Count                 int32                  `protobuf:"varint,2,opt,name=count,proto3" json:"count,omitempty"`
 	RequiredBandWidthMbps float32                `protobuf:"fixed32,3,opt,name=required_bandWidth_mbps,json=requiredBandWidthMbps,proto3" json:"required_bandWidth_mbps,omitempty"`
 	MaxLatencyMs          float32                `protobuf:"fixed32,4,opt,name=max_latency_ms,json=maxLatencyMs,proto3" json:"max_latency_ms,omitempty"`
-	unknownFields         protoimpl.UnknownFields
-	sizeCache             protoimpl.SizeCache
+	HopLimit              int32                  `protobuf:"varint,5,opt,name=hop_limit,json=hopLimit,proto3" json:"hop_limit,omitempty"`
+	Via                   []string               `protobuf:"bytes,6,rep,name=via,proto3" json:"via,omitempty"`
+	Auth                  *FederationAuth        `protobuf:"bytes,7,opt,name=auth,proto3" json:"auth,omitempty"`
+	// When set, only this super node is returned, whatever its load, so
+	// another region can check that it is registered.
+	NodeId string `protobuf:"bytes,8,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
+	// When set, only the identity of this peer is returned.
+	PeerId        string `protobuf:"bytes,9,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *RemoteSuperRequest) Reset() {
@@ -89,6 +97,41 @@ func (x *RemoteSuperRequest) GetMaxLatencyMs() float32 {
 	return 0
 }
 
+func (x *RemoteSuperRequest) GetHopLimit() int32 {
+	if x != nil {
+		return x.HopLimit
+	}
+	return 0
+}
+
+func (x *RemoteSuperRequest) GetVia() []string {
+	if x != nil {
+		return x.Via
+	}
+	return nil
+}
+
+func (x *RemoteSuperRequest) GetAuth() *FederationAuth {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
+func (x *RemoteSuperRequest) GetNodeId() string {
+	if x != nil {
+		return x.NodeId
+	}
+	return ""
+}
+
+func (x *RemoteSuperRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
 type SuperNodeInfo struct {
 	state              protoimpl.MessageState `protogen:"open.v1"`
 	NodeId             string                 `protobuf:"bytes,1,opt,name=node_id,json=nodeId,proto3" json:"node_id,omitempty"`
@@ -98,6 +141,8 @@ type SuperNodeInfo struct {
 	AvgLatencyMs       float32                `protobuf:"fixed32,5,opt,name=avg_latency_ms,json=avgLatencyMs,proto3" json:"avg_latency_ms,omitempty"`
 	ExitPeersAvailable int32                  `protobuf:"varint,6,opt,name=exit_peers_available,json=exitPeersAvailable,proto3" json:"exit_peers_available,omitempty"`
 	BandWidthMbps      float32                `protobuf:"fixed32,7,opt,name=bandWidth_mbps,json=bandWidthMbps,proto3" json:"bandWidth_mbps,omitempty"`
+	PublicKey          string                 `protobuf:"bytes,8,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	ExitBandwidthMbps  float32                `protobuf:"fixed32,9,opt,name=exit_bandwidth_mbps,json=exitBandwidthMbps,proto3" json:"exit_bandwidth_mbps,omitempty"`
 	unknownFields      protoimpl.UnknownFields
 	sizeCache          protoimpl.SizeCache
 }
@@ -181,16 +226,92 @@ func (x *SuperNodeInfo) GetBandWidthMbps() float32 {
 	return 0
 }
 
+func (x *SuperNodeInfo) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *SuperNodeInfo) GetExitBandwidthMbps() float32 {
+	if x != nil {
+		return x.ExitBandwidthMbps
+	}
+	return 0
+}
+
+type PeerIdentityInfo struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	Region        string                 `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	PublicKey     string                 `protobuf:"bytes,3,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PeerIdentityInfo) Reset() {
+	*x = PeerIdentityInfo{}
+	mi := &file_base_sync_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PeerIdentityInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PeerIdentityInfo) ProtoMessage() {}
+
+func (x *PeerIdentityInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_base_sync_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PeerIdentityInfo.ProtoReflect.Descriptor instead.
+func (*PeerIdentityInfo) Descriptor() ([]byte, []int) {
+	return file_base_sync_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *PeerIdentityInfo) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *PeerIdentityInfo) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *PeerIdentityInfo) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
 type RemoteSuperResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	SuperNodes    []*SuperNodeInfo       `protobuf:"bytes,1,rep,name=super_nodes,json=superNodes,proto3" json:"super_nodes,omitempty"`
+	Auth          *FederationAuth        `protobuf:"bytes,2,opt,name=auth,proto3" json:"auth,omitempty"`
+	Peers         []*PeerIdentityInfo    `protobuf:"bytes,3,rep,name=peers,proto3" json:"peers,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *RemoteSuperResponse) Reset() {
 	*x = RemoteSuperResponse{}
-	mi := &file_base_sync_proto_msgTypes[2]
+	mi := &file_base_sync_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -202,7 +323,7 @@ func (x *RemoteSuperResponse) String() string {
 func (*RemoteSuperResponse) ProtoMessage() {}
 
 func (x *RemoteSuperResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_base_sync_proto_msgTypes[2]
+	mi := &file_base_sync_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -215,7 +336,7 @@ func (x *RemoteSuperResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use RemoteSuperResponse.ProtoReflect.Descriptor instead.
 func (*RemoteSuperResponse) Descriptor() ([]byte, []int) {
-	return file_base_sync_proto_rawDescGZIP(), []int{2}
+	return file_base_sync_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *RemoteSuperResponse) GetSuperNodes() []*SuperNodeInfo {
@@ -225,16 +346,250 @@ func (x *RemoteSuperResponse) GetSuperNodes() []*SuperNodeInfo {
 	return nil
 }
 
+func (x *RemoteSuperResponse) GetAuth() *FederationAuth {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
+func (x *RemoteSuperResponse) GetPeers() []*PeerIdentityInfo {
+	if x != nil {
+		return x.Peers
+	}
+	return nil
+}
+
+type FederationMember struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MemberId      string                 `protobuf:"bytes,1,opt,name=member_id,json=memberId,proto3" json:"member_id,omitempty"`
+	Address       string                 `protobuf:"bytes,2,opt,name=address,proto3" json:"address,omitempty"`
+	Regions       []string               `protobuf:"bytes,3,rep,name=regions,proto3" json:"regions,omitempty"`
+	Heartbeat     uint64                 `protobuf:"varint,4,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`
+	Reachable     []string               `protobuf:"bytes,5,rep,name=reachable,proto3" json:"reachable,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationMember) Reset() {
+	*x = FederationMember{}
+	mi := &file_base_sync_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationMember) ProtoMessage() {}
+
+func (x *FederationMember) ProtoReflect() protoreflect.Message {
+	mi := &file_base_sync_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationMember.ProtoReflect.Descriptor instead.
+func (*FederationMember) Descriptor() ([]byte, []int) {
+	return file_base_sync_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *FederationMember) GetMemberId() string {
+	if x != nil {
+		return x.MemberId
+	}
+	return ""
+}
+
+func (x *FederationMember) GetAddress() string {
+	if x != nil {
+		return x.Address
+	}
+	return ""
+}
+
+func (x *FederationMember) GetRegions() []string {
+	if x != nil {
+		return x.Regions
+	}
+	return nil
+}
+
+func (x *FederationMember) GetHeartbeat() uint64 {
+	if x != nil {
+		return x.Heartbeat
+	}
+	return 0
+}
+
+func (x *FederationMember) GetReachable() []string {
+	if x != nil {
+		return x.Reachable
+	}
+	return nil
+}
+
+type GossipMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	SenderId      string                 `protobuf:"bytes,1,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	Members       []*FederationMember    `protobuf:"bytes,2,rep,name=members,proto3" json:"members,omitempty"`
+	Auth          *FederationAuth        `protobuf:"bytes,3,opt,name=auth,proto3" json:"auth,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GossipMessage) Reset() {
+	*x = GossipMessage{}
+	mi := &file_base_sync_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GossipMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GossipMessage) ProtoMessage() {}
+
+func (x *GossipMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_base_sync_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GossipMessage.ProtoReflect.Descriptor instead.
+func (*GossipMessage) Descriptor() ([]byte, []int) {
+	return file_base_sync_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GossipMessage) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *GossipMessage) GetMembers() []*FederationMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+func (x *GossipMessage) GetAuth() *FederationAuth {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
+// FederationAuth is the signature a base node attaches to every federation
+// message. The signature covers the whole message with this field's
+// signature left empty. A response carries the nonce of its request.
+type FederationAuth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Region        string                 `protobuf:"bytes,1,opt,name=region,proto3" json:"region,omitempty"`
+	PublicKey     []byte                 `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	SignedAt      int64                  `protobuf:"varint,3,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	Nonce         string                 `protobuf:"bytes,4,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	Signature     []byte                 `protobuf:"bytes,5,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FederationAuth) Reset() {
+	*x = FederationAuth{}
+	mi := &file_base_sync_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FederationAuth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FederationAuth) ProtoMessage() {}
+
+func (x *FederationAuth) ProtoReflect() protoreflect.Message {
+	mi := &file_base_sync_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FederationAuth.ProtoReflect.Descriptor instead.
+func (*FederationAuth) Descriptor() ([]byte, []int) {
+	return file_base_sync_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *FederationAuth) GetRegion() string {
+	if x != nil {
+		return x.Region
+	}
+	return ""
+}
+
+func (x *FederationAuth) GetPublicKey() []byte {
+	if x != nil {
+		return x.PublicKey
+	}
+	return nil
+}
+
+func (x *FederationAuth) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
+func (x *FederationAuth) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *FederationAuth) GetSignature() []byte {
+	if x != nil {
+		return x.Signature
+	}
+	return nil
+}
+
 var File_base_sync_proto protoreflect.FileDescriptor
 
 const file_base_sync_proto_rawDesc = "" +
 	"\n" +
-	"\x0fbase_sync.proto\x12\x04dvpn\"\xad\x01\n" +
+	"\x0fbase_sync.proto\x12\x04dvpn\"\xb8\x02\n" +
 	"\x12RemoteSuperRequest\x12#\n" +
 	"\rtarget_region\x18\x01 \x01(\tR\ftargetRegion\x12\x14\n" +
 	"\x05count\x18\x02 \x01(\x05R\x05count\x126\n" +
 	"\x17required_bandWidth_mbps\x18\x03 \x01(\x02R\x15requiredBandWidthMbps\x12$\n" +
-	"\x0emax_latency_ms\x18\x04 \x01(\x02R\fmaxLatencyMs\"\xe3\x01\n" +
+	"\x0emax_latency_ms\x18\x04 \x01(\x02R\fmaxLatencyMs\x12\x1b\n" +
+	"\thop_limit\x18\x05 \x01(\x05R\bhopLimit\x12\x10\n" +
+	"\x03via\x18\x06 \x03(\tR\x03via\x12(\n" +
+	"\x04auth\x18\a \x01(\v2\x14.dvpn.FederationAuthR\x04auth\x12\x17\n" +
+	"\anode_id\x18\b \x01(\tR\x06nodeId\x12\x17\n" +
+	"\apeer_id\x18\t \x01(\tR\x06peerId\"\xb2\x02\n" +
 	"\rSuperNodeInfo\x12\x17\n" +
 	"\anode_id\x18\x01 \x01(\tR\x06nodeId\x12\x0e\n" +
 	"\x02ip\x18\x02 \x01(\tR\x02ip\x12\x12\n" +
@@ -242,12 +597,40 @@ const file_base_sync_proto_rawDesc = "" +
 	"\x06region\x18\x04 \x01(\tR\x06region\x12$\n" +
 	"\x0eavg_latency_ms\x18\x05 \x01(\x02R\favgLatencyMs\x120\n" +
 	"\x14exit_peers_available\x18\x06 \x01(\x05R\x12exitPeersAvailable\x12%\n" +
-	"\x0ebandWidth_mbps\x18\a \x01(\x02R\rbandWidthMbps\"K\n" +
+	"\x0ebandWidth_mbps\x18\a \x01(\x02R\rbandWidthMbps\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\b \x01(\tR\tpublicKey\x12.\n" +
+	"\x13exit_bandwidth_mbps\x18\t \x01(\x02R\x11exitBandwidthMbps\"b\n" +
+	"\x10PeerIdentityInfo\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x16\n" +
+	"\x06region\x18\x02 \x01(\tR\x06region\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x03 \x01(\tR\tpublicKey\"\xa3\x01\n" +
 	"\x13RemoteSuperResponse\x124\n" +
 	"\vsuper_nodes\x18\x01 \x03(\v2\x13.dvpn.SuperNodeInfoR\n" +
-	"superNodes2g\n" +
+	"superNodes\x12(\n" +
+	"\x04auth\x18\x02 \x01(\v2\x14.dvpn.FederationAuthR\x04auth\x12,\n" +
+	"\x05peers\x18\x03 \x03(\v2\x16.dvpn.PeerIdentityInfoR\x05peers\"\x9f\x01\n" +
+	"\x10FederationMember\x12\x1b\n" +
+	"\tmember_id\x18\x01 \x01(\tR\bmemberId\x12\x18\n" +
+	"\aaddress\x18\x02 \x01(\tR\aaddress\x12\x18\n" +
+	"\aregions\x18\x03 \x03(\tR\aregions\x12\x1c\n" +
+	"\theartbeat\x18\x04 \x01(\x04R\theartbeat\x12\x1c\n" +
+	"\treachable\x18\x05 \x03(\tR\treachable\"\x88\x01\n" +
+	"\rGossipMessage\x12\x1b\n" +
+	"\tsender_id\x18\x01 \x01(\tR\bsenderId\x120\n" +
+	"\amembers\x18\x02 \x03(\v2\x16.dvpn.FederationMemberR\amembers\x12(\n" +
+	"\x04auth\x18\x03 \x01(\v2\x14.dvpn.FederationAuthR\x04auth\"\x98\x01\n" +
+	"\x0eFederationAuth\x12\x16\n" +
+	"\x06region\x18\x01 \x01(\tR\x06region\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\fR\tpublicKey\x12\x1b\n" +
+	"\tsigned_at\x18\x03 \x01(\x03R\bsignedAt\x12\x14\n" +
+	"\x05nonce\x18\x04 \x01(\tR\x05nonce\x12\x1c\n" +
+	"\tsignature\x18\x05 \x01(\fR\tsignature2\x9b\x01\n" +
 	"\x15BaseFederationService\x12N\n" +
-	"\x17RequestRemoteSuperNodes\x12\x18.dvpn.RemoteSuperRequest\x1a\x19.dvpn.RemoteSuperResponseB\x05Z\x03/pbb\x06proto3"
+	"\x17RequestRemoteSuperNodes\x12\x18.dvpn.RemoteSuperRequest\x1a\x19.dvpn.RemoteSuperResponse\x122\n" +
+	"\x06Gossip\x12\x13.dvpn.GossipMessage\x1a\x13.dvpn.GossipMessageB\x05Z\x03/pbb\x06proto3"
 
 var (
 	file_base_sync_proto_rawDescOnce sync.Once
@@ -261,21 +644,32 @@ func file_base_sync_proto_rawDescGZIP() []byte {
 	return file_base_sync_proto_rawDescData
 }
 
-var file_base_sync_proto_msgTypes = make([]protoimpl.MessageInfo, 3)
+var file_base_sync_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
 var file_base_sync_proto_goTypes = []any{
 	(*RemoteSuperRequest)(nil),  // 0: dvpn.RemoteSuperRequest
 	(*SuperNodeInfo)(nil),       // 1: dvpn.SuperNodeInfo
-	(*RemoteSuperResponse)(nil), // 2: dvpn.RemoteSuperResponse
+	(*PeerIdentityInfo)(nil),    // 2: dvpn.PeerIdentityInfo
+	(*RemoteSuperResponse)(nil), // 3: dvpn.RemoteSuperResponse
+	(*FederationMember)(nil),    // 4: dvpn.FederationMember
+	(*GossipMessage)(nil),       // 5: dvpn.GossipMessage
+	(*FederationAuth)(nil),      // 6: dvpn.FederationAuth
 }
 var file_base_sync_proto_depIdxs = []int32{
-	1, // 0: dvpn.RemoteSuperResponse.super_nodes:type_name -> dvpn.SuperNodeInfo
-	0, // 1: dvpn.BaseFederationService.RequestRemoteSuperNodes:input_type -> dvpn.RemoteSuperRequest
-	2, // 2: dvpn.BaseFederationService.RequestRemoteSuperNodes:output_type -> dvpn.RemoteSuperResponse
-	2, // [2:3] is the sub-list for method output_type
-	1, // [1:2] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	6, // 0: dvpn.RemoteSuperRequest.auth:type_name -> dvpn.FederationAuth
+	1, // 1: dvpn.RemoteSuperResponse.super_nodes:type_name -> dvpn.SuperNodeInfo
+	6, // 2: dvpn.RemoteSuperResponse.auth:type_name -> dvpn.FederationAuth
+	2, // 3: dvpn.RemoteSuperResponse.peers:type_name -> dvpn.PeerIdentityInfo
+	4, // 4: dvpn.GossipMessage.members:type_name -> dvpn.FederationMember
+	6, // 5: dvpn.GossipMessage.auth:type_name -> dvpn.FederationAuth
+	0, // 6: dvpn.BaseFederationService.RequestRemoteSuperNodes:input_type -> dvpn.RemoteSuperRequest
+	5, // 7: dvpn.BaseFederationService.Gossip:input_type -> dvpn.GossipMessage
+	3, // 8: dvpn.BaseFederationService.RequestRemoteSuperNodes:output_type -> dvpn.RemoteSuperResponse
+	5, // 9: dvpn.BaseFederationService.Gossip:output_type -> dvpn.GossipMessage
+	8, // [8:10] is the sub-list for method output_type
+	6, // [6:8] is the sub-list for method input_type
+	6, // [6:6] is the sub-list for extension type_name
+	6, // [6:6] is the sub-list for extension extendee
+	0, // [0:6] is the sub-list for field type_name
 }
 
 func init() { file_base_sync_proto_init() }
@@ -289,7 +683,7 @@ func file_base_sync_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_base_sync_proto_rawDesc), len(file_base_sync_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   3,
+			NumMessages:   7,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -20,6 +20,7 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	BaseFederationService_RequestRemoteSuperNodes_FullMethodName = "/dvpn.BaseFederationService/RequestRemoteSuperNodes"
+	BaseFederationService_Gossip_FullMethodName                  = "/dvpn.BaseFederationService/Gossip"
 )
 
 // BaseFederationServiceClient is the client API for BaseFederationService service.
@@ -27,6 +28,7 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type BaseFederationServiceClient interface {
 	RequestRemoteSuperNodes(ctx context.Context, in *RemoteSuperRequest, opts ...grpc.CallOption) (*RemoteSuperResponse, error)
+	Gossip(ctx context.Context, in *GossipMessage, opts ...grpc.CallOption) (*GossipMessage, error)
 }
 
 type baseFederationServiceClient struct {
@@ -47,11 +49,22 @@ func (c *baseFederationServiceClient) RequestRemoteSuperNodes(ctx context.Contex
 	return out, nil
 }
 
+func (c *baseFederationServiceClient) Gossip(ctx context.Context, in *GossipMessage, opts ...grpc.CallOption) (*GossipMessage, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GossipMessage)
+	err := c.cc.Invoke(ctx, BaseFederationService_Gossip_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BaseFederationServiceServer is the server API for BaseFederationService service.
 // All implementations must embed UnimplementedBaseFederationServiceServer
 // for forward compatibility.
 type BaseFederationServiceServer interface {
 	RequestRemoteSuperNodes(context.Context, *RemoteSuperRequest) (*RemoteSuperResponse, error)
+	Gossip(context.Context, *GossipMessage) (*GossipMessage, error)
 	mustEmbedUnimplementedBaseFederationServiceServer()
 }
 
@@ -65,6 +78,9 @@ type UnimplementedBaseFederationServiceServer struct{}
 func (UnimplementedBaseFederationServiceServer) RequestRemoteSuperNodes(context.Context, *RemoteSuperRequest) (*RemoteSuperResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RequestRemoteSuperNodes not implemented")
 }
+func (UnimplementedBaseFederationServiceServer) Gossip(context.Context, *GossipMessage) (*GossipMessage, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Gossip not implemented")
+}
 func (UnimplementedBaseFederationServiceServer) mustEmbedUnimplementedBaseFederationServiceServer() {}
 func (UnimplementedBaseFederationServiceServer) testEmbeddedByValue()                               {}
 
@@ -104,6 +120,24 @@ func _BaseFederationService_RequestRemoteSuperNodes_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BaseFederationService_Gossip_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GossipMessage)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BaseFederationServiceServer).Gossip(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BaseFederationService_Gossip_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BaseFederationServiceServer).Gossip(ctx, req.(*GossipMessage))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BaseFederationService_ServiceDesc is the grpc.ServiceDesc for BaseFederationService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,6 +149,10 @@ var BaseFederationService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RequestRemoteSuperNodes",
 			Handler:    _BaseFederationService_RequestRemoteSuperNodes_Handler,
 		},
+		{
+			MethodName: "Gossip",
+			Handler:    _BaseFederationService_Gossip_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "base_sync.proto",
@@ -0,0 +1,165 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	caCertFile = "ca.crt"
+	caKeyFile  = "ca.key"
+
+	caValidity = 10 * 365 * 24 * time.Hour
+)
+
+// InitCA creates a new network CA in dir. It refuses to overwrite an
+// existing CA key.
+func InitCA(dir, name string) error {
+	keyPath := filepath.Join(dir, caKeyFile)
+	if _, err := os.Stat(keyPath); err == nil {
+		return fmt.Errorf("CA already exists in %s", dir)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+
+	serial, err := newSerial()
+	if err != nil {
+		return err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, pub, priv)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteKey(keyPath, priv); err != nil {
+		return err
+	}
+	return WriteCert(filepath.Join(dir, caCertFile), der)
+}
+
+// IssueRequest describes a node certificate to issue.
+type IssueRequest struct {
+	Name     string
+	Role     string // recorded as the organizational unit
+	IPs      []net.IP
+	DNSNames []string
+	Validity time.Duration
+	// PublicKey is the node's existing identity key. When nil a fresh
+	// ed25519 key is generated and returned.
+	PublicKey ed25519.PublicKey
+}
+
+// Issue signs a node certificate with the CA in dir. Node certificates are
+// valid for both server and client auth since every node does both. The
+// returned key is nil when req.PublicKey was given.
+func Issue(dir string, req IssueRequest) ([]byte, ed25519.PrivateKey, error) {
+	caCert, caKey, err := loadCA(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pub := req.PublicKey
+	var priv ed25519.PrivateKey
+	if pub == nil {
+		pub, priv, err = ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	serial, err := newSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: req.Name, OrganizationalUnit: []string{req.Role}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(req.Validity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  req.IPs,
+		DNSNames:     req.DNSNames,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, caCert, pub, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return der, priv, nil
+}
+
+func loadCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	certPEM, err := os.ReadFile(filepath.Join(dir, caCertFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("invalid CA certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	keyPEM, err := os.ReadFile(filepath.Join(dir, caKeyFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CA key: %w", err)
+	}
+	block, _ = pem.Decode(keyPEM)
+	if block == nil {
+		return nil, nil, fmt.Errorf("invalid CA key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return nil, nil, fmt.Errorf("CA key cannot sign")
+	}
+	return cert, signer, nil
+}
+
+func newSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
+
+// WriteCert writes der as a PEM certificate.
+func WriteCert(path string, der []byte) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
+
+// WriteKey writes key as a PEM PKCS#8 private key readable only by us.
+func WriteKey(path string, key ed25519.PrivateKey) error {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600)
+}
@@ -5,9 +5,11 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"sync"
 	"time"
 
 	pb "Base_node/pb"
+	"Dvpn_common/replay"
 
 	"google.golang.org/protobuf/types/known/emptypb"
 )
@@ -26,34 +28,137 @@ type SuperNodeInfo struct {
 	BandwidthMbps float32
 	AvgLatency    float32
 	ExitPeers     int32
+	ActivePeers   int32
+	CPUPercent    float32
+	MemoryMB      float32
+	// ExitBandwidth is the most one of the node's exits can still grant,
+	// or exitBandwidthUnknown.
+	ExitBandwidth float32
 }
 
 type BaseNodeServer struct {
 	pb.UnimplementedBaseNodeServiceServer
-	localRegion          string
-	registeredSuperNodes map[string]*SuperNodeInfo
+	localRegion string
+	registry    *SuperNodeRegistry
+	locator     *RegionLocator
+	federation  *Membership
+	replica     *RaftNode
+	leader      *client.LeaderClient
+	superReplay *replay.Guard
+	peerReplay  *replay.Guard
+	pins        *KeyPins
+	allowlist   map[string]bool
+	peers       *PeerIdentityTable
+
+	pendingMu  sync.Mutex
+	pending    map[string]*heartbeatRecord
+	lastSigned map[string]int64
+
+	// stop ends the monitoring loops and the watch streams on shutdown.
+	stop     chan struct{}
+	stopOnce sync.Once
+	loops    sync.WaitGroup
 }
 
-func NewBaseNodeServer(local string) *BaseNodeServer {
+func NewBaseNodeServer(local string, registry *SuperNodeRegistry) *BaseNodeServer {
 	return &BaseNodeServer{
-		localRegion:          local,
-		registeredSuperNodes: make(map[string]*SuperNodeInfo),
+		localRegion: local,
+		registry:    registry,
+		leader:      client.NewLeaderClient(),
+		superReplay: replay.NewGuard(replay.MaxClockSkew, replay.NoncesPerID, replay.MaxSigners),
+		peerReplay:  replay.NewGuard(replay.MaxClockSkew, replay.NoncesPerID, replay.MaxSigners),
+		pins:        &KeyPins{pins: make(map[string]string)},
+		peers:       NewPeerIdentityTable(),
+		pending:     make(map[string]*heartbeatRecord),
+		lastSigned:  make(map[string]int64),
+		stop:        make(chan struct{}),
 	}
 }
 
+// Registry exposes the super node registry so other components can
+// subscribe to lifecycle events.
+func (s *BaseNodeServer) Registry() *SuperNodeRegistry {
+	return s.registry
+}
+
+// SetFederation connects the base node to the federation membership used to
+// route requests for remote regions.
+func (s *BaseNodeServer) SetFederation(m *Membership) {
+	s.federation = m
+}
+
+// SetKeyPins replaces the in-memory key pins with a persistent table.
+func (s *BaseNodeServer) SetKeyPins(p *KeyPins) {
+	s.pins = p
+}
+
+// SetKeyAllowlist restricts registration to super nodes whose public key is
+// in keys.
+func (s *BaseNodeServer) SetKeyAllowlist(keys map[string]bool) {
+	s.allowlist = keys
+}
+
+// checkIdentity ensures req.NodeId belongs to req.PublicKey: the ID must be
+// derived from the key, the key must be allowlisted (if a list is set) and
+// must match the key pinned at first registration.
+func (s *BaseNodeServer) checkIdentity(req *pb.RegisterRequest) error {
+	expected, err := DeriveNodeID("super", req.Region, req.PublicKey)
+	if err != nil {
+		return err
+	}
+	if req.NodeId != expected {
+		return fmt.Errorf("%w: got %s, want %s", ErrIDMismatch, req.NodeId, expected)
+	}
+	if s.allowlist != nil && !s.allowlist[req.PublicKey] {
+		return ErrKeyNotAllowed
+	}
+	return s.pins.Check(req.NodeId, req.PublicKey)
+}
+
 func (s *BaseNodeServer) RegisterSuperNode(ctx context.Context, req *pb.RegisterRequest) (*pb.RegisterResponse, error) {
-	valid := VerifySuperNodeSignature(req.NodeId, req.Region, req.Ip, req.Nonce, req.PublicKey, req.Signature)
+	if err := checkCaller(ctx, req.PublicKey); err != nil {
+		log.Printf("❌ Rejected registration of %s: %v", req.NodeId, err)
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	leader, forward, err := s.writeLeader()
+	if err != nil {
+		return nil, err
+	}
+	if forward {
+		return s.leader.ForwardRegister(ctx, leader, req)
+	}
+
+	if err := VerifySuperNodeSignature(req.NodeId, req.Region, req.Ip, req.Nonce, req.SignedAt, req.PublicKey, req.Signature); err != nil {
+		log.Printf("❌ Rejected registration of %s: %v", req.NodeId, err)
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	if err := s.checkIdentity(req); err != nil {
+		log.Printf("❌ Rejected registration of %s: %v", req.NodeId, err)
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
 
-	if !valid {
+	if err := s.superReplay.Check(req.NodeId, req.Nonce, req.SignedAt); err != nil {
+		log.Printf("❌ Rejected registration of %s: %v", req.NodeId, err)
 		return &pb.RegisterResponse{
 			Success: false,
-			Message: "Invalid signature",
+			Message: err.Error(),
 		}, nil
 	}
 
 	log.Println("Valid signature")
 
-	s.registeredSuperNodes[req.NodeId] = &SuperNodeInfo{
+	err = s.commit(&registryCommand{Op: opRegister, Node: &SuperNodeInfo{
 		NodeID:        req.NodeId,
 		Region:        req.Region,
 		IP:            req.Ip,
@@ -64,6 +169,11 @@ func (s *BaseNodeServer) RegisterSuperNode(ctx context.Context, req *pb.Register
 		RegisteredAt:  time.Now().Format(time.RFC3339),
 		LastHeartbeat: time.Now(),
 		Port:          req.Port,
+		ExitBandwidth: exitBandwidthUnknown, // until the first heartbeat
+	}})
+	if err != nil {
+		log.Printf("❌ Failed to commit registration of %s: %v", req.NodeId, err)
+		return nil, err
 	}
 
 	log.Printf("👤 Registered Super Node: %s [%s] IP: %s:%s", req.NodeId, req.Region, req.Ip, req.Port)
@@ -77,11 +187,29 @@ func (s *BaseNodeServer) RegisterSuperNode(ctx context.Context, req *pb.Register
 }
 
 func (s *BaseNodeServer) SuperNodeHeartbeat(ctx context.Context, req *pb.HeartbeatRequest) (*pb.Ack, error) {
-	log.Printf("💓 Heartbeat from %s | Peers: %d | Latency: %.1fms | Bandwidth: %.2fMbps",
-		req.NodeId, req.ActivePeers, req.AvgLatencyMs, req.BandwidthUsageMbps)
+	log.Printf("💓 Heartbeat from %s | Peers: %d | Exits: %d | Latency: %.1fms | Bandwidth: %.2fMbps | CPU: %.1f%% | Mem: %.1fMB",
+		req.NodeId, req.ActivePeers, req.ExitPeersAvailable, req.AvgLatencyMs, req.BandwidthUsageMbps, req.CpuPercent, req.MemoryMb)
+
+	node, found := s.registry.Get(req.NodeId)
+	if found {
+		if err := s.checkHeartbeat(ctx, req, node.PublicKey); err != nil {
+			log.Printf("❌ Rejected heartbeat for %s: %v", req.NodeId, err)
+			return &pb.Ack{
+				Received: false,
+				Message:  err.Error(),
+			}, nil
+		}
+	}
+
+	leader, forward, err := s.writeLeader()
+	if err != nil {
+		return nil, err
+	}
+	if forward {
+		return s.leader.ForwardHeartbeat(ctx, leader, req)
+	}
 
-	node := s.registeredSuperNodes[req.NodeId]
-	if node == nil {
+	if !found {
 		log.Printf("❌ Super Node %s not found", req.NodeId)
 		return &pb.Ack{
 			Received: false,
@@ -89,69 +217,147 @@ func (s *BaseNodeServer) SuperNodeHeartbeat(ctx context.Context, req *pb.Heartbe
 		}, nil
 	}
 
-	node.LastHeartbeat = time.Now()
-	node.BandwidthMbps = req.BandwidthUsageMbps
-	node.AvgLatency = req.AvgLatencyMs
-	log.Printf("Heartbeat from %s | Last heartbeat: %s", req.NodeId, node.LastHeartbeat.Format(time.RFC3339))
+	if err := s.recordHeartbeat(req, time.Now()); err != nil {
+		log.Printf("❌ Rejected heartbeat for %s: %v", req.NodeId, err)
+		return &pb.Ack{
+			Received: false,
+			Message:  err.Error(),
+		}, nil
+	}
+
+	log.Printf("Heartbeat from %s | Last heartbeat: %s", req.NodeId, time.Now().Format(time.RFC3339))
 	return &pb.Ack{
 		Received: true,
 		Message:  "Heartbeat received",
 	}, nil
 }
 
+// checkHeartbeat ensures a heartbeat comes from the super it names: the
+// caller's certificate must be the super's or a relaying base's, and the
+// heartbeat must carry a recent signature by the super's registered key.
+func (s *BaseNodeServer) checkHeartbeat(ctx context.Context, req *pb.HeartbeatRequest, pubKeyBase64 string) error {
+	if err := checkCaller(ctx, pubKeyBase64); err != nil {
+		return err
+	}
+	if err := VerifyHeartbeatSignature(req, pubKeyBase64); err != nil {
+		return err
+	}
+
+	signedAt := time.Unix(req.SignedAt, 0)
+	skew := time.Since(signedAt)
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > replay.MaxClockSkew {
+		return fmt.Errorf("%w: heartbeat signed at %s", replay.ErrStaleTimestamp, signedAt.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// StartSuperNodeMonitoring runs the registry sweeper and the heartbeat
+// batcher, and logs every lifecycle transition the registry produces, until
+// Stop is called.
 func (s *BaseNodeServer) StartSuperNodeMonitoring() {
+	s.loops.Add(3)
 	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		for range ticker.C {
-			now := time.Now()
-			log.Println("🔎Searching for stale Super Nodes")
-			for id, node := range s.registeredSuperNodes {
-				age := now.Sub(node.LastHeartbeat)
-
-				status := "Alive"
-				if age > 2*time.Minute {
-					status = "Stale"
-				}
-
-				log.Printf("🛰  %s | Region: %s | IP: %s | LastHeartbeat: %s | Status: %s",
-					id, node.Region, node.IP, node.LastHeartbeat.Format(time.RFC3339), status)
+		defer s.loops.Done()
+		// Subscribe again whenever the registry drops us for falling behind
+		for {
+			events, cancel := s.registry.Subscribe()
+			if !s.logEvents(events) {
+				cancel()
+				return
 			}
-			log.Println("End of super Node monitoring cycle")
 		}
 	}()
+	go func() {
+		defer s.loops.Done()
+		s.registry.Run(s.stop, s.evictDead)
+	}()
+	go func() {
+		defer s.loops.Done()
+		s.runHeartbeatBatches(s.stop)
+	}()
 }
 
-func (s *BaseNodeServer) GetActiveSuperNodes(ctx context.Context, _ *emptypb.Empty) (*pb.SuperNodeList, error) {
-	now := time.Now()
-	list := &pb.SuperNodeList{}
+// logEvents logs events until their channel closes, or reports false once
+// the server stops.
+func (s *BaseNodeServer) logEvents(events <-chan NodeEvent) bool {
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return true
+			}
+			if ev.Type == NodeUpdated {
+				continue // already logged as a heartbeat
+			}
+			log.Printf("🛰  %s | Region: %s | IP: %s | LastHeartbeat: %s | Status: %s",
+				ev.Node.NodeID, ev.Node.Region, ev.Node.IP, ev.Node.LastHeartbeat.Format(time.RFC3339), ev.Type)
+		case <-s.stop:
+			return false
+		}
+	}
+}
 
-	for _, node := range s.registeredSuperNodes {
-		isAlive := now.Sub(node.LastHeartbeat) <= 2*time.Minute
+// Stop ends the watch streams and waits for the monitoring loops to finish,
+// so nothing writes to the registry store afterwards but RPCs still being
+// served.
+func (s *BaseNodeServer) Stop() {
+	s.stopOnce.Do(func() { close(s.stop) })
+	s.loops.Wait()
+}
 
-		list.Nodes = append(list.Nodes, &pb.SuperNode{
-			NodeId:          node.NodeID,
-			Region:          node.Region,
-			Ip:              node.IP,
-			Version:         node.Version,
-			LatestHeartbeat: node.LastHeartbeat.Format(time.RFC3339),
-			IsAlive:         isAlive,
-			Port:            node.Port,
-		})
+// Close closes the connection writes are forwarded to the replication
+// leader over. Call it once the server stopped serving.
+func (s *BaseNodeServer) Close() error {
+	return s.leader.Close()
+}
+
+// GetActiveSuperNodes lists every registered super node. Live nodes with
+// room for more peers come first, best ranked first, followed by live nodes
+// at capacity and then stale ones, so a client taking the first live entry
+// lands on the least loaded super.
+func (s *BaseNodeServer) GetActiveSuperNodes(ctx context.Context, _ *emptypb.Empty) (*pb.SuperNodeList, error) {
+	var open, full, stale []*SuperNodeInfo
+	for _, node := range s.registry.List() {
+		switch {
+		case s.registry.IsStale(node.NodeID):
+			stale = append(stale, node)
+		case atCapacity(node):
+			full = append(full, node)
+		default:
+			open = append(open, node)
+		}
+	}
+	rankSuperNodes(open, s.registry.staleTTL, time.Now())
+
+	list := &pb.SuperNodeList{}
+	for _, node := range append(open, full...) {
+		list.Nodes = append(list.Nodes, superNodeToPB(node, true))
+	}
+	for _, node := range stale {
+		list.Nodes = append(list.Nodes, superNodeToPB(node, false))
 	}
 
-	log.Printf("📡 Returned %d Super Nodes to client peer", len(list.Nodes))
+	log.Printf("📡 Returned %d Super Nodes to client peer (%d with free capacity)", len(list.Nodes), len(open))
 	return list, nil
 }
 
+// GetFilteredSuperNodes returns up to count live super nodes that satisfy
+// the bandwidth and latency limits and still have room for peers, best
+// first. A count of zero or less returns every match.
 func (b *BaseNodeServer) GetFilteredSuperNodes(count int32, minBW float32, maxLatency float32) []*SuperNodeInfo {
 	var filtered []*SuperNodeInfo
-	for _, sn := range b.registeredSuperNodes {
-		if sn.BandwidthMbps >= 0 && sn.AvgLatency <= 1000 {
+	for _, sn := range b.registry.Alive() {
+		if !atCapacity(sn) && meetsConstraints(sn, minBW, maxLatency) {
 			filtered = append(filtered, sn)
 		}
 	}
 
-	if len(filtered) > int(count) {
+	rankSuperNodes(filtered, b.registry.staleTTL, time.Now())
+
+	if count > 0 && len(filtered) > int(count) {
 		filtered = filtered[:count]
 	}
 
@@ -163,26 +369,23 @@ func (s *BaseNodeServer) RequestExitRegion(ctx context.Context, req *pb.ExitRegi
 		local := s.GetFilteredSuperNodes(req.Count, req.MinBandwidthMbps, req.MaxLatencyMs)
 		var list pb.SuperNodeList
 		for _, n := range local {
-			list.Nodes = append(list.Nodes, &pb.SuperNode{
-				NodeId:          n.NodeID,
-				Region:          n.Region,
-				Ip:              n.IP,
-				Port:            n.Port,
-				Version:         n.Version,
-				LatestHeartbeat: n.LastHeartbeat.Format(time.RFC3339),
-				IsAlive:         true,
-			})
+			list.Nodes = append(list.Nodes, superNodeToPB(n, true))
 		}
 
 		return &list, nil
 	}
 
-	targetAddr := lookupBaseAddress(req.DesiredRegion)
-	if targetAddr == "" {
+	if s.federation == nil {
 		return nil, fmt.Errorf("No base node found for region %s", req.DesiredRegion)
 	}
 
-	remoteNodes, err := client.FetchRemoteSupers(targetAddr, req.DesiredRegion, req.Count, req.MinBandwidthMbps, req.MaxLatencyMs)
+	remoteNodes, err := s.federation.FetchSupers(&pb.RemoteSuperRequest{
+		TargetRegion:          req.DesiredRegion,
+		Count:                 req.Count,
+		RequiredBandWidthMbps: req.MinBandwidthMbps,
+		MaxLatencyMs:          req.MaxLatencyMs,
+		HopLimit:              defaultHopLimit,
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -190,26 +393,16 @@ func (s *BaseNodeServer) RequestExitRegion(ctx context.Context, req *pb.ExitRegi
 	var list pb.SuperNodeList
 	for _, sn := range remoteNodes {
 		list.Nodes = append(list.Nodes, &pb.SuperNode{
-			NodeId:  sn.NodeId,
-			Region:  sn.Region,
-			Ip:      sn.Ip,
-			Port:    sn.Port,
-			Version: "0.1",
-			IsAlive: true,
+			NodeId:        sn.NodeId,
+			Region:        sn.Region,
+			Ip:            sn.Ip,
+			Port:          sn.Port,
+			Version:       "0.1",
+			IsAlive:       true,
+			AvgLatencyMs:  sn.AvgLatencyMs,
+			BandwidthMbps: sn.BandWidthMbps,
 		})
 	}
 
 	return &list, nil
 }
-
-func lookupBaseAddress(region string) string {
-	// Office Network Configuration
-	switch region {
-	case "US":
-		return "192.168.1.43:50053" // US Base Node (PC at .43)
-	case "IN":
-		return "192.168.1.104:50051" // India Base Node (PC at .104)
-	default:
-		return ""
-	}
-}
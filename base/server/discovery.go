@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	pb "Base_node/pb"
+)
+
+// discoveryNodeCount is how many super nodes a discovering client receives.
+const discoveryNodeCount = 3
+
+// SetRegionLocator enables Geo-IP region assignment in DiscoverClientRegion.
+// Without a locator every client is assigned to the local region.
+func (s *BaseNodeServer) SetRegionLocator(l *RegionLocator) {
+	s.locator = l
+}
+
+// DiscoverClientRegion assigns a region to the calling peer from its source
+// address and returns the best super nodes for that region.
+func (s *BaseNodeServer) DiscoverClientRegion(ctx context.Context, req *pb.DiscoverRequest) (*pb.DiscoveryResponse, error) {
+	region := s.localRegion
+	ip := peerIP(ctx)
+
+	if s.locator != nil && ip != nil {
+		if located, source, ok := s.locator.Locate(ip); ok {
+			log.Printf("🌍 Located peer %s (%s) in region %s via %s", req.PeerId, ip, located, source)
+			region = located
+		} else {
+			log.Printf("🌍 No region found for peer %s (%s), using local region %s", req.PeerId, ip, s.localRegion)
+		}
+	}
+
+	if region != s.localRegion {
+		nodes, err := s.discoverRemote(region)
+		if err == nil && len(nodes) > 0 {
+			return &pb.DiscoveryResponse{
+				Accepted: true,
+				Region:   region,
+				Message:  "Assigned to region " + region,
+				Nodes:    nodes,
+			}, nil
+		}
+		log.Printf("⚠️ Could not reach super nodes for region %s (%v), assigning local region %s", region, err, s.localRegion)
+		region = s.localRegion
+	}
+
+	var nodes []*pb.SuperNode
+	for _, n := range s.GetFilteredSuperNodes(discoveryNodeCount, 0, 0) {
+		nodes = append(nodes, superNodeToPB(n, true))
+	}
+
+	if len(nodes) == 0 {
+		return &pb.DiscoveryResponse{
+			Accepted: false,
+			Region:   region,
+			Message:  "No super nodes available in region " + region,
+		}, nil
+	}
+
+	log.Printf("📡 Assigned peer %s to region %s with %d Super Nodes", req.PeerId, region, len(nodes))
+	return &pb.DiscoveryResponse{
+		Accepted: true,
+		Region:   region,
+		Message:  "Assigned to region " + region,
+		Nodes:    nodes,
+	}, nil
+}
+
+func (s *BaseNodeServer) discoverRemote(region string) ([]*pb.SuperNode, error) {
+	if s.federation == nil {
+		return nil, nil
+	}
+
+	remote, err := s.federation.FetchSupers(&pb.RemoteSuperRequest{
+		TargetRegion: region,
+		Count:        discoveryNodeCount,
+		HopLimit:     defaultHopLimit,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var nodes []*pb.SuperNode
+	for _, sn := range remote {
+		nodes = append(nodes, &pb.SuperNode{
+			NodeId:        sn.NodeId,
+			Region:        sn.Region,
+			Ip:            sn.Ip,
+			Port:          sn.Port,
+			Version:       "0.1",
+			IsAlive:       true,
+			AvgLatencyMs:  sn.AvgLatencyMs,
+			BandwidthMbps: sn.BandWidthMbps,
+		})
+	}
+	return nodes, nil
+}
+
+func superNodeToPB(n *SuperNodeInfo, alive bool) *pb.SuperNode {
+	return &pb.SuperNode{
+		NodeId:          n.NodeID,
+		Region:          n.Region,
+		Ip:              n.IP,
+		Port:            n.Port,
+		Version:         n.Version,
+		LatestHeartbeat: n.LastHeartbeat.Format(time.RFC3339),
+		IsAlive:         alive,
+		AvgLatencyMs:    n.AvgLatency,
+		BandwidthMbps:   n.BandwidthMbps,
+		ActivePeers:     n.ActivePeers,
+		MaxPeers:        n.MaxPeers,
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"Base_node/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/proto"
+)
+
+// newFederatedBase starts a base node of region with one live super node,
+// serving discovery and federation requests on a local port.
+func newFederatedBase(t *testing.T, region string) (*BaseNodeServer, string) {
+	t.Helper()
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Register(&SuperNodeInfo{NodeID: "super-" + region, Region: region, IP: "10.0.0.1", Port: "50051",
+		BandwidthMbps: 100, LastHeartbeat: time.Now()})
+	srv := NewBaseNodeServer(region, registry)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fed := NewMembership("base-"+region, lis.Addr().String(), region, nil, NewInsecureFederationKeys(region, key))
+	srv.SetFederation(fed)
+
+	g := grpc.NewServer()
+	pb.RegisterBaseNodeServiceServer(g, srv)
+	pb.RegisterBaseFederationServiceServer(g, NewFederationServer(region, srv, fed))
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+	return srv, lis.Addr().String()
+}
+
+// callerAt returns a context of a gRPC call from ip.
+func callerAt(ip string) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}})
+}
+
+func TestDiscoverClientRegion(t *testing.T) {
+	in, _ := newFederatedBase(t, "IN")
+	_, usAddr := newFederatedBase(t, "US")
+	in.federation.Merge([]*pb.FederationMember{{MemberId: "base-US", Address: usAddr, Regions: []string{"US"}, Heartbeat: 1}})
+
+	overrides := filepath.Join(t.TempDir(), "overrides")
+	if err := os.WriteFile(overrides, []byte("1.0.0.0/8 IN\n2.0.0.0/8 US\n3.0.0.0/8 EU\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	l, err := NewRegionLocator("", overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	in.SetRegionLocator(l)
+
+	tests := []struct {
+		name   string
+		ip     string
+		region string
+		super  string
+	}{
+		{"local client", "1.2.3.4", "IN", "super-IN"},
+		{"client of a federated region", "2.3.4.5", "US", "super-US"},
+		{"region without a base", "3.4.5.6", "IN", "super-IN"},
+		{"unknown address", "9.9.9.9", "IN", "super-IN"},
+	}
+	for _, tt := range tests {
+		resp, err := in.DiscoverClientRegion(callerAt(tt.ip), &pb.DiscoverRequest{PeerId: "peer-1"})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !resp.Accepted || resp.Region != tt.region || len(resp.Nodes) != 1 || resp.Nodes[0].NodeId != tt.super {
+			t.Errorf("%s: assigned to %s with %v, want %s with %s", tt.name, resp.Region, resp.Nodes, tt.region, tt.super)
+		}
+	}
+}
+
+func TestDiscoverRemote(t *testing.T) {
+	in, _ := newFederatedBase(t, "IN")
+	_, usAddr := newFederatedBase(t, "US")
+	in.federation.Merge([]*pb.FederationMember{{MemberId: "base-US", Address: usAddr, Regions: []string{"US"}, Heartbeat: 1}})
+	alone := NewBaseNodeServer("IN", in.registry)
+
+	tests := []struct {
+		name    string
+		base    *BaseNodeServer
+		region  string
+		want    []*pb.SuperNode
+		wantErr bool
+	}{
+		{"federated region", in, "US", []*pb.SuperNode{{NodeId: "super-US", Region: "US", Ip: "10.0.0.1", Port: "50051",
+			Version: "0.1", IsAlive: true, BandwidthMbps: 100}}, false},
+		{"region without a base", in, "EU", nil, true},
+		{"no federation", alone, "US", nil, false},
+	}
+	for _, tt := range tests {
+		got, err := tt.base.discoverRemote(tt.region)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: discoverRemote(%s) error = %v, want error %v", tt.name, tt.region, err, tt.wantErr)
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: discoverRemote(%s) = %v, want %v", tt.name, tt.region, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if !proto.Equal(got[i], tt.want[i]) {
+				t.Errorf("%s: node %d = %v, want %v", tt.name, i, got[i], tt.want[i])
+			}
+		}
+	}
+}
@@ -3,24 +3,85 @@ package server
 import (
 	"Base_node/pb"
 	"context"
+	"fmt"
 	"log"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type FederationServer struct {
 	pb.UnimplementedBaseFederationServiceServer
 	localRegion string
 	baseNode    *BaseNodeServer
+	membership  *Membership
 }
 
-func NewFederationServer(region string, base *BaseNodeServer) *FederationServer {
+func NewFederationServer(region string, base *BaseNodeServer, membership *Membership) *FederationServer {
 	return &FederationServer{
 		localRegion: region,
 		baseNode:    base,
+		membership:  membership,
 	}
 }
 
+// Gossip merges the sender's membership view and answers with ours.
+func (s *FederationServer) Gossip(ctx context.Context, req *pb.GossipMessage) (*pb.GossipMessage, error) {
+	if err := s.membership.Keys().VerifyGossip(req, nil); err != nil {
+		log.Printf("🕸  Rejected gossip from %s: %v", req.SenderId, err)
+		return nil, status.Errorf(codes.PermissionDenied, "gossip rejected: %v", err)
+	}
+
+	s.membership.Merge(req.Members)
+
+	resp := s.membership.Digest()
+	if err := s.membership.Keys().SignGossip(resp, req); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 func (s *FederationServer) RequestRemoteSuperNodes(ctx context.Context, req *pb.RemoteSuperRequest) (*pb.RemoteSuperResponse, error) {
-	supers := s.baseNode.GetFilteredSuperNodes(req.Count, req.RequiredBandWidthMbps, req.MaxLatencyMs)
+	keys := s.membership.Keys()
+	if err := keys.VerifyRequest(req); err != nil {
+		log.Printf("❌ Rejected federation request for region %s: %v", req.TargetRegion, err)
+		return nil, status.Errorf(codes.PermissionDenied, "federation request rejected: %v", err)
+	}
+
+	if req.TargetRegion != "" && req.TargetRegion != s.localRegion {
+		// We are a transit hop for another region.
+		if req.HopLimit <= 0 {
+			return nil, fmt.Errorf("hop limit exceeded routing to region %s", req.TargetRegion)
+		}
+		req.HopLimit--
+
+		resp, err := s.membership.Relay(req)
+		if err != nil {
+			return nil, err
+		}
+		log.Printf("🔀 Relayed %d Super Nodes for region %s (via %v)", len(resp.SuperNodes), req.TargetRegion, req.Via)
+		return resp, nil
+	}
+
+	if req.PeerId != "" {
+		resp := &pb.RemoteSuperResponse{}
+		if rec, ok := s.baseNode.peers.Get(req.PeerId, time.Now()); ok {
+			resp.Peers = []*pb.PeerIdentityInfo{{PeerId: req.PeerId, Region: rec.Region, PublicKey: rec.PublicKey}}
+		}
+		log.Printf("🪪 Sending identity of peer %s (%d found)", req.PeerId, len(resp.Peers))
+		if err := keys.SignResponse(resp, req); err != nil {
+			return nil, err
+		}
+		return resp, nil
+	}
+
+	var supers []*SuperNodeInfo
+	if req.NodeId != "" {
+		supers = s.baseNode.lookupSuperNode(req.NodeId)
+	} else {
+		supers = s.baseNode.GetFilteredSuperNodes(req.Count, req.RequiredBandWidthMbps, req.MaxLatencyMs)
+	}
 
 	var nodes []*pb.SuperNodeInfo
 	for _, n := range supers {
@@ -30,14 +91,20 @@ func (s *FederationServer) RequestRemoteSuperNodes(ctx context.Context, req *pb.
 			Port:               n.Port,
 			Region:             n.Region,
 			AvgLatencyMs:       n.AvgLatency,
-			ExitPeersAvailable: 10,
+			ExitPeersAvailable: n.ExitPeers,
 			BandWidthMbps:      n.BandwidthMbps,
+			PublicKey:          n.PublicKey,
+			ExitBandwidthMbps:  n.ExitBandwidth,
 		})
 	}
 
 	log.Printf("🚀 Sending %d Super Nodes to supernode", len(nodes))
 
-	return &pb.RemoteSuperResponse{
+	resp := &pb.RemoteSuperResponse{
 		SuperNodes: nodes,
-	}, nil
+	}
+	if err := keys.SignResponse(resp, req); err != nil {
+		return nil, err
+	}
+	return resp, nil
 }
@@ -0,0 +1,267 @@
+package server
+
+import (
+	"bufio"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"Base_node/pb"
+	"Dvpn_common/replay"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var (
+	ErrUnsignedFederation = errors.New("federation message is not signed")
+	ErrUntrustedRegion    = errors.New("federation key is not trusted for region")
+	ErrNonceMismatch      = errors.New("federation response does not answer our request")
+)
+
+// FederationKeys signs this base node's federation messages with the
+// region's identity key and verifies messages from other bases against the
+// configured trusted region keys. A region without trusted keys is not
+// trusted at all.
+type FederationKeys struct {
+	region  string
+	priv    ed25519.PrivateKey
+	trusted map[string][]ed25519.PublicKey
+	// insecure skips verification; messages are still signed.
+	insecure bool
+	replay   *replay.Guard
+}
+
+func NewFederationKeys(region string, priv ed25519.PrivateKey, trusted map[string][]ed25519.PublicKey) *FederationKeys {
+	return &FederationKeys{
+		region:  region,
+		priv:    priv,
+		trusted: trusted,
+		replay:  replay.NewGuard(replay.MaxClockSkew, replay.NoncesPerID, replay.MaxSigners),
+	}
+}
+
+// NewInsecureFederationKeys signs messages like NewFederationKeys but
+// accepts every message without verifying it. It is for development only.
+func NewInsecureFederationKeys(region string, priv ed25519.PrivateKey) *FederationKeys {
+	k := NewFederationKeys(region, priv, nil)
+	k.insecure = true
+	return k
+}
+
+// PublicKey returns the base64 federation key other regions must trust.
+func (k *FederationKeys) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(k.priv.Public().(ed25519.PublicKey))
+}
+
+// SignRequest signs req. A relayed request keeps the nonce chosen by the
+// origin so the owner's signed response still answers the origin.
+func (k *FederationKeys) SignRequest(req *pb.RemoteSuperRequest) error {
+	nonce := ""
+	if req.Auth != nil {
+		nonce = req.Auth.Nonce
+	}
+	req.Auth = k.newAuth(nonce)
+	return k.sign(req, req.Auth)
+}
+
+// VerifyRequest checks that req was signed by a trusted base and is not a
+// replay.
+func (k *FederationKeys) VerifyRequest(req *pb.RemoteSuperRequest) error {
+	return k.verifyFresh(req, req.Auth)
+}
+
+// SignResponse signs resp as an answer to req.
+func (k *FederationKeys) SignResponse(resp *pb.RemoteSuperResponse, req *pb.RemoteSuperRequest) error {
+	resp.Auth = k.newAuth(requestNonce(req.Auth))
+	return k.sign(resp, resp.Auth)
+}
+
+// VerifyResponse checks that resp answers req and was signed by a base
+// trusted for the region that was asked for.
+func (k *FederationKeys) VerifyResponse(resp *pb.RemoteSuperResponse, req *pb.RemoteSuperRequest) error {
+	if err := k.verify(resp, resp.Auth, req.TargetRegion); err != nil {
+		return err
+	}
+	if !k.insecure && resp.Auth.Nonce != requestNonce(req.Auth) {
+		return ErrNonceMismatch
+	}
+	return nil
+}
+
+// SignGossip signs msg. Replies pass the request they answer, requests nil.
+func (k *FederationKeys) SignGossip(msg, inReplyTo *pb.GossipMessage) error {
+	nonce := ""
+	if inReplyTo != nil {
+		nonce = requestNonce(inReplyTo.Auth)
+	}
+	msg.Auth = k.newAuth(nonce)
+	return k.sign(msg, msg.Auth)
+}
+
+// VerifyGossip checks a gossip request, or with inReplyTo set, the reply to
+// it.
+func (k *FederationKeys) VerifyGossip(msg, inReplyTo *pb.GossipMessage) error {
+	if inReplyTo == nil {
+		return k.verifyFresh(msg, msg.Auth)
+	}
+	if err := k.verify(msg, msg.Auth, ""); err != nil {
+		return err
+	}
+	if !k.insecure && msg.Auth.Nonce != requestNonce(inReplyTo.Auth) {
+		return ErrNonceMismatch
+	}
+	return nil
+}
+
+func (k *FederationKeys) newAuth(nonce string) *pb.FederationAuth {
+	if nonce == "" {
+		b := make([]byte, 16)
+		rand.Read(b)
+		nonce = base64.StdEncoding.EncodeToString(b)
+	}
+	return &pb.FederationAuth{
+		Region:    k.region,
+		PublicKey: k.priv.Public().(ed25519.PublicKey),
+		SignedAt:  time.Now().Unix(),
+		Nonce:     nonce,
+	}
+}
+
+func (k *FederationKeys) sign(m proto.Message, auth *pb.FederationAuth) error {
+	data, err := signedBytes(m, auth)
+	if err != nil {
+		return err
+	}
+	auth.Signature = ed25519.Sign(k.priv, data)
+	return nil
+}
+
+func (k *FederationKeys) verifyFresh(m proto.Message, auth *pb.FederationAuth) error {
+	if err := k.verify(m, auth, ""); err != nil {
+		return err
+	}
+	if k.insecure {
+		return nil
+	}
+	return k.replay.Check(base64.StdEncoding.EncodeToString(auth.PublicKey), auth.Nonce, auth.SignedAt)
+}
+
+// verify checks the signature on m and that its key is trusted for the
+// signer's region, which must be wantRegion unless that is empty.
+func (k *FederationKeys) verify(m proto.Message, auth *pb.FederationAuth, wantRegion string) error {
+	if k.insecure {
+		return nil
+	}
+	if auth == nil || len(auth.Signature) == 0 {
+		return ErrUnsignedFederation
+	}
+	if wantRegion != "" && auth.Region != wantRegion {
+		return fmt.Errorf("%w %s: signed by region %s", ErrUntrustedRegion, wantRegion, auth.Region)
+	}
+	if !k.isTrusted(auth.Region, auth.PublicKey) {
+		return fmt.Errorf("%w %s", ErrUntrustedRegion, auth.Region)
+	}
+
+	data, err := signedBytes(m, auth)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(auth.PublicKey, data, auth.Signature) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+func (k *FederationKeys) isTrusted(region string, key []byte) bool {
+	for _, t := range k.trusted[region] {
+		if t.Equal(ed25519.PublicKey(key)) {
+			return true
+		}
+	}
+	return false
+}
+
+// signedBytes is the deterministic encoding of m with auth's signature
+// cleared.
+func signedBytes(m proto.Message, auth *pb.FederationAuth) ([]byte, error) {
+	sig := auth.Signature
+	auth.Signature = nil
+	defer func() { auth.Signature = sig }()
+
+	return proto.MarshalOptions{Deterministic: true}.Marshal(m)
+}
+
+func requestNonce(auth *pb.FederationAuth) string {
+	if auth == nil {
+		return ""
+	}
+	return auth.Nonce
+}
+
+// LoadOrCreateFederationKey reads the base64 ed25519 private key at path,
+// generating it on first start.
+func LoadOrCreateFederationKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(priv)), 0600); err != nil {
+			return nil, fmt.Errorf("failed to write federation key: %w", err)
+		}
+		return priv, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("invalid federation key in %s", path)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// LoadTrustedRegions reads "<region> <base64 public key>" lines. A region
+// may be listed several times, once per replica key. Blank lines and
+// anything after '#' are ignored.
+func LoadTrustedRegions(path string) (map[string][]ed25519.PublicKey, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	trusted := make(map[string][]ed25519.PublicKey)
+	scanner := bufio.NewScanner(f)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected \"<region> <public key>\"", path, lineNo)
+		}
+
+		key, err := base64.StdEncoding.DecodeString(fields[1])
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s:%d: invalid public key", path, lineNo)
+		}
+		trusted[fields[0]] = append(trusted[fields[0]], ed25519.PublicKey(key))
+	}
+	return trusted, scanner.Err()
+}
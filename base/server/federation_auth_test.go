@@ -0,0 +1,48 @@
+package server
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"Base_node/pb"
+)
+
+func newFederationKey(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestFederationWithoutTrustedKeysFailsClosed(t *testing.T) {
+	in := NewFederationKeys("IN", newFederationKey(t), nil)
+	us := NewFederationKeys("US", newFederationKey(t), nil)
+
+	req := &pb.RemoteSuperRequest{TargetRegion: "US", Count: 1}
+	if err := in.SignRequest(req); err != nil {
+		t.Fatal(err)
+	}
+	if err := us.VerifyRequest(req); !errors.Is(err, ErrUntrustedRegion) {
+		t.Fatalf("request from an untrusted region: got %v, want %v", err, ErrUntrustedRegion)
+	}
+	if err := us.VerifyRequest(&pb.RemoteSuperRequest{TargetRegion: "US"}); !errors.Is(err, ErrUnsignedFederation) {
+		t.Fatalf("unsigned request: got %v, want %v", err, ErrUnsignedFederation)
+	}
+
+	trusting := NewFederationKeys("US", newFederationKey(t), map[string][]ed25519.PublicKey{
+		"IN": {in.priv.Public().(ed25519.PublicKey)},
+	})
+	if err := trusting.VerifyRequest(req); err != nil {
+		t.Fatalf("request from a trusted region rejected: %v", err)
+	}
+
+	// Only an explicitly insecure base skips verification
+	insecure := NewInsecureFederationKeys("US", newFederationKey(t))
+	if err := insecure.VerifyRequest(&pb.RemoteSuperRequest{TargetRegion: "US"}); err != nil {
+		t.Fatalf("insecure federation rejected a request: %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const (
+	snapshotFile = "registry.snapshot"
+	logFile      = "registry.log"
+
+	// compactAfter is the number of log entries written before the log is
+	// folded into a fresh snapshot.
+	compactAfter = 1000
+)
+
+// logEntry is one line of the append-only registry log.
+type logEntry struct {
+	Op     string         `json:"op"`
+	NodeID string         `json:"node_id"`
+	Node   *SuperNodeInfo `json:"node,omitempty"`
+}
+
+// FileStore is an embedded on-disk RegistryStore. Every change is appended
+// to registry.log and the log is periodically compacted into
+// registry.snapshot, so startup only has to read one snapshot plus a short
+// tail of changes.
+type FileStore struct {
+	mu         sync.Mutex
+	dir        string
+	nodes      map[string]*SuperNodeInfo
+	log        *os.File
+	logEntries int
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+
+	fs := &FileStore{
+		dir:   dir,
+		nodes: make(map[string]*SuperNodeInfo),
+	}
+
+	if err := fs.loadSnapshot(); err != nil {
+		return nil, err
+	}
+	if err := fs.replayLog(); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(fs.path(logFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open registry log: %w", err)
+	}
+	fs.log = f
+
+	return fs, nil
+}
+
+func (fs *FileStore) path(name string) string {
+	return filepath.Join(fs.dir, name)
+}
+
+func (fs *FileStore) loadSnapshot() error {
+	f, err := os.Open(fs.path(snapshotFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open registry snapshot: %w", err)
+	}
+	defer f.Close()
+
+	nodes, err := readSnapshot(f)
+	if err != nil {
+		return err
+	}
+	fs.nodes = nodes
+	return nil
+}
+
+// replayLog applies the entries of registry.log. A torn tail left by a
+// crash is cut off, so new entries are not appended onto a broken line.
+func (fs *FileStore) replayLog() error {
+	data, err := os.ReadFile(fs.path(logFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read registry log: %w", err)
+	}
+
+	// good is the end of the last complete, valid line
+	good := 0
+	for good < len(data) {
+		end := bytes.IndexByte(data[good:], '\n')
+		if end < 0 {
+			log.Printf("⚠️ Ignoring unterminated registry log tail (%d bytes)", len(data)-good)
+			break
+		}
+		line := data[good : good+end]
+		if len(line) > 0 {
+			var e logEntry
+			if err := json.Unmarshal(line, &e); err != nil {
+				// A torn final write from a crash is expected; anything after it is unusable.
+				log.Printf("⚠️ Ignoring corrupt registry log tail: %v", err)
+				break
+			}
+			fs.apply(e)
+			fs.logEntries++
+		}
+		good += end + 1
+	}
+
+	if good < len(data) {
+		if err := os.Truncate(fs.path(logFile), int64(good)); err != nil {
+			return fmt.Errorf("failed to cut torn registry log tail: %w", err)
+		}
+	}
+	return nil
+}
+
+func (fs *FileStore) apply(e logEntry) {
+	switch e.Op {
+	case "put":
+		if e.Node != nil {
+			fs.nodes[e.NodeID] = e.Node
+		}
+	case "delete":
+		delete(fs.nodes, e.NodeID)
+	}
+}
+
+func (fs *FileStore) append(e logEntry) error {
+	line, err := json.Marshal(&e)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if _, err := fs.log.Write(line); err != nil {
+		return fmt.Errorf("failed to append to registry log: %w", err)
+	}
+	if err := fs.log.Sync(); err != nil {
+		return fmt.Errorf("failed to sync registry log: %w", err)
+	}
+
+	fs.apply(e)
+	fs.logEntries++
+
+	if fs.logEntries >= compactAfter {
+		if err := fs.compact(); err != nil {
+			log.Printf("⚠️ Registry compaction failed: %v", err)
+		}
+	}
+	return nil
+}
+
+// compact writes the current state to a new snapshot and truncates the log.
+func (fs *FileStore) compact() error {
+	if err := fs.writeSnapshotFile(); err != nil {
+		return err
+	}
+	if err := fs.log.Truncate(0); err != nil {
+		return fmt.Errorf("failed to truncate registry log: %w", err)
+	}
+	fs.logEntries = 0
+	return nil
+}
+
+func (fs *FileStore) writeSnapshotFile() error {
+	tmp := fs.path(snapshotFile + ".tmp")
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create registry snapshot: %w", err)
+	}
+
+	if err := writeSnapshot(f, fs.nodes); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, fs.path(snapshotFile))
+}
+
+func (fs *FileStore) Load() ([]*SuperNodeInfo, error) {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	var out []*SuperNodeInfo
+	for _, n := range fs.nodes {
+		out = append(out, copyNode(n))
+	}
+	return out, nil
+}
+
+func (fs *FileStore) Save(node *SuperNodeInfo) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return fs.append(logEntry{Op: "put", NodeID: node.NodeID, Node: copyNode(node)})
+}
+
+func (fs *FileStore) Delete(nodeID string) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if _, ok := fs.nodes[nodeID]; !ok {
+		return nil
+	}
+	return fs.append(logEntry{Op: "delete", NodeID: nodeID})
+}
+
+func (fs *FileStore) Export(w io.Writer) error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	return writeSnapshot(w, fs.nodes)
+}
+
+func (fs *FileStore) Import(r io.Reader) error {
+	nodes, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	fs.nodes = nodes
+	return fs.compact()
+}
+
+func (fs *FileStore) Close() error {
+	fs.mu.Lock()
+	defer fs.mu.Unlock()
+
+	if err := fs.compact(); err != nil {
+		log.Printf("⚠️ Registry compaction on close failed: %v", err)
+	}
+	return fs.log.Close()
+}
@@ -0,0 +1,240 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testNode returns a registry record with every field set.
+func testNode(id string) *SuperNodeInfo {
+	return &SuperNodeInfo{
+		NodeID:        id,
+		Region:        "IN",
+		IP:            "10.0.0.1",
+		Port:          "50052",
+		PublicKey:     "key-" + id,
+		Version:       "1.0",
+		MaxPeers:      50,
+		RegisteredAt:  "2026-01-01T00:00:00Z",
+		LastHeartbeat: time.Now().Truncate(time.Second),
+		BandwidthMbps: 100,
+		AvgLatency:    12.5,
+		ExitPeers:     2,
+		ActivePeers:   7,
+		CPUPercent:    30,
+		MemoryMB:      512,
+		ExitBandwidth: 40,
+	}
+}
+
+// loadAll returns the records of fs by node ID.
+func loadAll(t *testing.T, fs *FileStore) map[string]*SuperNodeInfo {
+	t.Helper()
+	nodes, err := fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := make(map[string]*SuperNodeInfo)
+	for _, n := range nodes {
+		out[n.NodeID] = n
+	}
+	return out
+}
+
+// assertNodes fails unless got holds exactly the records of want.
+func assertNodes(t *testing.T, got map[string]*SuperNodeInfo, want ...*SuperNodeInfo) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for _, w := range want {
+		g, ok := got[w.NodeID]
+		if !ok {
+			t.Fatalf("record %s missing", w.NodeID)
+		}
+		if !sameNode(g, w) {
+			t.Fatalf("record %s is %+v, want %+v", w.NodeID, g, w)
+		}
+	}
+}
+
+func TestFileStoreReloadsChanges(t *testing.T) {
+	dir := t.TempDir()
+
+	fs, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b, c := testNode("a"), testNode("b"), testNode("c")
+	for _, n := range []*SuperNodeInfo{a, b, c} {
+		if err := fs.Save(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b.ActivePeers = 9
+	if err := fs.Save(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Delete("c"); err != nil {
+		t.Fatal(err)
+	}
+	crash(fs)
+
+	// Without a compaction on close, the log alone brings the changes back
+	fs, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertNodes(t, loadAll(t, fs), a, b)
+	if err := fs.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// and after one, the snapshot does
+	if info, err := os.Stat(filepath.Join(dir, logFile)); err != nil || info.Size() != 0 {
+		t.Fatalf("registry log not compacted on close: %v", err)
+	}
+	fs, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	assertNodes(t, loadAll(t, fs), a, b)
+}
+
+func TestFileStoreExportImportRoundTrip(t *testing.T) {
+	src, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	a, b := testNode("a"), testNode("b")
+	for _, n := range []*SuperNodeInfo{a, b} {
+		if err := src.Save(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var snap bytes.Buffer
+	if err := src.Export(&snap); err != nil {
+		t.Fatal(err)
+	}
+
+	// Importing replaces whatever the store held
+	dir := t.TempDir()
+	dst, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Save(testNode("stale")); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Import(&snap); err != nil {
+		t.Fatal(err)
+	}
+	assertNodes(t, loadAll(t, dst), a, b)
+	crash(dst)
+
+	dst, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	assertNodes(t, loadAll(t, dst), a, b)
+}
+
+func TestFileStoreSnapshotKeepsLaterLogEntries(t *testing.T) {
+	dir := t.TempDir()
+
+	fs, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enough writes to fold the log into a snapshot on the way
+	var n *SuperNodeInfo
+	for i := 0; i < compactAfter+5; i++ {
+		n = testNode(fmt.Sprintf("node-%d", i%3))
+		n.ActivePeers = int32(i)
+		if err := fs.Save(n); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotFile)); err != nil {
+		t.Fatalf("no snapshot written after %d entries: %v", compactAfter, err)
+	}
+	want := loadAll(t, fs)
+	crash(fs)
+
+	fs, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+	got := loadAll(t, fs)
+	var wantNodes []*SuperNodeInfo
+	for _, w := range want {
+		wantNodes = append(wantNodes, w)
+	}
+	assertNodes(t, got, wantNodes...)
+	if got[n.NodeID].ActivePeers != compactAfter+4 {
+		t.Fatalf("latest write lost: %s has %d active peers, want %d", n.NodeID, got[n.NodeID].ActivePeers, compactAfter+4)
+	}
+}
+
+func TestFileStoreCutsTornLogTail(t *testing.T) {
+	dir := t.TempDir()
+
+	fs, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Save(&SuperNodeInfo{NodeID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	crash(fs)
+
+	// A crash in the middle of the next write leaves a torn line behind
+	f, err := os.OpenFile(filepath.Join(dir, logFile), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"op":"put","node_id":"b","no`)
+	f.Close()
+
+	fs, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fs.Save(&SuperNodeInfo{NodeID: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	crash(fs)
+
+	fs, err = NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fs.Close()
+
+	nodes, err := fs.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]bool)
+	for _, n := range nodes {
+		got[n.NodeID] = true
+	}
+	if len(got) != 2 || !got["a"] || !got["c"] {
+		t.Fatalf("after restart got %v, want a and c", got)
+	}
+}
+
+// crash drops fs the way a killed process would, without the compaction
+// Close does.
+func crash(fs *FileStore) {
+	fs.log.Close()
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/oschwald/maxminddb-golang"
+	"google.golang.org/grpc/peer"
+)
+
+type cidrOverride struct {
+	network *net.IPNet
+	region  string
+}
+
+// RegionLocator assigns a region to a client address. Operator-defined
+// CIDR overrides win; otherwise the country from a local MaxMind-format
+// (mmdb) database is used, mapped through optional country overrides.
+type RegionLocator struct {
+	db        *maxminddb.Reader
+	cidrs     []cidrOverride
+	countries map[string]string
+}
+
+type geoRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	RegisteredCountry struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"registered_country"`
+}
+
+// NewRegionLocator opens the mmdb database at dbPath and reads the
+// overrides file at overridesPath. Either path may be empty.
+//
+// The overrides file holds one rule per line:
+//
+//	192.168.1.0/24 IN     # clients in this network belong to IN
+//	country CA US         # clients geolocated to Canada belong to US
+func NewRegionLocator(dbPath, overridesPath string) (*RegionLocator, error) {
+	l := &RegionLocator{countries: make(map[string]string)}
+
+	if dbPath != "" {
+		db, err := maxminddb.Open(dbPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open geoip database: %w", err)
+		}
+		l.db = db
+	}
+
+	if overridesPath != "" {
+		if err := l.loadOverrides(overridesPath); err != nil {
+			l.Close()
+			return nil, err
+		}
+	}
+
+	return l, nil
+}
+
+func (l *RegionLocator) loadOverrides(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open region overrides: %w", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		switch {
+		case len(fields) == 3 && fields[0] == "country":
+			l.countries[strings.ToUpper(fields[1])] = fields[2]
+		case len(fields) == 2:
+			_, network, err := net.ParseCIDR(fields[0])
+			if err != nil {
+				return fmt.Errorf("%s:%d: invalid CIDR %q: %w", path, lineNo, fields[0], err)
+			}
+			l.cidrs = append(l.cidrs, cidrOverride{network: network, region: fields[1]})
+		default:
+			return fmt.Errorf("%s:%d: expected \"<cidr> <region>\" or \"country <iso> <region>\"", path, lineNo)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// Most specific network first so that nested overrides behave as expected.
+	sort.SliceStable(l.cidrs, func(i, j int) bool {
+		a, _ := l.cidrs[i].network.Mask.Size()
+		b, _ := l.cidrs[j].network.Mask.Size()
+		return a > b
+	})
+	return nil
+}
+
+// Locate returns the region for ip and a short description of how it was
+// chosen. ok is false when neither the overrides nor the database know ip.
+func (l *RegionLocator) Locate(ip net.IP) (region string, source string, ok bool) {
+	for _, o := range l.cidrs {
+		if o.network.Contains(ip) {
+			return o.region, "override " + o.network.String(), true
+		}
+	}
+
+	if l.db == nil {
+		return "", "", false
+	}
+
+	var rec geoRecord
+	if err := l.db.Lookup(ip, &rec); err != nil {
+		return "", "", false
+	}
+
+	country := rec.Country.ISOCode
+	if country == "" {
+		country = rec.RegisteredCountry.ISOCode
+	}
+	if country == "" {
+		return "", "", false
+	}
+
+	if mapped, found := l.countries[country]; found {
+		return mapped, "country " + country, true
+	}
+	return country, "country " + country, true
+}
+
+func (l *RegionLocator) Close() error {
+	if l.db != nil {
+		return l.db.Close()
+	}
+	return nil
+}
+
+// peerIP returns the remote address of the gRPC caller.
+func peerIP(ctx context.Context) net.IP {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return nil
+	}
+
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return nil
+	}
+	return net.ParseIP(host)
+}
@@ -0,0 +1,187 @@
+package server
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+)
+
+// mmdbNode is a node of the search tree of a test database. A side either
+// leads to a child or holds the offset of a record in the data section.
+type mmdbNode struct {
+	child [2]*mmdbNode
+	data  [2]int
+}
+
+func newMMDBNode() *mmdbNode {
+	return &mmdbNode{data: [2]int{-1, -1}}
+}
+
+// writeTestMMDB writes an IPv4 MaxMind-format database that holds each
+// record for its (non-overlapping) CIDR and returns its path.
+func writeTestMMDB(t *testing.T, records map[string]map[string]any) string {
+	t.Helper()
+
+	root := newMMDBNode()
+	var data []byte
+	for cidr, rec := range records {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ip := network.IP.To4()
+		ones, _ := network.Mask.Size()
+		bit := func(i int) int { return int(ip[i/8]>>(7-i%8)) & 1 }
+
+		node := root
+		for i := 0; i < ones-1; i++ {
+			if node.child[bit(i)] == nil {
+				node.child[bit(i)] = newMMDBNode()
+			}
+			node = node.child[bit(i)]
+		}
+		node.data[bit(ones-1)] = len(data)
+		data = append(data, mmdbEncode(rec)...)
+	}
+
+	// Number the nodes breadth first, the root being 0
+	nodes := []*mmdbNode{root}
+	index := map[*mmdbNode]int{root: 0}
+	for i := 0; i < len(nodes); i++ {
+		for _, c := range nodes[i].child {
+			if c != nil {
+				index[c] = len(nodes)
+				nodes = append(nodes, c)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	count := len(nodes)
+	for _, n := range nodes {
+		for side := 0; side < 2; side++ {
+			rec := count // no data
+			switch {
+			case n.child[side] != nil:
+				rec = index[n.child[side]]
+			case n.data[side] >= 0:
+				rec = count + 16 + n.data[side]
+			}
+			buf.Write([]byte{byte(rec >> 16), byte(rec >> 8), byte(rec)})
+		}
+	}
+	buf.Write(make([]byte, 16))
+	buf.Write(data)
+	buf.WriteString("\xAB\xCD\xEFMaxMind.com")
+	buf.Write(mmdbEncode(map[string]any{
+		"node_count":                  uint32(count),
+		"record_size":                 uint16(24),
+		"ip_version":                  uint16(4),
+		"binary_format_major_version": uint16(2),
+		"binary_format_minor_version": uint16(0),
+		"database_type":               "Test-Country",
+	}))
+
+	path := filepath.Join(t.TempDir(), "test.mmdb")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// mmdbEncode encodes v in the MaxMind DB data format. Only the types the
+// test databases need are supported.
+func mmdbEncode(v any) []byte {
+	control := func(typ, size int) []byte { return []byte{byte(typ<<5 | size)} }
+	unsigned := func(typ int, x uint64, width int) []byte {
+		b := make([]byte, 8)
+		binary.BigEndian.PutUint64(b, x)
+		b = bytes.TrimLeft(b[8-width:], "\x00")
+		return append(control(typ, len(b)), b...)
+	}
+
+	switch v := v.(type) {
+	case string:
+		return append(control(2, len(v)), v...)
+	case uint16:
+		return unsigned(5, uint64(v), 2)
+	case uint32:
+		return unsigned(6, uint64(v), 4)
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		out := control(7, len(v))
+		for _, k := range keys {
+			out = append(out, mmdbEncode(k)...)
+			out = append(out, mmdbEncode(v[k])...)
+		}
+		return out
+	default:
+		panic("mmdbEncode: unsupported type")
+	}
+}
+
+func TestRegionLocatorLocate(t *testing.T) {
+	country := func(iso string) map[string]any {
+		return map[string]any{"country": map[string]any{"iso_code": iso}}
+	}
+	db := writeTestMMDB(t, map[string]map[string]any{
+		"10.0.0.0/8": country("DE"),
+		"20.0.0.0/8": country("CA"),
+		"30.0.0.0/8": {"registered_country": map[string]any{"iso_code": "JP"}},
+		"40.0.0.0/8": {},
+	})
+
+	// The nested network is listed after its parent on purpose
+	overrides := filepath.Join(t.TempDir(), "overrides")
+	rules := "10.1.0.0/16 EU\n10.1.2.0/24 IN  # more specific wins\ncountry CA US\n"
+	if err := os.WriteFile(overrides, []byte(rules), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := NewRegionLocator(db, overrides)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	tests := []struct {
+		name   string
+		ip     string
+		region string
+		source string
+		ok     bool
+	}{
+		{"most specific override", "10.1.2.3", "IN", "override 10.1.2.0/24", true},
+		{"override over the database", "10.1.9.9", "EU", "override 10.1.0.0/16", true},
+		{"database country", "10.9.9.9", "DE", "country DE", true},
+		{"mapped country", "20.1.1.1", "US", "country CA", true},
+		{"registered country", "30.1.1.1", "JP", "country JP", true},
+		{"record without a country", "40.1.1.1", "", "", false},
+		{"address not in the database", "50.1.1.1", "", "", false},
+	}
+	for _, tt := range tests {
+		region, source, ok := l.Locate(net.ParseIP(tt.ip))
+		if region != tt.region || source != tt.source || ok != tt.ok {
+			t.Errorf("%s: Locate(%s) = %q, %q, %v, want %q, %q, %v",
+				tt.name, tt.ip, region, source, ok, tt.region, tt.source, tt.ok)
+		}
+	}
+}
+
+func TestRegionLocatorWithoutDatabase(t *testing.T) {
+	l, err := NewRegionLocator("", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if region, _, ok := l.Locate(net.ParseIP("10.1.2.3")); ok {
+		t.Fatalf("locator without rules placed an address in %s", region)
+	}
+}
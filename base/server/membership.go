@@ -0,0 +1,421 @@
+package server
+
+import (
+	"Base_node/client"
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"Base_node/pb"
+)
+
+const (
+	gossipInterval = 5 * time.Second
+	gossipFanout   = 3
+
+	// A member whose heartbeat has not advanced for suspectAfter is not used
+	// for routing; after deadAfter it is dropped from the table.
+	suspectAfter = 30 * time.Second
+	deadAfter    = 2 * time.Minute
+
+	// defaultHopLimit bounds how many intermediate bases a federation
+	// request may transit.
+	defaultHopLimit = 2
+)
+
+type member struct {
+	id        string
+	address   string
+	regions   []string
+	heartbeat uint64
+	reachable []string
+	updatedAt time.Time
+}
+
+func (m *member) owns(region string) bool {
+	for _, r := range m.regions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *member) canReach(id string) bool {
+	for _, r := range m.reachable {
+		if r == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Membership is this base node's view of the federation. Bases learn about
+// each other from a few seed addresses and then spread region ownership and
+// reachability by periodic push-pull gossip.
+type Membership struct {
+	mu          sync.Mutex
+	self        *member
+	members     map[string]*member
+	tombstones  map[string]uint64
+	lastContact map[string]time.Time
+	seeds       []string
+	keys        *FederationKeys
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	loop     sync.WaitGroup
+}
+
+func NewMembership(id, address, region string, seeds []string, keys *FederationKeys) *Membership {
+	var filtered []string
+	for _, s := range seeds {
+		if s != "" && s != address {
+			filtered = append(filtered, s)
+		}
+	}
+
+	return &Membership{
+		self: &member{
+			id:        id,
+			address:   address,
+			regions:   []string{region},
+			updatedAt: time.Now(),
+		},
+		members:     make(map[string]*member),
+		tombstones:  make(map[string]uint64),
+		lastContact: make(map[string]time.Time),
+		seeds:       filtered,
+		keys:        keys,
+		stop:        make(chan struct{}),
+	}
+}
+
+// Keys returns the keys used to sign and verify federation messages.
+func (m *Membership) Keys() *FederationKeys {
+	return m.keys
+}
+
+// ID returns this base node's federation member ID.
+func (m *Membership) ID() string {
+	return m.self.id
+}
+
+// Start runs the gossip loop in the background until Stop is called.
+func (m *Membership) Start() {
+	m.loop.Add(1)
+	go func() {
+		defer m.loop.Done()
+		ticker := time.NewTicker(gossipInterval)
+		defer ticker.Stop()
+
+		m.gossipRound()
+		for {
+			select {
+			case <-ticker.C:
+				m.gossipRound()
+			case <-m.stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop ends the gossip loop started by Start and waits for its round in
+// progress.
+func (m *Membership) Stop() {
+	m.stopOnce.Do(func() { close(m.stop) })
+	m.loop.Wait()
+}
+
+func (m *Membership) gossipRound() {
+	m.mu.Lock()
+	m.self.heartbeat++
+	m.self.reachable = m.directlyReachableLocked(time.Now())
+	m.expireLocked(time.Now())
+	targets := m.pickTargetsLocked()
+	msg := m.digestLocked()
+	m.mu.Unlock()
+
+	if err := m.keys.SignGossip(msg, nil); err != nil {
+		log.Printf("🕸  Failed to sign gossip: %v", err)
+		return
+	}
+
+	for _, addr := range targets {
+		resp, err := client.Gossip(addr, msg)
+		if err != nil {
+			log.Printf("🕸  Gossip to %s failed: %v", addr, err)
+			continue
+		}
+		if err := m.keys.VerifyGossip(resp, msg); err != nil {
+			log.Printf("🕸  Ignoring gossip reply from %s: %v", addr, err)
+			continue
+		}
+
+		m.mu.Lock()
+		m.lastContact[resp.SenderId] = time.Now()
+		m.mu.Unlock()
+		m.Merge(resp.Members)
+	}
+}
+
+// pickTargetsLocked selects up to gossipFanout member addresses, falling
+// back to seeds while the table is still empty.
+func (m *Membership) pickTargetsLocked() []string {
+	var addrs []string
+	for _, mem := range m.members {
+		addrs = append(addrs, mem.address)
+	}
+	if len(addrs) == 0 {
+		addrs = append(addrs, m.seeds...)
+	}
+
+	rand.Shuffle(len(addrs), func(i, j int) { addrs[i], addrs[j] = addrs[j], addrs[i] })
+	if len(addrs) > gossipFanout {
+		addrs = addrs[:gossipFanout]
+	}
+	return addrs
+}
+
+func (m *Membership) directlyReachableLocked(now time.Time) []string {
+	var ids []string
+	for id, at := range m.lastContact {
+		if now.Sub(at) <= suspectAfter {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+func (m *Membership) expireLocked(now time.Time) {
+	for id, mem := range m.members {
+		if now.Sub(mem.updatedAt) > deadAfter {
+			log.Printf("🕸  Federation member %s (%s) is dead, removing", id, mem.address)
+			m.tombstones[id] = mem.heartbeat
+			delete(m.members, id)
+			delete(m.lastContact, id)
+		}
+	}
+}
+
+func toPBMember(mem *member) *pb.FederationMember {
+	return &pb.FederationMember{
+		MemberId:  mem.id,
+		Address:   mem.address,
+		Regions:   mem.regions,
+		Heartbeat: mem.heartbeat,
+		Reachable: mem.reachable,
+	}
+}
+
+// Digest returns this node's full membership view as a gossip message.
+func (m *Membership) Digest() *pb.GossipMessage {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.digestLocked()
+}
+
+func (m *Membership) digestLocked() *pb.GossipMessage {
+	msg := &pb.GossipMessage{
+		SenderId: m.self.id,
+		Members:  []*pb.FederationMember{toPBMember(m.self)},
+	}
+	for _, mem := range m.members {
+		msg.Members = append(msg.Members, toPBMember(mem))
+	}
+	return msg
+}
+
+// Merge folds a remote membership view into ours. An entry only replaces
+// what we know when its heartbeat is newer.
+func (m *Membership) Merge(entries []*pb.FederationMember) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, e := range entries {
+		if e.MemberId == "" || e.MemberId == m.self.id {
+			continue
+		}
+		if dead, ok := m.tombstones[e.MemberId]; ok {
+			if e.Heartbeat <= dead {
+				continue
+			}
+			delete(m.tombstones, e.MemberId)
+		}
+
+		existing, ok := m.members[e.MemberId]
+		if ok && e.Heartbeat <= existing.heartbeat {
+			continue
+		}
+		if !ok {
+			log.Printf("🕸  Federation member joined: %s at %s owning %v", e.MemberId, e.Address, e.Regions)
+		}
+
+		m.members[e.MemberId] = &member{
+			id:        e.MemberId,
+			address:   e.Address,
+			regions:   e.Regions,
+			heartbeat: e.Heartbeat,
+			reachable: e.Reachable,
+			updatedAt: now,
+		}
+	}
+}
+
+// MarkContact records that we reached (or failed to reach) memberID
+// directly, which feeds the reachability we advertise.
+func (m *Membership) MarkContact(memberID string, ok bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if ok {
+		m.lastContact[memberID] = time.Now()
+	} else {
+		delete(m.lastContact, memberID)
+	}
+}
+
+type route struct {
+	memberID string
+	address  string
+	transit  bool
+}
+
+// Routes returns the ways to reach a base owning region, best first:
+// live owners we can dial directly, then live members that advertise
+// direct reachability to an owner. Members listed in exclude are skipped.
+func (m *Membership) Routes(region string, exclude []string) []route {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	skip := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		skip[id] = true
+	}
+
+	var direct, untried, transit []route
+	var owners []*member
+	for _, mem := range m.members {
+		if skip[mem.id] || now.Sub(mem.updatedAt) > suspectAfter || !mem.owns(region) {
+			continue
+		}
+		owners = append(owners, mem)
+
+		r := route{memberID: mem.id, address: mem.address}
+		if at, ok := m.lastContact[mem.id]; ok && now.Sub(at) <= suspectAfter {
+			direct = append(direct, r)
+		} else {
+			untried = append(untried, r)
+		}
+	}
+
+	for _, mem := range m.members {
+		if skip[mem.id] || now.Sub(mem.updatedAt) > suspectAfter || mem.owns(region) {
+			continue
+		}
+		if _, ok := m.lastContact[mem.id]; !ok {
+			continue
+		}
+		for _, owner := range owners {
+			if mem.canReach(owner.id) {
+				transit = append(transit, route{memberID: mem.id, address: mem.address, transit: true})
+				break
+			}
+		}
+	}
+
+	routes := append(direct, untried...)
+	return append(routes, transit...)
+}
+
+// Owners returns the addresses of the live bases owning region, the ones we
+// reached recently first.
+func (m *Membership) Owners(region string) []string {
+	var out []string
+	for _, r := range m.Routes(region, nil) {
+		if !r.transit {
+			out = append(out, r.address)
+		}
+	}
+	return out
+}
+
+// FetchSupers asks the federation for super nodes in req.TargetRegion,
+// trying direct owners first and then transit bases.
+func (m *Membership) FetchSupers(req *pb.RemoteSuperRequest) ([]*pb.SuperNodeInfo, error) {
+	resp, err := m.Relay(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.SuperNodes, nil
+}
+
+// FetchPeer asks the federation for the identity of req.PeerId, registered
+// with the base of req.TargetRegion.
+func (m *Membership) FetchPeer(req *pb.RemoteSuperRequest) ([]*pb.PeerIdentityInfo, error) {
+	resp, err := m.Relay(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Peers, nil
+}
+
+// Relay forwards req towards req.TargetRegion and returns the owner's
+// signed response unchanged, so a transit base cannot alter it.
+func (m *Membership) Relay(req *pb.RemoteSuperRequest) (*pb.RemoteSuperResponse, error) {
+	routes := m.Routes(req.TargetRegion, req.Via)
+	if len(routes) == 0 {
+		return nil, fmt.Errorf("no federation member advertises region %s", req.TargetRegion)
+	}
+
+	fwd := &pb.RemoteSuperRequest{
+		TargetRegion:          req.TargetRegion,
+		Count:                 req.Count,
+		RequiredBandWidthMbps: req.RequiredBandWidthMbps,
+		MaxLatencyMs:          req.MaxLatencyMs,
+		HopLimit:              req.HopLimit,
+		Via:                   append(append([]string{}, req.Via...), m.self.id),
+		Auth:                  req.Auth,
+		NodeId:                req.NodeId,
+		PeerId:                req.PeerId,
+	}
+	if err := m.keys.SignRequest(fwd); err != nil {
+		return nil, err
+	}
+
+	var lastErr error
+	for _, r := range routes {
+		if r.transit && fwd.HopLimit <= 0 {
+			continue
+		}
+
+		if r.transit {
+			log.Printf("🔀 Routing region %s via transit base %s (%s)", req.TargetRegion, r.memberID, r.address)
+		}
+
+		resp, err := client.FetchRemoteSupers(r.address, fwd)
+		m.MarkContact(r.memberID, err == nil)
+		if err != nil {
+			log.Printf("⚠️ Federation request to %s (%s) failed: %v", r.memberID, r.address, err)
+			lastErr = err
+			continue
+		}
+		if err := m.keys.VerifyResponse(resp, fwd); err != nil {
+			log.Printf("⚠️ Rejected federation response from %s (%s): %v", r.memberID, r.address, err)
+			lastErr = err
+			continue
+		}
+		return resp, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("hop limit exhausted")
+	}
+	return nil, fmt.Errorf("no route to region %s: %w", req.TargetRegion, lastErr)
+}
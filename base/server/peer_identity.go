@@ -0,0 +1,255 @@
+package server
+
+import (
+	"Base_node/client"
+	"Base_node/pb"
+	"Base_node/utils"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// peerIdentityTTL is how long a registered peer identity is kept without
+// being registered again. Peers refresh it well before then.
+const peerIdentityTTL = 30 * time.Minute
+
+// PeerRecord is the identity key a peer registered with this base.
+type PeerRecord struct {
+	PeerID    string    `json:"peer_id"`
+	Region    string    `json:"region"`
+	PublicKey string    `json:"public_key"`
+	Expires   time.Time `json:"expires"`
+}
+
+// PeerIdentityTable is the concurrency-safe set of peer identities
+// registered with this base.
+type PeerIdentityTable struct {
+	mu    sync.Mutex
+	peers map[string]*PeerRecord
+}
+
+func NewPeerIdentityTable() *PeerIdentityTable {
+	return &PeerIdentityTable{peers: make(map[string]*PeerRecord)}
+}
+
+// Put records rec, registered at at, and drops identities that expired.
+func (t *PeerIdentityTable) Put(rec *PeerRecord, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, p := range t.peers {
+		if !at.Before(p.Expires) {
+			delete(t.peers, id)
+		}
+	}
+	cp := *rec
+	cp.Expires = at.Add(peerIdentityTTL)
+	t.peers[rec.PeerID] = &cp
+}
+
+// Get returns the identity of peerID if it has not expired by now.
+func (t *PeerIdentityTable) Get(peerID string, now time.Time) (PeerRecord, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	rec, ok := t.peers[peerID]
+	if !ok || !now.Before(rec.Expires) {
+		return PeerRecord{}, false
+	}
+	return *rec, true
+}
+
+// All returns copies of every identity, for replica snapshots.
+func (t *PeerIdentityTable) All() map[string]*PeerRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	out := make(map[string]*PeerRecord, len(t.peers))
+	for id, rec := range t.peers {
+		cp := *rec
+		out[id] = &cp
+	}
+	return out
+}
+
+// Replace swaps in the identities of a replica snapshot.
+func (t *PeerIdentityTable) Replace(peers map[string]*PeerRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.peers = make(map[string]*PeerRecord, len(peers))
+	for id, rec := range peers {
+		t.peers[id] = rec
+	}
+}
+
+// RegisterPeerIdentity records the identity key of a client or exit peer.
+// The peer ID must be derived from the key, the request signed with it and,
+// with mutual TLS, made with the peer's own certificate. A peer of another
+// region, placed there by discovery, is registered with that region's base,
+// which is the one asked to verify it.
+func (s *BaseNodeServer) RegisterPeerIdentity(ctx context.Context, req *pb.PeerIdentity) (*pb.Ack, error) {
+	if err := checkPeerCaller(ctx, req.PublicKey); err != nil {
+		log.Printf("❌ Rejected identity of peer %s: %v", req.PeerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+	if req.Region != s.localRegion {
+		return s.forwardPeerIdentity(ctx, req)
+	}
+
+	leader, forward, err := s.writeLeader()
+	if err != nil {
+		return nil, err
+	}
+	if forward {
+		return s.leader.ForwardPeerIdentity(ctx, leader, req)
+	}
+
+	if err := verifyPeerIdentity(req); err != nil {
+		log.Printf("❌ Rejected identity of peer %s: %v", req.PeerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+	if err := s.peerReplay.Check(req.PeerId, req.Nonce, req.SignedAt); err != nil {
+		log.Printf("❌ Rejected identity of peer %s: %v", req.PeerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+
+	err = s.commit(&registryCommand{Op: opPeerIdentity, At: time.Now(), Peer: &PeerRecord{
+		PeerID:    req.PeerId,
+		Region:    req.Region,
+		PublicKey: req.PublicKey,
+	}})
+	if err != nil {
+		log.Printf("❌ Failed to commit identity of peer %s: %v", req.PeerId, err)
+		return nil, err
+	}
+
+	log.Printf("🪪 Registered identity of peer %s [%s]", req.PeerId, req.Region)
+	return &pb.Ack{Received: true, Message: "Peer identity registered"}, nil
+}
+
+// forwardPeerIdentity passes the registration of a peer of another region
+// on to a base owning that region. The peer's signature travels with it, so
+// the owner checks the request itself; we only check it first to not relay
+// junk. Only a peer's own registration is forwarded, never a forwarded one.
+func (s *BaseNodeServer) forwardPeerIdentity(ctx context.Context, req *pb.PeerIdentity) (*pb.Ack, error) {
+	if id, ok := utils.PeerIdentity(ctx); ok && id.Role == utils.RoleBase {
+		return &pb.Ack{Received: false, Message: fmt.Sprintf("Base node does not own region %s", req.Region)}, nil
+	}
+	if err := verifyPeerIdentity(req); err != nil {
+		log.Printf("❌ Rejected identity of peer %s: %v", req.PeerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+	if s.federation == nil {
+		return &pb.Ack{Received: false, Message: fmt.Sprintf("No base node found for region %s", req.Region)}, nil
+	}
+
+	var lastErr error
+	for _, addr := range s.federation.Owners(req.Region) {
+		ack, err := client.ForwardPeerIdentity(ctx, addr, req)
+		if err != nil {
+			log.Printf("⚠️ Failed to forward identity of peer %s to %s: %v", req.PeerId, addr, err)
+			lastErr = err
+			continue
+		}
+		log.Printf("🪪 Forwarded identity of peer %s [%s] to %s", req.PeerId, req.Region, addr)
+		return ack, nil
+	}
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no base node found for region %s", req.Region)
+	}
+	return nil, lastErr
+}
+
+// VerifyPeer tells a peer whether another peer registered its identity,
+// under the key it claims, with the base of its region. Exits check their
+// clients and clients their exits with it.
+func (s *BaseNodeServer) VerifyPeer(ctx context.Context, req *pb.PeerIdentity) (*pb.Ack, error) {
+	key, err := s.peerKey(req.PeerId, req.Region)
+	if err != nil {
+		log.Printf("❌ Could not verify peer %s [%s]: %v", req.PeerId, req.Region, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+	if key != req.PublicKey {
+		log.Printf("❌ Peer %s [%s] is registered under a different key", req.PeerId, req.Region)
+		return &pb.Ack{Received: false, Message: "Peer registered under a different key"}, nil
+	}
+	return &pb.Ack{Received: true, Message: "Peer verified"}, nil
+}
+
+// peerKey returns the public key peerID registered, asking the base of
+// region over federation when it did not register with us.
+func (s *BaseNodeServer) peerKey(peerID, region string) (string, error) {
+	if rec, ok := s.peers.Get(peerID, time.Now()); ok && rec.Region == region {
+		return rec.PublicKey, nil
+	}
+	if region == s.localRegion {
+		return "", fmt.Errorf("Peer not found")
+	}
+
+	if s.federation == nil {
+		return "", fmt.Errorf("No base node found for region %s", region)
+	}
+	remote, err := s.federation.FetchPeer(&pb.RemoteSuperRequest{
+		TargetRegion: region,
+		PeerId:       peerID,
+		HopLimit:     defaultHopLimit,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, p := range remote {
+		if p.PeerId == peerID && p.Region == region {
+			return p.PublicKey, nil
+		}
+	}
+	return "", fmt.Errorf("Peer not found in region %s", region)
+}
+
+// verifyPeerIdentity checks that the peer ID of req is derived from its key
+// and that the key signed peer-identity|id|region|nonce|signedAt.
+func verifyPeerIdentity(req *pb.PeerIdentity) error {
+	expected, err := DeriveNodeID("peer", req.Region, req.PublicKey)
+	if err != nil {
+		return err
+	}
+	if req.PeerId != expected {
+		return fmt.Errorf("%w: got %s, want %s", ErrIDMismatch, req.PeerId, expected)
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(req.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
+	}
+	sig, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	msg := fmt.Sprintf("peer-identity|%s|%s|%s|%d", req.PeerId, req.Region, req.Nonce, req.SignedAt)
+	if !ed25519.Verify(pub, []byte(msg), sig) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// checkPeerCaller ensures the TLS certificate of a peer registering its
+// identity carries pubKeyBase64. A base certificate is let through because
+// replicas relay registrations to their leader, which checks the peer's own
+// signature. Without mutual TLS it always passes.
+func checkPeerCaller(ctx context.Context, pubKeyBase64 string) error {
+	id, ok := utils.PeerIdentity(ctx)
+	if !ok || id.Role == utils.RoleBase {
+		return nil
+	}
+	if id.Role != utils.RolePeer {
+		return fmt.Errorf("%w: %q is not a peer certificate", ErrCertMismatch, id.Name)
+	}
+	if id.PublicKey != pubKeyBase64 {
+		return fmt.Errorf("%w: certificate %q carries a different key", ErrCertMismatch, id.Name)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"Base_node/pb"
+
+	"google.golang.org/grpc"
+)
+
+// signedPeerIdentity returns the identity registration of a new peer of
+// region.
+func signedPeerIdentity(t *testing.T, region string) *pb.PeerIdentity {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubB64 := base64.StdEncoding.EncodeToString(pub)
+	id, err := DeriveNodeID("peer", region, pubB64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &pb.PeerIdentity{PeerId: id, Region: region, PublicKey: pubB64, Nonce: "nonce-1", SignedAt: time.Now().Unix()}
+	msg := fmt.Sprintf("peer-identity|%s|%s|%s|%d", req.PeerId, req.Region, req.Nonce, req.SignedAt)
+	req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(msg)))
+	return req
+}
+
+// newServedBase starts a base node of region serving on a local port.
+func newServedBase(t *testing.T, region string) (*BaseNodeServer, string) {
+	t.Helper()
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewBaseNodeServer(region, registry)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := grpc.NewServer()
+	pb.RegisterBaseNodeServiceServer(g, srv)
+	go g.Serve(lis)
+	t.Cleanup(g.Stop)
+	return srv, lis.Addr().String()
+}
+
+func TestPeerOfOtherRegionIsRegisteredWithItsBase(t *testing.T) {
+	us, usAddr := newServedBase(t, "US")
+	in, inAddr := newServedBase(t, "IN")
+
+	_, fedKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fed := NewMembership("base-IN", inAddr, "IN", nil, NewInsecureFederationKeys("IN", fedKey))
+	fed.Merge([]*pb.FederationMember{{MemberId: "base-US", Address: usAddr, Regions: []string{"US"}, Heartbeat: 1}})
+	in.SetFederation(fed)
+
+	// Discovery placed the peer in US while it stays on the IN base it dialed
+	req := signedPeerIdentity(t, "US")
+	ack, err := in.RegisterPeerIdentity(context.Background(), req)
+	if err != nil || !ack.Received {
+		t.Fatalf("registration of a US peer through IN failed: %v %v", ack, err)
+	}
+	if _, ok := in.peers.Get(req.PeerId, time.Now()); ok {
+		t.Fatal("IN recorded the identity of a US peer")
+	}
+
+	// The exits of US ask the US base
+	ack, err = us.VerifyPeer(context.Background(), &pb.PeerIdentity{PeerId: req.PeerId, Region: "US", PublicKey: req.PublicKey})
+	if err != nil || !ack.Received {
+		t.Fatalf("US base could not verify the peer: %v %v", ack, err)
+	}
+
+	// A base with no owner for the region turns the peer away
+	lost := signedPeerIdentity(t, "EU")
+	if ack, err := in.RegisterPeerIdentity(context.Background(), lost); err == nil && ack.Received {
+		t.Fatal("peer of a region without a base was registered")
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// nodeIDHashBytes is how many bytes of the key hash go into a node ID.
+const nodeIDHashBytes = 10
+
+var (
+	ErrIDMismatch    = errors.New("node ID is not derived from its public key")
+	ErrKeyMismatch   = errors.New("node ID is pinned to a different public key")
+	ErrKeyNotAllowed = errors.New("public key is not in the super node allowlist")
+)
+
+// DeriveNodeID returns the ID a node with the given base64 public key must
+// use: <prefix>-<region>-<hex of the leading bytes of sha256(key)>.
+func DeriveNodeID(prefix, region, pubKeyBase64 string) (string, error) {
+	pub, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
+	}
+	sum := sha256.Sum256(pub)
+	return fmt.Sprintf("%s-%s-%s", prefix, region, hex.EncodeToString(sum[:nodeIDHashBytes])), nil
+}
+
+// KeyPins remembers the first public key seen for each node ID (trust on
+// first use). Pins survive registry eviction so a returning ID must prove
+// possession of its original key.
+type KeyPins struct {
+	mu   sync.RWMutex
+	path string
+	pins map[string]string
+}
+
+// NewKeyPins loads pins from dir/pins.json. An empty dir keeps pins in
+// memory only.
+func NewKeyPins(dir string) (*KeyPins, error) {
+	p := &KeyPins{pins: make(map[string]string)}
+	if dir == "" {
+		return p, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	p.path = filepath.Join(dir, "pins.json")
+
+	data, err := os.ReadFile(p.path)
+	if os.IsNotExist(err) {
+		return p, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &p.pins); err != nil {
+		return nil, fmt.Errorf("corrupt key pins %s: %w", p.path, err)
+	}
+	return p, nil
+}
+
+// Check returns ErrKeyMismatch if id is pinned to a key other than
+// pubKeyBase64. Unpinned IDs pass.
+func (p *KeyPins) Check(id, pubKeyBase64 string) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if pinned, ok := p.pins[id]; ok && pinned != pubKeyBase64 {
+		return fmt.Errorf("%w: %s", ErrKeyMismatch, id)
+	}
+	return nil
+}
+
+// Pin records pubKeyBase64 as the key for id if it has none yet.
+func (p *KeyPins) Pin(id, pubKeyBase64 string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.pins[id]; ok {
+		return nil
+	}
+	p.pins[id] = pubKeyBase64
+	return p.saveLocked()
+}
+
+// All returns a copy of every pin.
+func (p *KeyPins) All() map[string]string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	out := make(map[string]string, len(p.pins))
+	for id, key := range p.pins {
+		out[id] = key
+	}
+	return out
+}
+
+// Replace swaps the whole pin table, e.g. when a replica installs a
+// snapshot from the leader.
+func (p *KeyPins) Replace(pins map[string]string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pins = make(map[string]string, len(pins))
+	for id, key := range pins {
+		p.pins[id] = key
+	}
+	return p.saveLocked()
+}
+
+func (p *KeyPins) saveLocked() error {
+	if p.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(p.pins)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(p.path, data)
+}
+
+// LoadKeyAllowlist reads base64 public keys, one per line. Blank lines and
+// anything after '#' are ignored.
+func LoadKeyAllowlist(path string) (map[string]bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	allowed := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		if _, err := base64.StdEncoding.DecodeString(line); err != nil {
+			return nil, fmt.Errorf("allowlist %s: invalid key %q", path, line)
+		}
+		allowed[line] = true
+	}
+	return allowed, scanner.Err()
+}
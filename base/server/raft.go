@@ -0,0 +1,892 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"Base_node/pb"
+	"Base_node/utils"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	raftHeartbeatInterval = 300 * time.Millisecond
+	raftElectionMin       = 1500 * time.Millisecond
+	raftElectionMax       = 3000 * time.Millisecond
+	raftRPCTimeout        = time.Second
+	raftProposeTimeout    = 5 * time.Second
+
+	// raftCompactAfter is the number of applied log entries kept before the
+	// log is truncated. The state machine persists its own state, so a
+	// lagging follower is caught up with a fresh Snapshot instead.
+	raftCompactAfter = 500
+
+	raftStateFile = "raft.state"
+)
+
+// ErrNotLeader is returned by Propose on replicas that are not the leader.
+var ErrNotLeader = errors.New("not the replication leader")
+
+type raftRole int
+
+const (
+	raftFollower raftRole = iota
+	raftCandidate
+	raftLeader
+)
+
+func (r raftRole) String() string {
+	switch r {
+	case raftLeader:
+		return "leader"
+	case raftCandidate:
+		return "candidate"
+	default:
+		return "follower"
+	}
+}
+
+// StateMachine is what a RaftNode replicates. Apply is called with every
+// committed command in log order on every replica.
+type StateMachine interface {
+	Apply(command []byte)
+	Snapshot() ([]byte, error)
+	Restore(data []byte) error
+}
+
+// raftPersistentState is written to disk before answering any RPC that
+// depends on it.
+type raftPersistentState struct {
+	Term      uint64         `json:"term"`
+	VotedFor  string         `json:"voted_for"`
+	SnapIndex uint64         `json:"snap_index"`
+	SnapTerm  uint64         `json:"snap_term"`
+	Entries   []*pb.LogEntry `json:"entries"`
+}
+
+// RaftNode is one replica of the region's base node cluster. Replica IDs are
+// the gRPC addresses the replicas listen on, so the leader ID doubles as the
+// address followers forward writes to.
+type RaftNode struct {
+	pb.UnimplementedBaseReplicationServiceServer
+
+	// applyMu is held while committed entries are applied or a snapshot is
+	// taken or installed, so the state machine and lastApplied always move
+	// together. Lock order: applyMu, then mu.
+	applyMu sync.Mutex
+
+	mu    sync.Mutex
+	id    string
+	peers []string
+	dir   string
+	fsm   StateMachine
+
+	compactAfter uint64
+
+	term      uint64
+	votedFor  string
+	entries   []*pb.LogEntry
+	snapIndex uint64
+	snapTerm  uint64
+
+	role          raftRole
+	leaderID      string
+	commitIndex   uint64
+	lastApplied   uint64
+	nextIndex     map[string]uint64
+	matchIndex    map[string]uint64
+	electionReset time.Time
+	timeout       time.Duration
+
+	applyCond *sync.Cond
+	waiters   map[uint64]chan struct{}
+	stopped   bool
+	stop      chan struct{}
+
+	connsMu sync.Mutex
+	conns   map[string]*grpc.ClientConn
+}
+
+// NewRaftNode creates a replica with the given ID among replicas (which may
+// include id itself). dir holds the persistent Raft state; an empty dir keeps
+// it in memory.
+func NewRaftNode(id string, replicas []string, dir string, fsm StateMachine) (*RaftNode, error) {
+	n := &RaftNode{
+		id:        id,
+		dir:       dir,
+		fsm:       fsm,
+		waiters:   make(map[uint64]chan struct{}),
+		conns:     make(map[string]*grpc.ClientConn),
+		nextIndex: make(map[string]uint64),
+		stop:      make(chan struct{}),
+
+		compactAfter: raftCompactAfter,
+	}
+	n.applyCond = sync.NewCond(&n.mu)
+
+	for _, r := range replicas {
+		if r != "" && r != id {
+			n.peers = append(n.peers, r)
+		}
+	}
+
+	if err := n.load(); err != nil {
+		return nil, err
+	}
+	n.resetElectionTimer()
+	return n, nil
+}
+
+// Start launches the election, replication and apply loops.
+func (n *RaftNode) Start() {
+	n.mu.Lock()
+	n.resetElectionTimer()
+	n.mu.Unlock()
+
+	go n.run()
+	go n.applyLoop()
+	log.Printf("🗳  Replica %s started with peers %v", n.id, n.peers)
+}
+
+// Stop ends the election, replication and apply loops and closes the
+// connections to the other replicas.
+func (n *RaftNode) Stop() {
+	n.mu.Lock()
+	if n.stopped {
+		n.mu.Unlock()
+		return
+	}
+	n.stopped = true
+	n.role = raftFollower
+	close(n.stop)
+	n.applyCond.Broadcast()
+	n.mu.Unlock()
+
+	n.connsMu.Lock()
+	defer n.connsMu.Unlock()
+	for addr, conn := range n.conns {
+		conn.Close()
+		delete(n.conns, addr)
+	}
+}
+
+func (n *RaftNode) IsLeader() bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	return n.role == raftLeader
+}
+
+// Leader returns the ID (address) of the current leader, if known.
+func (n *RaftNode) Leader() string {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.role == raftLeader {
+		return n.id
+	}
+	return n.leaderID
+}
+
+// Propose appends command to the log and waits until it has been committed
+// and applied locally.
+func (n *RaftNode) Propose(command []byte) error {
+	n.mu.Lock()
+	if n.role != raftLeader {
+		n.mu.Unlock()
+		return ErrNotLeader
+	}
+
+	entry := &pb.LogEntry{Index: n.lastIndex() + 1, Term: n.term, Command: command}
+	n.entries = append(n.entries, entry)
+	if err := n.persist(); err != nil {
+		n.mu.Unlock()
+		return err
+	}
+
+	done := make(chan struct{})
+	n.waiters[entry.Index] = done
+	n.advanceCommit()
+	n.mu.Unlock()
+
+	n.broadcast()
+
+	select {
+	case <-done:
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if t, ok := n.termAt(entry.Index); ok && t != entry.Term {
+			return fmt.Errorf("entry %d was overwritten by a new leader", entry.Index)
+		}
+		return nil
+	case <-time.After(raftProposeTimeout):
+		n.mu.Lock()
+		delete(n.waiters, entry.Index)
+		n.mu.Unlock()
+		return fmt.Errorf("timed out waiting for entry %d to commit", entry.Index)
+	}
+}
+
+// ---- log helpers (n.mu held) ----
+
+func (n *RaftNode) lastIndex() uint64 {
+	return n.snapIndex + uint64(len(n.entries))
+}
+
+func (n *RaftNode) lastTerm() uint64 {
+	if len(n.entries) == 0 {
+		return n.snapTerm
+	}
+	return n.entries[len(n.entries)-1].Term
+}
+
+func (n *RaftNode) termAt(index uint64) (uint64, bool) {
+	if index == n.snapIndex {
+		return n.snapTerm, true
+	}
+	if index < n.snapIndex || index > n.lastIndex() {
+		return 0, false
+	}
+	return n.entries[index-n.snapIndex-1].Term, true
+}
+
+func (n *RaftNode) entryAt(index uint64) *pb.LogEntry {
+	return n.entries[index-n.snapIndex-1]
+}
+
+// truncateFrom drops index and every entry after it.
+func (n *RaftNode) truncateFrom(index uint64) {
+	if index <= n.snapIndex {
+		n.entries = nil
+		return
+	}
+	n.entries = n.entries[:index-n.snapIndex-1]
+}
+
+func (n *RaftNode) quorum() int {
+	return (len(n.peers)+1)/2 + 1
+}
+
+func (n *RaftNode) resetElectionTimer() {
+	n.electionReset = time.Now()
+	n.timeout = raftElectionMin + time.Duration(rand.Int63n(int64(raftElectionMax-raftElectionMin)))
+}
+
+func (n *RaftNode) becomeFollower(term uint64) {
+	if n.role == raftLeader {
+		log.Printf("🗳  Replica %s stepping down in term %d", n.id, term)
+	}
+	n.role = raftFollower
+	if term > n.term {
+		n.term = term
+		n.votedFor = ""
+	}
+}
+
+// ---- persistence (n.mu held) ----
+
+func (n *RaftNode) load() error {
+	if n.dir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(n.dir, raftStateFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read raft state: %w", err)
+	}
+
+	var st raftPersistentState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return fmt.Errorf("failed to decode raft state: %w", err)
+	}
+	n.term = st.Term
+	n.votedFor = st.VotedFor
+	n.snapIndex = st.SnapIndex
+	n.snapTerm = st.SnapTerm
+	n.entries = st.Entries
+
+	// The state machine's own store already reflects everything up to the
+	// snapshot, so replay starts from there.
+	n.commitIndex = n.snapIndex
+	n.lastApplied = n.snapIndex
+	return nil
+}
+
+func (n *RaftNode) persist() error {
+	if n.dir == "" {
+		return nil
+	}
+
+	data, err := json.Marshal(&raftPersistentState{
+		Term:      n.term,
+		VotedFor:  n.votedFor,
+		SnapIndex: n.snapIndex,
+		SnapTerm:  n.snapTerm,
+		Entries:   n.entries,
+	})
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(n.dir, raftStateFile), data)
+}
+
+func writeFileAtomic(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ---- loops ----
+
+func (n *RaftNode) run() {
+	ticker := time.NewTicker(raftHeartbeatInterval / 3)
+	defer ticker.Stop()
+
+	lastBeat := time.Time{}
+	for {
+		select {
+		case <-ticker.C:
+		case <-n.stop:
+			return
+		}
+
+		n.mu.Lock()
+		role := n.role
+		expired := time.Since(n.electionReset) > n.timeout
+		n.mu.Unlock()
+
+		switch {
+		case role == raftLeader && time.Since(lastBeat) >= raftHeartbeatInterval:
+			lastBeat = time.Now()
+			n.broadcast()
+		case role != raftLeader && expired:
+			n.startElection()
+		}
+	}
+}
+
+func (n *RaftNode) applyLoop() {
+	for {
+		n.mu.Lock()
+		for n.lastApplied >= n.commitIndex && !n.stopped {
+			n.applyCond.Wait()
+		}
+		stopped := n.stopped
+		n.mu.Unlock()
+		if stopped {
+			return
+		}
+
+		n.applyBatch()
+	}
+}
+
+// applyBatch applies every committed entry not applied yet. The batch is
+// read under applyMu, so a snapshot installed while waiting for it is
+// never overwritten by entries it already covers.
+func (n *RaftNode) applyBatch() {
+	n.applyMu.Lock()
+	defer n.applyMu.Unlock()
+
+	n.mu.Lock()
+	var batch []*pb.LogEntry
+	for i := n.lastApplied + 1; i <= n.commitIndex; i++ {
+		batch = append(batch, n.entryAt(i))
+	}
+	n.mu.Unlock()
+
+	for _, e := range batch {
+		if len(e.Command) > 0 {
+			n.fsm.Apply(e.Command)
+		}
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, e := range batch {
+		if e.Index > n.lastApplied {
+			n.lastApplied = e.Index
+		}
+		if done, ok := n.waiters[e.Index]; ok {
+			close(done)
+			delete(n.waiters, e.Index)
+		}
+	}
+	n.maybeCompact()
+}
+
+// maybeCompact drops applied entries once the log grows past
+// compactAfter.
+func (n *RaftNode) maybeCompact() {
+	if n.lastApplied-n.snapIndex < n.compactAfter {
+		return
+	}
+
+	term, _ := n.termAt(n.lastApplied)
+	n.entries = append([]*pb.LogEntry(nil), n.entries[n.lastApplied-n.snapIndex:]...)
+	n.snapIndex = n.lastApplied
+	n.snapTerm = term
+	if err := n.persist(); err != nil {
+		log.Printf("⚠️ Failed to persist compacted raft log: %v", err)
+	}
+}
+
+func (n *RaftNode) startElection() {
+	n.mu.Lock()
+	n.role = raftCandidate
+	n.term++
+	n.votedFor = n.id
+	n.resetElectionTimer()
+	if err := n.persist(); err != nil {
+		log.Printf("⚠️ Failed to persist raft state: %v", err)
+	}
+
+	term := n.term
+	req := &pb.VoteRequest{
+		Term:         term,
+		CandidateId:  n.id,
+		LastLogIndex: n.lastIndex(),
+		LastLogTerm:  n.lastTerm(),
+	}
+	votes := 1
+	if votes >= n.quorum() {
+		n.becomeLeader()
+		n.mu.Unlock()
+		return
+	}
+	n.mu.Unlock()
+
+	log.Printf("🗳  Replica %s starting election for term %d", n.id, term)
+
+	for _, peer := range n.peers {
+		go func(peer string) {
+			c, err := n.client(peer)
+			if err != nil {
+				return
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), raftRPCTimeout)
+			resp, err := c.RequestVote(ctx, req)
+			cancel()
+			if err != nil {
+				return
+			}
+
+			n.mu.Lock()
+			defer n.mu.Unlock()
+
+			if resp.Term > n.term {
+				n.becomeFollower(resp.Term)
+				n.persist()
+				return
+			}
+			if n.role != raftCandidate || n.term != term || !resp.VoteGranted {
+				return
+			}
+			votes++
+			if votes >= n.quorum() {
+				n.becomeLeader()
+			}
+		}(peer)
+	}
+}
+
+func (n *RaftNode) becomeLeader() {
+	n.role = raftLeader
+	n.leaderID = n.id
+	n.matchIndex = make(map[string]uint64)
+	for _, p := range n.peers {
+		n.nextIndex[p] = n.lastIndex() + 1
+	}
+
+	// A no-op entry from the new term lets earlier entries commit.
+	n.entries = append(n.entries, &pb.LogEntry{Index: n.lastIndex() + 1, Term: n.term})
+	if err := n.persist(); err != nil {
+		log.Printf("⚠️ Failed to persist raft state: %v", err)
+	}
+	n.advanceCommit()
+
+	log.Printf("👑 Replica %s is now leader for term %d", n.id, n.term)
+	go n.broadcast()
+}
+
+// advanceCommit moves commitIndex to the highest entry of the current term
+// stored on a quorum of replicas.
+func (n *RaftNode) advanceCommit() {
+	for idx := n.lastIndex(); idx > n.commitIndex; idx-- {
+		if t, _ := n.termAt(idx); t != n.term {
+			break
+		}
+
+		count := 1
+		for _, p := range n.peers {
+			if n.matchIndex[p] >= idx {
+				count++
+			}
+		}
+		if count >= n.quorum() {
+			n.commitIndex = idx
+			n.applyCond.Broadcast()
+			return
+		}
+	}
+}
+
+func (n *RaftNode) broadcast() {
+	for _, peer := range n.peers {
+		go n.replicateTo(peer)
+	}
+}
+
+func (n *RaftNode) replicateTo(peer string) {
+	n.mu.Lock()
+	if n.role != raftLeader {
+		n.mu.Unlock()
+		return
+	}
+
+	term := n.term
+	next := n.nextIndex[peer]
+	if next == 0 {
+		next = 1
+	}
+
+	if next <= n.snapIndex {
+		n.mu.Unlock()
+		n.sendSnapshot(peer, term)
+		return
+	}
+
+	prev := next - 1
+	prevTerm, _ := n.termAt(prev)
+	req := &pb.AppendEntriesRequest{
+		Term:         term,
+		LeaderId:     n.id,
+		PrevLogIndex: prev,
+		PrevLogTerm:  prevTerm,
+		LeaderCommit: n.commitIndex,
+	}
+	for i := next; i <= n.lastIndex(); i++ {
+		req.Entries = append(req.Entries, n.entryAt(i))
+	}
+	n.mu.Unlock()
+
+	c, err := n.client(peer)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), raftRPCTimeout)
+	resp, err := c.AppendEntries(ctx, req)
+	cancel()
+	if err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if resp.Term > n.term {
+		n.becomeFollower(resp.Term)
+		n.persist()
+		return
+	}
+	if n.role != raftLeader || n.term != term {
+		return
+	}
+
+	if resp.Success {
+		match := prev + uint64(len(req.Entries))
+		if match > n.matchIndex[peer] {
+			n.matchIndex[peer] = match
+		}
+		n.nextIndex[peer] = n.matchIndex[peer] + 1
+		n.advanceCommit()
+		return
+	}
+
+	// Jump back to just after the follower's last entry rather than one
+	// step at a time.
+	back := resp.LastLogIndex + 1
+	if back >= next {
+		back = next - 1
+	}
+	if back < 1 {
+		back = 1
+	}
+	n.nextIndex[peer] = back
+}
+
+func (n *RaftNode) sendSnapshot(peer string, term uint64) {
+	// The state machine is exactly at lastApplied while applyMu is held,
+	// so the snapshot is labelled with that index rather than snapIndex.
+	n.applyMu.Lock()
+	n.mu.Lock()
+	data, err := n.fsm.Snapshot()
+	if err != nil {
+		n.mu.Unlock()
+		n.applyMu.Unlock()
+		log.Printf("⚠️ Raft snapshot for %s failed: %v", peer, err)
+		return
+	}
+	lastTerm, _ := n.termAt(n.lastApplied)
+	req := &pb.InstallSnapshotRequest{
+		Term:              term,
+		LeaderId:          n.id,
+		LastIncludedIndex: n.lastApplied,
+		LastIncludedTerm:  lastTerm,
+		Data:              data,
+	}
+	n.mu.Unlock()
+	n.applyMu.Unlock()
+
+	c, err := n.client(peer)
+	if err != nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), raftRPCTimeout)
+	resp, err := c.InstallSnapshot(ctx, req)
+	cancel()
+	if err != nil {
+		return
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if resp.Term > n.term {
+		n.becomeFollower(resp.Term)
+		n.persist()
+		return
+	}
+	if n.role == raftLeader && n.term == term {
+		n.matchIndex[peer] = req.LastIncludedIndex
+		n.nextIndex[peer] = req.LastIncludedIndex + 1
+	}
+}
+
+func (n *RaftNode) client(addr string) (pb.BaseReplicationServiceClient, error) {
+	n.connsMu.Lock()
+	defer n.connsMu.Unlock()
+
+	select {
+	case <-n.stop:
+		return nil, errors.New("replica stopped")
+	default:
+	}
+
+	conn, ok := n.conns[addr]
+	if !ok {
+		var err error
+		// Keep reconnect backoff short so a restarted replica rejoins quickly.
+		conn, err = grpc.Dial(addr, utils.DialOption(utils.RoleBase), grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoff.Config{BaseDelay: 100 * time.Millisecond, Multiplier: 1.6, MaxDelay: raftElectionMin},
+			MinConnectTimeout: raftRPCTimeout,
+		}))
+		if err != nil {
+			return nil, err
+		}
+		n.conns[addr] = conn
+	}
+	return pb.NewBaseReplicationServiceClient(conn), nil
+}
+
+// ---- RPC handlers ----
+
+// checkReplica rejects replication calls from outside the replica set: the
+// claimed sender must be one of our peers and, with mutual TLS, the
+// caller's certificate must be issued for that peer's host. Any other base
+// of the network holds a base certificate too.
+func (n *RaftNode) checkReplica(ctx context.Context, claimed string) error {
+	known := false
+	for _, p := range n.peers {
+		if p == claimed {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return status.Errorf(codes.PermissionDenied, "%q is not a replica of %s", claimed, n.id)
+	}
+
+	id, ok := utils.PeerIdentity(ctx)
+	if !ok {
+		return nil
+	}
+	host, _, err := net.SplitHostPort(claimed)
+	if err != nil {
+		host = claimed
+	}
+	if !id.Covers(host) {
+		return status.Errorf(codes.PermissionDenied, "certificate %q is not issued for replica %s", id.Name, claimed)
+	}
+	return nil
+}
+
+func (n *RaftNode) RequestVote(ctx context.Context, req *pb.VoteRequest) (*pb.VoteResponse, error) {
+	if err := n.checkReplica(ctx, req.CandidateId); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if req.Term > n.term {
+		n.becomeFollower(req.Term)
+	}
+
+	granted := false
+	upToDate := req.LastLogTerm > n.lastTerm() ||
+		(req.LastLogTerm == n.lastTerm() && req.LastLogIndex >= n.lastIndex())
+	if req.Term == n.term && (n.votedFor == "" || n.votedFor == req.CandidateId) && upToDate {
+		granted = true
+		n.votedFor = req.CandidateId
+		n.resetElectionTimer()
+	}
+
+	if err := n.persist(); err != nil {
+		return nil, err
+	}
+	return &pb.VoteResponse{Term: n.term, VoteGranted: granted}, nil
+}
+
+func (n *RaftNode) AppendEntries(ctx context.Context, req *pb.AppendEntriesRequest) (*pb.AppendEntriesResponse, error) {
+	if err := n.checkReplica(ctx, req.LeaderId); err != nil {
+		return nil, err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if req.Term < n.term {
+		return &pb.AppendEntriesResponse{Term: n.term, LastLogIndex: n.lastIndex()}, nil
+	}
+	n.becomeFollower(req.Term)
+	if n.leaderID != req.LeaderId {
+		log.Printf("🗳  Replica %s following leader %s in term %d", n.id, req.LeaderId, req.Term)
+	}
+	n.leaderID = req.LeaderId
+	n.resetElectionTimer()
+
+	if req.PrevLogIndex > n.lastIndex() {
+		return &pb.AppendEntriesResponse{Term: n.term, LastLogIndex: n.lastIndex()}, nil
+	}
+	if req.PrevLogIndex >= n.snapIndex {
+		if t, _ := n.termAt(req.PrevLogIndex); t != req.PrevLogTerm {
+			n.truncateFrom(req.PrevLogIndex)
+			if err := n.persist(); err != nil {
+				return nil, err
+			}
+			return &pb.AppendEntriesResponse{Term: n.term, LastLogIndex: n.lastIndex()}, nil
+		}
+	}
+
+	changed := false
+	for _, e := range req.Entries {
+		if e.Index <= n.snapIndex {
+			continue
+		}
+		if e.Index <= n.lastIndex() {
+			if t, _ := n.termAt(e.Index); t == e.Term {
+				continue
+			}
+			n.truncateFrom(e.Index)
+		}
+		n.entries = append(n.entries, e)
+		changed = true
+	}
+	if changed {
+		if err := n.persist(); err != nil {
+			return nil, err
+		}
+	}
+
+	lastNew := req.PrevLogIndex + uint64(len(req.Entries))
+	if req.LeaderCommit > n.commitIndex {
+		n.commitIndex = req.LeaderCommit
+		if lastNew < n.commitIndex {
+			n.commitIndex = lastNew
+		}
+		n.applyCond.Broadcast()
+	}
+
+	return &pb.AppendEntriesResponse{Term: n.term, Success: true, LastLogIndex: n.lastIndex()}, nil
+}
+
+func (n *RaftNode) InstallSnapshot(ctx context.Context, req *pb.InstallSnapshotRequest) (*pb.InstallSnapshotResponse, error) {
+	if err := n.checkReplica(ctx, req.LeaderId); err != nil {
+		return nil, err
+	}
+
+	n.applyMu.Lock()
+	defer n.applyMu.Unlock()
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if req.Term < n.term {
+		return &pb.InstallSnapshotResponse{Term: n.term}, nil
+	}
+	n.becomeFollower(req.Term)
+	n.leaderID = req.LeaderId
+	n.resetElectionTimer()
+
+	if req.LastIncludedIndex <= n.snapIndex {
+		return &pb.InstallSnapshotResponse{Term: n.term}, nil
+	}
+
+	// A replica that already applied past the snapshot keeps its state and
+	// only drops the covered log entries.
+	if req.LastIncludedIndex > n.lastApplied {
+		if err := n.fsm.Restore(req.Data); err != nil {
+			return nil, fmt.Errorf("failed to restore snapshot: %w", err)
+		}
+	}
+
+	if t, ok := n.termAt(req.LastIncludedIndex); ok && t == req.LastIncludedTerm {
+		n.entries = append([]*pb.LogEntry(nil), n.entries[req.LastIncludedIndex-n.snapIndex:]...)
+	} else {
+		n.entries = nil
+	}
+	n.snapIndex = req.LastIncludedIndex
+	n.snapTerm = req.LastIncludedTerm
+	if n.commitIndex < n.snapIndex {
+		n.commitIndex = n.snapIndex
+	}
+	if n.lastApplied < n.snapIndex {
+		n.lastApplied = n.snapIndex
+	}
+	if err := n.persist(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("📥 Replica %s installed snapshot at index %d from %s", n.id, req.LastIncludedIndex, req.LeaderId)
+	return &pb.InstallSnapshotResponse{Term: n.term}, nil
+}
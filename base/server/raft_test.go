@@ -0,0 +1,258 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"Base_node/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// memFSM records the commands applied to it.
+type memFSM struct {
+	mu   sync.Mutex
+	cmds []string
+}
+
+func (f *memFSM) Apply(command []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cmds = append(f.cmds, string(command))
+}
+
+func (f *memFSM) Snapshot() ([]byte, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return json.Marshal(f.cmds)
+}
+
+func (f *memFSM) Restore(data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.cmds = nil
+	return json.Unmarshal(data, &f.cmds)
+}
+
+func (f *memFSM) applied() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.cmds...)
+}
+
+type testReplica struct {
+	node *RaftNode
+	fsm  *memFSM
+	lis  net.Listener
+}
+
+// newTestCluster creates size in-memory replicas listening on loopback.
+// None is started.
+func newTestCluster(t *testing.T, size int) []*testReplica {
+	t.Helper()
+
+	reps := make([]*testReplica, size)
+	addrs := make([]string, size)
+	for i := range reps {
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		reps[i] = &testReplica{fsm: &memFSM{}, lis: lis}
+		addrs[i] = lis.Addr().String()
+	}
+	for i, r := range reps {
+		node, err := NewRaftNode(addrs[i], addrs, "", r.fsm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.node = node
+	}
+	t.Cleanup(func() {
+		for _, r := range reps {
+			r.node.Stop()
+			r.lis.Close()
+		}
+	})
+	return reps
+}
+
+func (r *testReplica) start(t *testing.T) {
+	srv := grpc.NewServer()
+	pb.RegisterBaseReplicationServiceServer(srv, r.node)
+	go srv.Serve(r.lis)
+	r.node.Start()
+	t.Cleanup(srv.Stop)
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(15 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// leaderOf waits until exactly one of reps is leader and returns it.
+func leaderOf(t *testing.T, reps []*testReplica) *testReplica {
+	t.Helper()
+	var leader *testReplica
+	waitFor(t, "a single leader", func() bool {
+		leader = nil
+		count := 0
+		for _, r := range reps {
+			if r.node.IsLeader() {
+				leader = r
+				count++
+			}
+		}
+		return count == 1
+	})
+	return leader
+}
+
+// propose commits cmd through whichever replica currently leads.
+func propose(t *testing.T, reps []*testReplica, cmd string) {
+	t.Helper()
+	waitFor(t, "commit of "+cmd, func() bool {
+		return leaderOf(t, reps).node.Propose([]byte(cmd)) == nil
+	})
+}
+
+func TestRaftElectsOneLeader(t *testing.T) {
+	reps := newTestCluster(t, 3)
+	for _, r := range reps {
+		r.start(t)
+	}
+
+	leader := leaderOf(t, reps)
+	waitFor(t, "followers to learn the leader", func() bool {
+		for _, r := range reps {
+			if r.node.Leader() != leader.node.id {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestRaftReplicatesLogInOrder(t *testing.T) {
+	reps := newTestCluster(t, 3)
+	for _, r := range reps {
+		r.start(t)
+	}
+
+	var want []string
+	for i := 0; i < 5; i++ {
+		cmd := fmt.Sprintf("cmd-%d", i)
+		propose(t, reps, cmd)
+		want = append(want, cmd)
+	}
+
+	waitFor(t, "every replica to apply the log", func() bool {
+		for _, r := range reps {
+			if !reflect.DeepEqual(r.fsm.applied(), want) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func TestRaftInstallsSnapshotOnLaggingReplica(t *testing.T) {
+	reps := newTestCluster(t, 3)
+	for _, r := range reps {
+		r.node.compactAfter = 4
+	}
+	// The third replica stays down while the others commit and compact
+	reps[0].start(t)
+	reps[1].start(t)
+
+	var want []string
+	for i := 0; i < 12; i++ {
+		cmd := fmt.Sprintf("cmd-%d", i)
+		propose(t, reps[:2], cmd)
+		want = append(want, cmd)
+	}
+
+	late := reps[2]
+	late.start(t)
+	waitFor(t, "the late replica to catch up", func() bool {
+		return reflect.DeepEqual(late.fsm.applied(), want)
+	})
+
+	late.node.mu.Lock()
+	snapIndex := late.node.snapIndex
+	late.node.mu.Unlock()
+	if snapIndex == 0 {
+		t.Fatal("late replica caught up without installing a snapshot")
+	}
+}
+
+// withBaseCert returns ctx as seen by a server whose caller presented a base
+// certificate issued for ip.
+func withBaseCert(ctx context.Context, ip string) context.Context {
+	cert := &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "base-" + ip, OrganizationalUnit: []string{"base"}},
+		IPAddresses: []net.IP{net.ParseIP(ip)},
+	}
+	return peer.NewContext(ctx, &peer.Peer{
+		AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+	})
+}
+
+func TestRaftRejectsReplicationFromOutsideReplicaSet(t *testing.T) {
+	replicas := []string{"10.0.0.1:50051", "10.0.0.2:50051"}
+	fsm := &memFSM{}
+	node, err := NewRaftNode(replicas[0], replicas, "", fsm)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	denied := func(err error) bool { return status.Code(err) == codes.PermissionDenied }
+	entries := []*pb.LogEntry{{Index: 1, Term: 1, Command: []byte("forged")}}
+
+	// A base outside the replica set, whatever leader it claims to be
+	if _, err := node.AppendEntries(withBaseCert(context.Background(), "10.9.9.9"),
+		&pb.AppendEntriesRequest{Term: 1, LeaderId: "10.9.9.9:50051", Entries: entries, LeaderCommit: 1}); !denied(err) {
+		t.Fatalf("expected PermissionDenied for an unknown leader, got %v", err)
+	}
+	if _, err := node.AppendEntries(withBaseCert(context.Background(), "10.9.9.9"),
+		&pb.AppendEntriesRequest{Term: 1, LeaderId: replicas[1], Entries: entries, LeaderCommit: 1}); !denied(err) {
+		t.Fatalf("expected PermissionDenied for a certificate of another host, got %v", err)
+	}
+	if _, err := node.InstallSnapshot(withBaseCert(context.Background(), "10.9.9.9"),
+		&pb.InstallSnapshotRequest{Term: 1, LeaderId: replicas[1], LastIncludedIndex: 5, LastIncludedTerm: 1, Data: []byte(`["forged"]`)}); !denied(err) {
+		t.Fatalf("expected PermissionDenied for a forged snapshot, got %v", err)
+	}
+	if _, err := node.RequestVote(withBaseCert(context.Background(), "10.9.9.9"),
+		&pb.VoteRequest{Term: 1, CandidateId: replicas[1]}); !denied(err) {
+		t.Fatalf("expected PermissionDenied for a vote request, got %v", err)
+	}
+	if node.lastIndex() != 0 || len(fsm.applied()) != 0 {
+		t.Fatal("rejected calls changed the replica")
+	}
+
+	// The real replica is accepted
+	resp, err := node.AppendEntries(withBaseCert(context.Background(), "10.0.0.2"),
+		&pb.AppendEntriesRequest{Term: 1, LeaderId: replicas[1], Entries: entries})
+	if err != nil || !resp.Success {
+		t.Fatalf("expected the replica's entries to be accepted, got %v, %v", resp, err)
+	}
+}
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"sort"
+	"time"
+)
+
+// Weights of each factor in a super node's score. They sum to 1 so a score
+// is always in [0, 1].
+const (
+	weightLatency   = 0.30
+	weightLoad      = 0.20
+	weightExitPeers = 0.20
+	weightHeadroom  = 0.20
+	weightFreshness = 0.10
+
+	// Reference values at which a factor is considered "good enough", or
+	// for bandwidth in use, at which a super counts as half loaded.
+	refLatencyMs     = 50.0
+	refBandwidthMbps = 100.0
+	refExitPeers     = 10.0
+
+	// exitBandwidthUnknown is the exit bandwidth of a super that has not
+	// reported it, or has an exit that advertised no capacity.
+	exitBandwidthUnknown = -1
+)
+
+// meetsConstraints reports whether n satisfies the caller's limits. A zero
+// limit means "no limit". The bandwidth minimum is checked against what the
+// node's exits can still grant, not against the bandwidth its peers use
+// now, which is scored as load; a node whose exits have nothing left fails
+// it, and one whose exit bandwidth is unknown passes. A node that has not
+// reported latency yet (zero) is not excluded by the latency limit.
+func meetsConstraints(n *SuperNodeInfo, minBW, maxLatency float32) bool {
+	if minBW > 0 && n.ExitBandwidth >= 0 && n.ExitBandwidth < minBW {
+		return false
+	}
+	if maxLatency > 0 && n.AvgLatency > maxLatency {
+		return false
+	}
+	return true
+}
+
+// atCapacity reports whether n has no room for another client peer. A node
+// that never announced a limit is never full.
+func atCapacity(n *SuperNodeInfo) bool {
+	return n.MaxPeers > 0 && n.ActivePeers >= n.MaxPeers
+}
+
+// scoreSuperNode rates n between 0 and 1; higher is better.
+func scoreSuperNode(n *SuperNodeInfo, staleTTL time.Duration, now time.Time) float64 {
+	latency := 0.5
+	if n.AvgLatency > 0 {
+		latency = 1 / (1 + float64(n.AvgLatency)/refLatencyMs)
+	}
+
+	// Bandwidth in use is load: the busier a super, the lower it scores
+	load := 1 / (1 + float64(n.BandwidthMbps)/refBandwidthMbps)
+
+	exits := clamp01(float64(n.ExitPeers) / refExitPeers)
+
+	headroom := 0.5
+	if n.MaxPeers > 0 {
+		headroom = clamp01(float64(n.MaxPeers-n.ActivePeers) / float64(n.MaxPeers))
+	}
+
+	freshness := 1 - clamp01(float64(now.Sub(n.LastHeartbeat))/float64(staleTTL))
+
+	return weightLatency*latency +
+		weightLoad*load +
+		weightExitPeers*exits +
+		weightHeadroom*headroom +
+		weightFreshness*freshness
+}
+
+// rankSuperNodes sorts nodes best first. Nodes at capacity go last whatever
+// their score, and nodes that score the same are ordered by ID, so the
+// ranking does not depend on the order the registry listed them in.
+func rankSuperNodes(nodes []*SuperNodeInfo, staleTTL time.Duration, now time.Time) {
+	scores := make(map[string]float64, len(nodes))
+	for _, n := range nodes {
+		scores[n.NodeID] = scoreSuperNode(n, staleTTL, now)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool {
+		a, b := nodes[i], nodes[j]
+		if atCapacity(a) != atCapacity(b) {
+			return !atCapacity(a)
+		}
+		if scores[a.NodeID] != scores[b.NodeID] {
+			return scores[a.NodeID] > scores[b.NodeID]
+		}
+		return a.NodeID < b.NodeID
+	})
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
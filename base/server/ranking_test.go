@@ -0,0 +1,95 @@
+package server
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMeetsConstraintsOnExitBandwidth(t *testing.T) {
+	cases := []struct {
+		name          string
+		exitBandwidth float32
+		minBW         float32
+		want          bool
+	}{
+		{"enough left", 20, 10, true},
+		{"too little left", 5, 10, false},
+		{"exhausted exits", 0, 10, false},
+		{"exhausted exits without a minimum", 0, 0, true},
+		{"unknown", exitBandwidthUnknown, 10, true},
+	}
+	for _, c := range cases {
+		n := &SuperNodeInfo{NodeID: "super-1", ExitBandwidth: c.exitBandwidth}
+		if got := meetsConstraints(n, c.minBW, 0); got != c.want {
+			t.Errorf("%s: meetsConstraints = %v, want %v", c.name, got, c.want)
+		}
+	}
+}
+
+func TestFilteredSuperNodesSkipExhaustedExits(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	registry.Register(&SuperNodeInfo{NodeID: "exhausted", LastHeartbeat: now, ExitBandwidth: 0})
+	registry.Register(&SuperNodeInfo{NodeID: "free", LastHeartbeat: now, ExitBandwidth: 50})
+	registry.Register(&SuperNodeInfo{NodeID: "uncapped", LastHeartbeat: now, ExitBandwidth: exitBandwidthUnknown})
+	s := NewBaseNodeServer("IN", registry)
+
+	got := make(map[string]bool)
+	for _, n := range s.GetFilteredSuperNodes(0, 10, 0) {
+		got[n.NodeID] = true
+	}
+	if got["exhausted"] || !got["free"] || !got["uncapped"] || len(got) != 2 {
+		t.Fatalf("expected only free and uncapped supers, got %v", got)
+	}
+}
+
+func TestRankSuperNodesOrder(t *testing.T) {
+	now := time.Now()
+	node := func(id string, latency float32, active, maxPeers int32) *SuperNodeInfo {
+		return &SuperNodeInfo{NodeID: id, AvgLatency: latency, ActivePeers: active, MaxPeers: maxPeers, LastHeartbeat: now}
+	}
+
+	tests := []struct {
+		name  string
+		nodes []*SuperNodeInfo
+		want  []string
+	}{
+		{"lower latency first",
+			[]*SuperNodeInfo{node("slow", 200, 0, 0), node("fast", 10, 0, 0), node("medium", 50, 0, 0)},
+			[]string{"fast", "medium", "slow"}},
+		{"more headroom first",
+			[]*SuperNodeInfo{node("busy", 10, 9, 10), node("idle", 10, 1, 10)},
+			[]string{"idle", "busy"}},
+		{"full nodes last despite their score",
+			[]*SuperNodeInfo{node("full", 1, 10, 10), node("open", 200, 9, 10), node("fuller", 1, 12, 10)},
+			[]string{"open", "full", "fuller"}},
+		{"ties by node ID",
+			[]*SuperNodeInfo{node("c", 20, 0, 0), node("a", 20, 0, 0), node("b", 20, 0, 0)},
+			[]string{"a", "b", "c"}},
+	}
+	for _, tt := range tests {
+		// The same nodes in any order rank the same
+		for _, nodes := range [][]*SuperNodeInfo{tt.nodes, reversed(tt.nodes)} {
+			rankSuperNodes(nodes, time.Minute, now)
+			var got []string
+			for _, n := range nodes {
+				got = append(got, n.NodeID)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("%s: ranked %v, want %v", tt.name, got, tt.want)
+			}
+		}
+	}
+}
+
+func reversed(nodes []*SuperNodeInfo) []*SuperNodeInfo {
+	out := make([]*SuperNodeInfo, len(nodes))
+	for i, n := range nodes {
+		out[len(nodes)-1-i] = n
+	}
+	return out
+}
@@ -0,0 +1,464 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+)
+
+// NodeEventType describes a lifecycle transition of a registered super node.
+type NodeEventType int
+
+const (
+	NodeRegistered NodeEventType = iota
+	NodeStale
+	NodeRecovered
+	NodeEvicted
+	NodeUpdated
+)
+
+func (t NodeEventType) String() string {
+	switch t {
+	case NodeRegistered:
+		return "registered"
+	case NodeStale:
+		return "stale"
+	case NodeRecovered:
+		return "recovered"
+	case NodeEvicted:
+		return "evicted"
+	case NodeUpdated:
+		return "updated"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(t))
+	}
+}
+
+// NodeEvent is delivered to registry subscribers. Node is a copy taken at
+// the time of the transition.
+type NodeEvent struct {
+	Type NodeEventType
+	Node SuperNodeInfo
+	At   time.Time
+}
+
+// subscriberBuffer is how many events a subscriber may have waiting on top
+// of one update per registered node. Updates of a node coalesce while they
+// wait, so a liveness batch covering the whole region fits.
+const subscriberBuffer = 64
+
+// SuperNodeRegistry is the concurrency-safe set of super nodes registered
+// with this base. A node that misses heartbeats for staleTTL is marked
+// stale and one that misses them for deadTTL is evicted.
+type SuperNodeRegistry struct {
+	mu       sync.RWMutex
+	nodes    map[string]*SuperNodeInfo
+	stale    map[string]bool
+	store    RegistryStore
+	staleTTL time.Duration
+	deadTTL  time.Duration
+
+	subsMu  sync.Mutex
+	subs    map[int]*subscriber
+	nextSub int
+}
+
+func NewSuperNodeRegistry(store RegistryStore, staleTTL, deadTTL time.Duration) (*SuperNodeRegistry, error) {
+	if staleTTL <= 0 || deadTTL <= staleTTL {
+		return nil, fmt.Errorf("invalid TTLs: stale %s, dead %s (need 0 < stale < dead)", staleTTL, deadTTL)
+	}
+
+	r := &SuperNodeRegistry{
+		nodes:    make(map[string]*SuperNodeInfo),
+		stale:    make(map[string]bool),
+		store:    store,
+		staleTTL: staleTTL,
+		deadTTL:  deadTTL,
+		subs:     make(map[int]*subscriber),
+	}
+
+	nodes, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load super node registry: %w", err)
+	}
+	for _, n := range nodes {
+		r.nodes[n.NodeID] = n
+	}
+	log.Printf("📂 Restored %d Super Nodes from registry store", len(nodes))
+
+	return r, nil
+}
+
+func (r *SuperNodeRegistry) persist(node *SuperNodeInfo) {
+	if err := r.store.Save(node); err != nil {
+		log.Printf("⚠️ Failed to persist Super Node %s: %v", node.NodeID, err)
+	}
+}
+
+// Register inserts or replaces a super node record.
+func (r *SuperNodeRegistry) Register(node *SuperNodeInfo) {
+	r.mu.Lock()
+	r.nodes[node.NodeID] = node
+	delete(r.stale, node.NodeID)
+	r.persist(node)
+	r.publish(NodeEvent{Type: NodeRegistered, Node: *node, At: time.Now()})
+	r.mu.Unlock()
+}
+
+// Heartbeat refreshes LastHeartbeat for nodeID and applies update to the
+// record under the registry lock. It reports whether the node is known.
+func (r *SuperNodeRegistry) Heartbeat(nodeID string, update func(n *SuperNodeInfo)) bool {
+	r.mu.Lock()
+	node, ok := r.nodes[nodeID]
+	if !ok {
+		r.mu.Unlock()
+		return false
+	}
+
+	node.LastHeartbeat = time.Now()
+	if update != nil {
+		update(node)
+	}
+	r.persist(node)
+
+	ev := NodeEvent{Type: NodeUpdated, Node: *node, At: node.LastHeartbeat}
+	if r.stale[nodeID] {
+		delete(r.stale, nodeID)
+		ev.Type = NodeRecovered
+	}
+	r.publish(ev)
+	r.mu.Unlock()
+	return true
+}
+
+// Get returns a copy of the record for nodeID.
+func (r *SuperNodeRegistry) Get(nodeID string) (SuperNodeInfo, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	node, ok := r.nodes[nodeID]
+	if !ok {
+		return SuperNodeInfo{}, false
+	}
+	return *node, true
+}
+
+// IsStale reports whether nodeID has missed heartbeats for longer than the
+// stale TTL.
+func (r *SuperNodeRegistry) IsStale(nodeID string) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.stale[nodeID]
+}
+
+// List returns copies of every registered node, stale ones included.
+func (r *SuperNodeRegistry) List() []*SuperNodeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]*SuperNodeInfo, 0, len(r.nodes))
+	for _, n := range r.nodes {
+		out = append(out, copyNode(n))
+	}
+	return out
+}
+
+// Alive returns copies of every node that is not stale.
+func (r *SuperNodeRegistry) Alive() []*SuperNodeInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var out []*SuperNodeInfo
+	for id, n := range r.nodes {
+		if !r.stale[id] {
+			out = append(out, copyNode(n))
+		}
+	}
+	return out
+}
+
+// Sweep marks nodes stale based on the time since their last heartbeat and
+// returns the IDs of nodes past the dead TTL. It does not remove them:
+// evictions are replicated decisions, applied through Evict.
+func (r *SuperNodeRegistry) Sweep(now time.Time) []string {
+	var events []NodeEvent
+	var dead []string
+
+	r.mu.Lock()
+	for id, node := range r.nodes {
+		age := now.Sub(node.LastHeartbeat)
+		switch {
+		case age > r.deadTTL:
+			dead = append(dead, id)
+		case age > r.staleTTL && !r.stale[id]:
+			r.stale[id] = true
+			events = append(events, NodeEvent{Type: NodeStale, Node: *node, At: now})
+		}
+	}
+	r.publish(events...)
+	r.mu.Unlock()
+
+	return dead
+}
+
+// Evict removes nodeID if it is still past the dead TTL at the given time.
+// A heartbeat that landed after the sweep keeps the node. It reports
+// whether the node was removed.
+func (r *SuperNodeRegistry) Evict(nodeID string, at time.Time) bool {
+	r.mu.Lock()
+	node, ok := r.nodes[nodeID]
+	if !ok || at.Sub(node.LastHeartbeat) <= r.deadTTL {
+		r.mu.Unlock()
+		return false
+	}
+
+	delete(r.nodes, nodeID)
+	delete(r.stale, nodeID)
+	if err := r.store.Delete(nodeID); err != nil {
+		log.Printf("⚠️ Failed to delete Super Node %s from store: %v", nodeID, err)
+	}
+	r.publish(NodeEvent{Type: NodeEvicted, Node: *node, At: at})
+	r.mu.Unlock()
+	return true
+}
+
+// Run sweeps the registry until stop is closed, handing the nodes due for
+// eviction to evict.
+func (r *SuperNodeRegistry) Run(stop <-chan struct{}, evict func(nodeIDs []string, at time.Time)) {
+	interval := r.staleTTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			if dead := r.Sweep(now); len(dead) > 0 {
+				evict(dead, now)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// Subscribe returns a channel of lifecycle events and a function that
+// cancels the subscription. Updates of a node that are still waiting to be
+// delivered are replaced by newer ones. A subscriber that falls behind
+// anyway is dropped rather than blocking the registry: its channel is
+// closed without the subscriber cancelling, and it has to subscribe again
+// for a fresh view.
+func (r *SuperNodeRegistry) Subscribe() (<-chan NodeEvent, func()) {
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+
+	id := r.nextSub
+	r.nextSub++
+	sub := newSubscriber()
+	r.subs[id] = sub
+
+	cancel := func() {
+		r.subsMu.Lock()
+		defer r.subsMu.Unlock()
+		delete(r.subs, id)
+		sub.stop()
+	}
+	return sub.out, cancel
+}
+
+// subscriberLimit is how many events a subscriber may have waiting.
+func (r *SuperNodeRegistry) subscriberLimit() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.subscriberLimitLocked()
+}
+
+func (r *SuperNodeRegistry) subscriberLimitLocked() int {
+	return subscriberBuffer + len(r.nodes)
+}
+
+// publish queues events for every subscriber. The caller holds r.mu, so
+// subscribers see the events of concurrent changes in the order the changes
+// were made; queueing never blocks.
+func (r *SuperNodeRegistry) publish(events ...NodeEvent) {
+	if len(events) == 0 {
+		return
+	}
+
+	limit := r.subscriberLimitLocked()
+	r.subsMu.Lock()
+	defer r.subsMu.Unlock()
+
+	for _, ev := range events {
+		for id, sub := range r.subs {
+			if !sub.push(ev, limit) {
+				log.Printf("⚠️ Registry subscriber %d fell behind at %s event for %s, dropping it", id, ev.Type, ev.Node.NodeID)
+				delete(r.subs, id)
+				sub.stop()
+			}
+		}
+	}
+}
+
+// subscriber queues the events of one Subscribe call until its reader
+// takes them. A node's update replaces its previous update while that is
+// still queued; any other event of the node is queued behind it, so the
+// order of a node's transitions is kept.
+type subscriber struct {
+	mu      sync.Mutex
+	queue   []NodeEvent
+	head    int            // events taken from the queue so far
+	updates map[string]int // node ID -> position of its queued update
+	wake    chan struct{}
+	done    chan struct{}
+	once    sync.Once
+	out     chan NodeEvent
+}
+
+func newSubscriber() *subscriber {
+	sub := &subscriber{
+		updates: make(map[string]int),
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		out:     make(chan NodeEvent),
+	}
+	go sub.deliver()
+	return sub
+}
+
+// push queues ev. It reports false when limit events are already waiting.
+func (s *subscriber) push(ev NodeEvent, limit int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := ev.Node.NodeID
+	if ev.Type != NodeUpdated {
+		delete(s.updates, id)
+	} else if pos, ok := s.updates[id]; ok {
+		s.queue[pos-s.head] = ev
+		return true
+	}
+
+	if len(s.queue) >= limit {
+		return false
+	}
+	if ev.Type == NodeUpdated {
+		s.updates[id] = s.head + len(s.queue)
+	}
+	s.queue = append(s.queue, ev)
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+func (s *subscriber) pop() (NodeEvent, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.queue) == 0 {
+		return NodeEvent{}, false
+	}
+	ev := s.queue[0]
+	s.queue = s.queue[1:]
+	if pos, ok := s.updates[ev.Node.NodeID]; ok && pos == s.head {
+		delete(s.updates, ev.Node.NodeID)
+	}
+	s.head++
+	return ev, true
+}
+
+// deliver hands queued events to the reader until the subscriber stops,
+// then closes its channel.
+func (s *subscriber) deliver() {
+	defer close(s.out)
+	for {
+		ev, ok := s.pop()
+		if !ok {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case s.out <- ev:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *subscriber) stop() {
+	s.once.Do(func() { close(s.done) })
+}
+
+// Export writes the persisted registry to w.
+func (r *SuperNodeRegistry) Export(w io.Writer) error {
+	return r.store.Export(w)
+}
+
+// Import replaces the registry with the snapshot read from r. Subscribers
+// see the difference as events: nodes the snapshot adds are registered,
+// changed or no longer stale ones updated and missing ones evicted.
+func (r *SuperNodeRegistry) Import(src io.Reader) error {
+	r.mu.Lock()
+	if err := r.store.Import(src); err != nil {
+		r.mu.Unlock()
+		return err
+	}
+
+	nodes, err := r.store.Load()
+	if err != nil {
+		r.mu.Unlock()
+		return err
+	}
+
+	now := time.Now()
+	var events []NodeEvent
+	old, oldStale := r.nodes, r.stale
+	r.nodes = make(map[string]*SuperNodeInfo, len(nodes))
+	r.stale = make(map[string]bool)
+	for _, n := range nodes {
+		r.nodes[n.NodeID] = n
+		prev, ok := old[n.NodeID]
+		switch {
+		case !ok:
+			events = append(events, NodeEvent{Type: NodeRegistered, Node: *n, At: now})
+		case oldStale[n.NodeID]:
+			events = append(events, NodeEvent{Type: NodeRecovered, Node: *n, At: now})
+		case !sameNode(prev, n):
+			events = append(events, NodeEvent{Type: NodeUpdated, Node: *n, At: now})
+		}
+	}
+	for id, n := range old {
+		if _, ok := r.nodes[id]; !ok {
+			events = append(events, NodeEvent{Type: NodeEvicted, Node: *n, At: now})
+		}
+	}
+	r.publish(events...)
+	r.mu.Unlock()
+
+	log.Printf("📥 Imported %d Super Nodes from snapshot", len(nodes))
+	return nil
+}
+
+// sameNode reports whether a and b hold the same record, comparing times by
+// instant since a snapshot does not keep their monotonic clock reading.
+func sameNode(a, b *SuperNodeInfo) bool {
+	x, y := *a, *b
+	if !x.LastHeartbeat.Equal(y.LastHeartbeat) {
+		return false
+	}
+	x.LastHeartbeat, y.LastHeartbeat = time.Time{}, time.Time{}
+	return x == y
+}
@@ -0,0 +1,249 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"Base_node/pb"
+	"Dvpn_common/replay"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	opRegister     = "register"
+	opLiveness     = "liveness"
+	opPeerIdentity = "peer-identity"
+	opEvict        = "evict"
+
+	// heartbeatBatchMax is the longest the leader holds heartbeats before
+	// replicating them as one liveness command. Supers beat every 30
+	// seconds and the stale TTL is minutes, so a batch per interval keeps
+	// followers current without a log entry per heartbeat.
+	heartbeatBatchMax = 10 * time.Second
+)
+
+// registryCommand is one replicated change to the super node registry.
+// Timestamps are taken on the leader so every replica applies the same
+// values.
+type registryCommand struct {
+	Op       string             `json:"op"`
+	Node     *SuperNodeInfo     `json:"node,omitempty"`
+	Liveness []*heartbeatRecord `json:"liveness,omitempty"`
+	Peer     *PeerRecord        `json:"peer,omitempty"`
+	NodeID   string             `json:"node_id,omitempty"`
+	At       time.Time          `json:"at"`
+}
+
+// heartbeatRecord is one heartbeat held for the next liveness command, with
+// the time the leader received it.
+type heartbeatRecord struct {
+	Heartbeat *pb.HeartbeatRequest `json:"heartbeat"`
+	At        time.Time            `json:"at"`
+}
+
+// SetReplica makes registry writes go through the region's replication log.
+// Without a replica the base node applies writes directly.
+func (s *BaseNodeServer) SetReplica(r *RaftNode) {
+	s.replica = r
+}
+
+// StateMachine returns the adapter a RaftNode uses to apply committed
+// registry commands to this base node.
+func (s *BaseNodeServer) StateMachine() StateMachine {
+	return registryStateMachine{s: s}
+}
+
+// writeLeader reports whether this replica must forward writes, and where.
+func (s *BaseNodeServer) writeLeader() (string, bool, error) {
+	if s.replica == nil || s.replica.IsLeader() {
+		return "", false, nil
+	}
+
+	leader := s.replica.Leader()
+	if leader == "" {
+		return "", false, status.Error(codes.Unavailable, "no replication leader elected yet")
+	}
+	return leader, true, nil
+}
+
+func (s *BaseNodeServer) commit(cmd *registryCommand) error {
+	if s.replica == nil {
+		s.applyCommand(cmd)
+		return nil
+	}
+
+	data, err := json.Marshal(cmd)
+	if err != nil {
+		return err
+	}
+	if err := s.replica.Propose(data); err != nil {
+		return status.Errorf(codes.Unavailable, "replication failed: %v", err)
+	}
+	return nil
+}
+
+// recordHeartbeat applies a heartbeat directly on a base without replicas.
+// A replicated leader holds it for the next liveness batch instead, so
+// heartbeats never cost a consensus round each. Only the latest heartbeat
+// of a node is kept, and one signed no later than the last accepted is
+// refused as a replay.
+func (s *BaseNodeServer) recordHeartbeat(req *pb.HeartbeatRequest, at time.Time) error {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if last, ok := s.lastSigned[req.NodeId]; ok && req.SignedAt <= last {
+		return fmt.Errorf("%w: heartbeat signed at %d, last accepted %d", replay.ErrReplayedNonce, req.SignedAt, last)
+	}
+	s.lastSigned[req.NodeId] = req.SignedAt
+
+	rec := &heartbeatRecord{Heartbeat: req, At: at}
+	if s.replica == nil {
+		s.applyCommand(&registryCommand{Op: opLiveness, Liveness: []*heartbeatRecord{rec}, At: at})
+		return nil
+	}
+	s.pending[req.NodeId] = rec
+	return nil
+}
+
+// flushHeartbeats replicates the heartbeats held since the last batch.
+func (s *BaseNodeServer) flushHeartbeats() {
+	s.pendingMu.Lock()
+	if len(s.pending) == 0 {
+		s.pendingMu.Unlock()
+		return
+	}
+	batch := make([]*heartbeatRecord, 0, len(s.pending))
+	for _, rec := range s.pending {
+		batch = append(batch, rec)
+	}
+	s.pending = make(map[string]*heartbeatRecord)
+	s.pendingMu.Unlock()
+
+	if err := s.commit(&registryCommand{Op: opLiveness, Liveness: batch, At: time.Now()}); err != nil {
+		log.Printf("⚠️ Failed to replicate %d heartbeats: %v", len(batch), err)
+	}
+}
+
+// runHeartbeatBatches flushes held heartbeats until stop is closed. The
+// interval stays well inside the stale TTL so followers never see a live
+// node as stale.
+func (s *BaseNodeServer) runHeartbeatBatches(stop <-chan struct{}) {
+	interval := s.registry.staleTTL / 4
+	if interval > heartbeatBatchMax {
+		interval = heartbeatBatchMax
+	}
+	if interval < 100*time.Millisecond {
+		interval = 100 * time.Millisecond
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.flushHeartbeats()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// evictDead proposes the eviction of nodes a sweep found past the dead TTL.
+// Only the leader proposes, so every replica drops the same nodes at the
+// same log position; followers wait for the committed command.
+func (s *BaseNodeServer) evictDead(nodeIDs []string, at time.Time) {
+	if s.replica != nil && !s.replica.IsLeader() {
+		return
+	}
+
+	// Held heartbeats go first, so a node that just beat is kept by the
+	// eviction check on every replica.
+	s.flushHeartbeats()
+	for _, id := range nodeIDs {
+		if err := s.commit(&registryCommand{Op: opEvict, NodeID: id, At: at}); err != nil {
+			log.Printf("⚠️ Failed to evict Super Node %s: %v", id, err)
+		}
+	}
+}
+
+func (s *BaseNodeServer) applyCommand(cmd *registryCommand) {
+	switch cmd.Op {
+	case opRegister:
+		if err := s.pins.Pin(cmd.Node.NodeID, cmd.Node.PublicKey); err != nil {
+			log.Printf("⚠️ Failed to persist key pin for %s: %v", cmd.Node.NodeID, err)
+		}
+		s.registry.Register(cmd.Node)
+	case opLiveness:
+		for _, rec := range cmd.Liveness {
+			req, at := rec.Heartbeat, rec.At
+			s.registry.Heartbeat(req.NodeId, func(node *SuperNodeInfo) {
+				node.LastHeartbeat = at
+				node.BandwidthMbps = req.BandwidthUsageMbps
+				node.AvgLatency = req.AvgLatencyMs
+				node.ActivePeers = req.ActivePeers
+				node.ExitPeers = req.ExitPeersAvailable
+				node.ExitBandwidth = req.ExitBandwidthMbps
+				node.CPUPercent = req.CpuPercent
+				node.MemoryMB = req.MemoryMb
+			})
+		}
+	case opPeerIdentity:
+		s.peers.Put(cmd.Peer, cmd.At)
+	case opEvict:
+		if s.registry.Evict(cmd.NodeID, cmd.At) {
+			s.pendingMu.Lock()
+			delete(s.lastSigned, cmd.NodeID)
+			s.pendingMu.Unlock()
+		}
+	default:
+		log.Printf("⚠️ Ignoring unknown registry command %q", cmd.Op)
+	}
+}
+
+type registryStateMachine struct {
+	s *BaseNodeServer
+}
+
+func (m registryStateMachine) Apply(command []byte) {
+	var cmd registryCommand
+	if err := json.Unmarshal(command, &cmd); err != nil {
+		log.Printf("⚠️ Failed to decode registry command: %v", err)
+		return
+	}
+	m.s.applyCommand(&cmd)
+}
+
+// replicaSnapshot is the state a lagging replica installs: the registry
+// plus the key pins, which outlive registry entries, and the peer
+// identities.
+type replicaSnapshot struct {
+	Registry []byte                 `json:"registry"`
+	Pins     map[string]string      `json:"pins"`
+	Peers    map[string]*PeerRecord `json:"peers,omitempty"`
+}
+
+func (m registryStateMachine) Snapshot() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := m.s.registry.Export(&buf); err != nil {
+		return nil, err
+	}
+	return json.Marshal(replicaSnapshot{Registry: buf.Bytes(), Pins: m.s.pins.All(), Peers: m.s.peers.All()})
+}
+
+func (m registryStateMachine) Restore(data []byte) error {
+	var snap replicaSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return err
+	}
+	if err := m.s.pins.Replace(snap.Pins); err != nil {
+		return err
+	}
+	m.s.peers.Replace(snap.Peers)
+	return m.s.registry.Import(bytes.NewReader(snap.Registry))
+}
@@ -0,0 +1,222 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"Base_node/pb"
+
+	"google.golang.org/grpc"
+)
+
+type testBase struct {
+	srv  *BaseNodeServer
+	node *RaftNode
+}
+
+// newReplicatedBases starts size base node servers replicating one
+// in-memory registry.
+func newReplicatedBases(t *testing.T, size int) []*testBase {
+	t.Helper()
+
+	lis := make([]net.Listener, size)
+	addrs := make([]string, size)
+	for i := range lis {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		lis[i] = l
+		addrs[i] = l.Addr().String()
+	}
+
+	bases := make([]*testBase, size)
+	for i := range bases {
+		registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv := NewBaseNodeServer("IN", registry)
+		node, err := NewRaftNode(addrs[i], addrs, "", srv.StateMachine())
+		if err != nil {
+			t.Fatal(err)
+		}
+		srv.SetReplica(node)
+
+		g := grpc.NewServer()
+		pb.RegisterBaseNodeServiceServer(g, srv)
+		pb.RegisterBaseReplicationServiceServer(g, node)
+		go g.Serve(lis[i])
+		node.Start()
+		t.Cleanup(func() {
+			node.Stop()
+			g.Stop()
+			srv.Close()
+		})
+		bases[i] = &testBase{srv: srv, node: node}
+	}
+	return bases
+}
+
+func signedRegistration(t *testing.T, region string) (*pb.RegisterRequest, ed25519.PrivateKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubB64 := base64.StdEncoding.EncodeToString(pub)
+	id, err := DeriveNodeID("super", region, pubB64)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := &pb.RegisterRequest{
+		NodeId:    id,
+		Region:    region,
+		Ip:        "10.0.0.1",
+		Port:      "50052",
+		PublicKey: pubB64,
+		Nonce:     "nonce-1",
+		SignedAt:  time.Now().Unix(),
+		MaxPeers:  10,
+	}
+	msg := fmt.Sprintf("%s|%s|%s|%s|%d", req.NodeId, req.Region, req.Ip, req.Nonce, req.SignedAt)
+	req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(msg)))
+	return req, priv
+}
+
+func signedHeartbeat(priv ed25519.PrivateKey, nodeID string, activePeers int32, signedAt int64) *pb.HeartbeatRequest {
+	req := &pb.HeartbeatRequest{NodeId: nodeID, ActivePeers: activePeers, SignedAt: signedAt}
+	msg := fmt.Sprintf("heartbeat|%s|%d|%d|%g|%g|%g|%g|%g|%d", req.NodeId, req.ActivePeers, req.ExitPeersAvailable,
+		req.AvgLatencyMs, req.BandwidthUsageMbps, req.ExitBandwidthMbps, req.CpuPercent, req.MemoryMb, req.SignedAt)
+	req.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(msg)))
+	return req
+}
+
+func TestFollowerForwardsWritesToLeader(t *testing.T) {
+	bases := newReplicatedBases(t, 3)
+
+	var leader, follower *testBase
+	waitFor(t, "a leader", func() bool {
+		for _, b := range bases {
+			if b.node.IsLeader() {
+				leader = b
+			}
+		}
+		return leader != nil
+	})
+	for _, b := range bases {
+		if b != leader {
+			follower = b
+			break
+		}
+	}
+	waitFor(t, "the follower to learn the leader", func() bool {
+		return follower.node.Leader() == leader.node.id
+	})
+
+	req, priv := signedRegistration(t, "IN")
+	res, err := follower.srv.RegisterSuperNode(context.Background(), req)
+	if err != nil || !res.Success {
+		t.Fatalf("registration through follower failed: %v %v", res, err)
+	}
+	for _, b := range bases {
+		b := b
+		waitFor(t, "the registration on every replica", func() bool {
+			_, ok := b.srv.registry.Get(req.NodeId)
+			return ok
+		})
+	}
+
+	ack, err := follower.srv.SuperNodeHeartbeat(context.Background(), signedHeartbeat(priv, req.NodeId, 7, time.Now().Unix()))
+	if err != nil || !ack.Received {
+		t.Fatalf("heartbeat through follower failed: %v %v", ack, err)
+	}
+	// Heartbeats are held by the leader until the next liveness batch
+	leader.srv.flushHeartbeats()
+	for _, b := range bases {
+		b := b
+		waitFor(t, "the heartbeat on every replica", func() bool {
+			node, _ := b.srv.registry.Get(req.NodeId)
+			return node.ActivePeers == 7
+		})
+	}
+}
+
+func TestEvictionIsReplicated(t *testing.T) {
+	bases := newReplicatedBases(t, 3)
+
+	var leader *testBase
+	waitFor(t, "a leader", func() bool {
+		for _, b := range bases {
+			if b.node.IsLeader() {
+				leader = b
+			}
+		}
+		return leader != nil
+	})
+
+	req, _ := signedRegistration(t, "IN")
+	if res, err := leader.srv.RegisterSuperNode(context.Background(), req); err != nil || !res.Success {
+		t.Fatalf("registration failed: %v %v", res, err)
+	}
+
+	// A sweep past the dead TTL on the leader removes the node everywhere
+	later := time.Now().Add(time.Hour)
+	leader.srv.evictDead(leader.srv.registry.Sweep(later), later)
+	for _, b := range bases {
+		b := b
+		waitFor(t, "the eviction on every replica", func() bool {
+			_, ok := b.srv.registry.Get(req.NodeId)
+			return !ok
+		})
+	}
+}
+
+func TestHeartbeatMustBeSignedBySuper(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := NewBaseNodeServer("IN", registry)
+
+	req, priv := signedRegistration(t, "IN")
+	if res, err := srv.RegisterSuperNode(context.Background(), req); err != nil || !res.Success {
+		t.Fatalf("registration failed: %v %v", res, err)
+	}
+	now := time.Now().Unix()
+
+	// A relaying base cannot change the figures of a signed heartbeat
+	forged := signedHeartbeat(priv, req.NodeId, 1, now)
+	forged.ActivePeers = 0
+	if ack, _ := srv.SuperNodeHeartbeat(context.Background(), forged); ack.Received {
+		t.Fatal("altered heartbeat accepted")
+	}
+
+	_, other, _ := ed25519.GenerateKey(rand.Reader)
+	if ack, _ := srv.SuperNodeHeartbeat(context.Background(), signedHeartbeat(other, req.NodeId, 1, now)); ack.Received {
+		t.Fatal("heartbeat signed by another key accepted")
+	}
+
+	if ack, _ := srv.SuperNodeHeartbeat(context.Background(), signedHeartbeat(priv, req.NodeId, 1, now-600)); ack.Received {
+		t.Fatal("stale heartbeat accepted")
+	}
+
+	good := signedHeartbeat(priv, req.NodeId, 3, now)
+	if ack, _ := srv.SuperNodeHeartbeat(context.Background(), good); !ack.Received {
+		t.Fatalf("signed heartbeat rejected: %s", ack.Message)
+	}
+	if ack, _ := srv.SuperNodeHeartbeat(context.Background(), good); ack.Received {
+		t.Fatal("replayed heartbeat accepted")
+	}
+	if node, _ := registry.Get(req.NodeId); node.ActivePeers != 3 {
+		t.Fatalf("active peers %d, want 3", node.ActivePeers)
+	}
+}
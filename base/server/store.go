@@ -0,0 +1,136 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RegistryStore persists the super node registry so that a base node
+// restart does not make the region forget its super nodes.
+type RegistryStore interface {
+	// Load returns every record known to the store.
+	Load() ([]*SuperNodeInfo, error)
+	// Save inserts or replaces the record for node.NodeID.
+	Save(node *SuperNodeInfo) error
+	// Delete removes the record for nodeID, if any.
+	Delete(nodeID string) error
+	// Export writes a point-in-time snapshot of all records to w.
+	Export(w io.Writer) error
+	// Import replaces the store contents with the snapshot read from r.
+	Import(r io.Reader) error
+	Close() error
+}
+
+const snapshotVersion = 1
+
+// registrySnapshot is the on-disk and export format of a full registry.
+type registrySnapshot struct {
+	Version int              `json:"version"`
+	TakenAt time.Time        `json:"taken_at"`
+	Nodes   []*SuperNodeInfo `json:"nodes"`
+}
+
+func writeSnapshot(w io.Writer, nodes map[string]*SuperNodeInfo) error {
+	snap := registrySnapshot{
+		Version: snapshotVersion,
+		TakenAt: time.Now(),
+	}
+	for _, n := range nodes {
+		snap.Nodes = append(snap.Nodes, n)
+	}
+	sort.Slice(snap.Nodes, func(i, j int) bool { return snap.Nodes[i].NodeID < snap.Nodes[j].NodeID })
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(&snap)
+}
+
+func readSnapshot(r io.Reader) (map[string]*SuperNodeInfo, error) {
+	var snap registrySnapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, fmt.Errorf("failed to decode registry snapshot: %w", err)
+	}
+	if snap.Version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported registry snapshot version %d", snap.Version)
+	}
+
+	nodes := make(map[string]*SuperNodeInfo, len(snap.Nodes))
+	for _, n := range snap.Nodes {
+		if n == nil || n.NodeID == "" {
+			continue
+		}
+		nodes[n.NodeID] = n
+	}
+	return nodes, nil
+}
+
+func copyNode(n *SuperNodeInfo) *SuperNodeInfo {
+	c := *n
+	return &c
+}
+
+// MemoryStore keeps records in memory only. It is used when no data
+// directory is configured.
+type MemoryStore struct {
+	mu    sync.Mutex
+	nodes map[string]*SuperNodeInfo
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{nodes: make(map[string]*SuperNodeInfo)}
+}
+
+func (m *MemoryStore) Load() ([]*SuperNodeInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var out []*SuperNodeInfo
+	for _, n := range m.nodes {
+		out = append(out, copyNode(n))
+	}
+	return out, nil
+}
+
+func (m *MemoryStore) Save(node *SuperNodeInfo) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nodes[node.NodeID] = copyNode(node)
+	return nil
+}
+
+func (m *MemoryStore) Delete(nodeID string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.nodes, nodeID)
+	return nil
+}
+
+func (m *MemoryStore) Export(w io.Writer) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return writeSnapshot(w, m.nodes)
+}
+
+func (m *MemoryStore) Import(r io.Reader) error {
+	nodes, err := readSnapshot(r)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.nodes = nodes
+	return nil
+}
+
+func (m *MemoryStore) Close() error {
+	return nil
+}
@@ -0,0 +1,63 @@
+package server
+
+import (
+	"Base_node/pb"
+	"context"
+	"fmt"
+	"log"
+)
+
+// VerifySuperNode tells a super node whether another super is registered,
+// under the key it claims, with the base of its region. Supers use it before
+// serving exits to a remote super.
+func (s *BaseNodeServer) VerifySuperNode(ctx context.Context, req *pb.SuperNodeIdentity) (*pb.Ack, error) {
+	key, err := s.superNodeKey(req.NodeId, req.Region)
+	if err != nil {
+		log.Printf("❌ Could not verify Super Node %s [%s]: %v", req.NodeId, req.Region, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+	if key != req.PublicKey {
+		log.Printf("❌ Super Node %s [%s] is registered under a different key", req.NodeId, req.Region)
+		return &pb.Ack{Received: false, Message: "Super Node registered under a different key"}, nil
+	}
+	return &pb.Ack{Received: true, Message: "Super Node verified"}, nil
+}
+
+// superNodeKey returns the public key nodeID is registered under, asking the
+// base of region over federation when it is not ours.
+func (s *BaseNodeServer) superNodeKey(nodeID, region string) (string, error) {
+	if region == s.localRegion {
+		nodes := s.lookupSuperNode(nodeID)
+		if len(nodes) == 0 {
+			return "", fmt.Errorf("Super Node not found")
+		}
+		return nodes[0].PublicKey, nil
+	}
+
+	if s.federation == nil {
+		return "", fmt.Errorf("No base node found for region %s", region)
+	}
+	remote, err := s.federation.FetchSupers(&pb.RemoteSuperRequest{
+		TargetRegion: region,
+		NodeId:       nodeID,
+		HopLimit:     defaultHopLimit,
+	})
+	if err != nil {
+		return "", err
+	}
+	for _, sn := range remote {
+		if sn.NodeId == nodeID && sn.Region == region {
+			return sn.PublicKey, nil
+		}
+	}
+	return "", fmt.Errorf("Super Node not found in region %s", region)
+}
+
+// lookupSuperNode returns nodeID if it is registered here and not stale.
+func (s *BaseNodeServer) lookupSuperNode(nodeID string) []*SuperNodeInfo {
+	node, ok := s.registry.Get(nodeID)
+	if !ok || s.registry.IsStale(nodeID) {
+		return nil
+	}
+	return []*SuperNodeInfo{&node}
+}
@@ -1,24 +1,78 @@
 package server
 
 import (
+	"context"
 	"crypto/ed25519"
 	"encoding/base64"
+	"errors"
 	"fmt"
+
+	"Base_node/pb"
+	"Base_node/utils"
+)
+
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrCertMismatch     = errors.New("TLS certificate does not match the claimed identity")
 )
 
+// VerifySuperNodeSignature checks the ed25519 signature over
+// id|region|ip|nonce|signedAt.
+func VerifySuperNodeSignature(nodeID, region, ip, nonce string, signedAt int64, pubKeyBase64, signatureBase64 string) error {
+	msg := fmt.Sprintf("%s|%s|%s|%s|%d", nodeID, region, ip, nonce, signedAt)
 
-func VerifySuperNodeSignature(nodeID, region, ip, nonce, pubKeyBase64, signatureBase64 string) bool {
-	msg := fmt.Sprintf("%s|%s|%s|%s", nodeID, region, ip, nonce)
-	
 	pubKeyBytes, err := base64.StdEncoding.DecodeString(pubKeyBase64)
-	if err != nil {
-		return false
+	if err != nil || len(pubKeyBytes) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
 	}
 
 	signBytes, err := base64.StdEncoding.DecodeString(signatureBase64)
 	if err != nil {
-		return false
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+
+	if !ed25519.Verify(pubKeyBytes, []byte(msg), signBytes) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// VerifyHeartbeatSignature checks the ed25519 signature a super node puts on
+// its heartbeat.
+func VerifyHeartbeatSignature(req *pb.HeartbeatRequest, pubKeyBase64 string) error {
+	msg := fmt.Sprintf("heartbeat|%s|%d|%d|%g|%g|%g|%g|%g|%d", req.NodeId, req.ActivePeers, req.ExitPeersAvailable,
+		req.AvgLatencyMs, req.BandwidthUsageMbps, req.ExitBandwidthMbps, req.CpuPercent, req.MemoryMb, req.SignedAt)
+
+	pubKeyBytes, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+	if err != nil || len(pubKeyBytes) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
+	}
+
+	signBytes, err := base64.StdEncoding.DecodeString(req.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
 	}
 
-	return ed25519.Verify(pubKeyBytes, []byte(msg), signBytes)
-}
\ No newline at end of file
+	if !ed25519.Verify(pubKeyBytes, []byte(msg), signBytes) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// checkCaller ensures the TLS certificate of a super node caller carries
+// pubKeyBase64. A base certificate is let through because replicas relay
+// writes to their leader; every relayed write is signed by the super itself
+// and the leader checks that signature. Without mutual TLS it always passes.
+func checkCaller(ctx context.Context, pubKeyBase64 string) error {
+	id, ok := utils.PeerIdentity(ctx)
+	if !ok || id.Role == utils.RoleBase {
+		return nil
+	}
+	if id.Role != utils.RoleSuper {
+		return fmt.Errorf("%w: %q is not a super node certificate", ErrCertMismatch, id.Name)
+	}
+	if id.PublicKey != pubKeyBase64 {
+		return fmt.Errorf("%w: certificate %q carries a different key", ErrCertMismatch, id.Name)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package server
+
+import (
+	"log"
+	"time"
+
+	"Base_node/pb"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// WatchSuperNodes streams a snapshot of the registry followed by every
+// registry change until the client goes away. A watcher that falls too far
+// behind gets its stream ended with ResourceExhausted, rather than silently
+// missing events, and should reconnect to get a fresh snapshot.
+func (s *BaseNodeServer) WatchSuperNodes(_ *emptypb.Empty, stream pb.BaseNodeService_WatchSuperNodesServer) error {
+	// Subscribe before taking the snapshot so no change falls in between.
+	events, cancel := s.registry.Subscribe()
+	defer cancel()
+
+	snapshot := &pb.SuperNodeEvent{
+		Type: pb.SuperNodeEvent_SNAPSHOT,
+		At:   time.Now().Format(time.RFC3339),
+	}
+	for _, n := range s.registry.List() {
+		snapshot.Snapshot = append(snapshot.Snapshot, superNodeToPB(n, !s.registry.IsStale(n.NodeID)))
+	}
+	if err := stream.Send(snapshot); err != nil {
+		return err
+	}
+
+	log.Printf("👀 Super Node watcher connected, sent %d nodes", len(snapshot.Snapshot))
+	defer log.Println("👀 Super Node watcher disconnected")
+
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				log.Println("⚠️ Super Node watcher fell behind, ending its stream")
+				return status.Error(codes.ResourceExhausted, "watcher fell behind the registry, reconnect for a fresh snapshot")
+			}
+			if err := stream.Send(nodeEventToPB(ev)); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.stop:
+			return status.Error(codes.Unavailable, "base node is shutting down")
+		}
+	}
+}
+
+func nodeEventToPB(ev NodeEvent) *pb.SuperNodeEvent {
+	out := &pb.SuperNodeEvent{
+		At: ev.At.Format(time.RFC3339),
+	}
+
+	alive := true
+	switch ev.Type {
+	case NodeRegistered:
+		out.Type = pb.SuperNodeEvent_ADDED
+	case NodeUpdated, NodeRecovered:
+		out.Type = pb.SuperNodeEvent_UPDATED
+	case NodeStale:
+		out.Type = pb.SuperNodeEvent_STALE
+		alive = false
+	case NodeEvicted:
+		out.Type = pb.SuperNodeEvent_REMOVED
+		alive = false
+	}
+	out.Node = superNodeToPB(&ev.Node, alive)
+	return out
+}
@@ -0,0 +1,317 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"Base_node/pb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// blockedWatchStream accepts the snapshot, then blocks every send until
+// release is closed, like a watcher on a stalled connection.
+type blockedWatchStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	snapshot chan struct{}
+	release  chan struct{}
+	sent     int
+}
+
+func (b *blockedWatchStream) Context() context.Context { return b.ctx }
+
+func (b *blockedWatchStream) Send(ev *pb.SuperNodeEvent) error {
+	if ev.Type == pb.SuperNodeEvent_SNAPSHOT {
+		close(b.snapshot)
+		return nil
+	}
+	<-b.release
+	b.sent++
+	return nil
+}
+
+func TestRegistryDropsSlowSubscriber(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Register(&SuperNodeInfo{NodeID: "super-1", Region: "IN"})
+
+	slow, cancelSlow := registry.Subscribe()
+	defer cancelSlow()
+	fast, cancelFast := registry.Subscribe()
+	defer cancelFast()
+
+	// Registrations do not coalesce. One is held in delivery, then the
+	// queue fills up and overflows.
+	limit := registry.subscriberLimit()
+	received := 0
+	for i := 0; i < limit+2; i++ {
+		registry.Register(&SuperNodeInfo{NodeID: "super-1", Region: "IN"})
+		<-fast
+		received++
+	}
+
+	// The slow subscriber's channel was closed, its backlog dropped
+	got := 0
+	closed := make(chan struct{})
+	go func() {
+		for range slow {
+			got++
+		}
+		close(closed)
+	}()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow subscriber was not dropped")
+	}
+	if got > 1 {
+		t.Fatalf("slow subscriber got %d events after being dropped, want at most the one in delivery", got)
+	}
+	if received != limit+2 {
+		t.Fatalf("fast subscriber got %d events, want %d", received, limit+2)
+	}
+
+	// Cancelling after being dropped is harmless
+	cancelSlow()
+	cancelSlow()
+}
+
+func TestWatchEndsStreamOfSlowWatcher(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Register(&SuperNodeInfo{NodeID: "super-1", Region: "IN"})
+	s := NewBaseNodeServer("IN", registry)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream := &blockedWatchStream{
+		ctx:      ctx,
+		snapshot: make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- s.WatchSuperNodes(nil, stream) }()
+	<-stream.snapshot
+
+	// One event is held by the blocked send, one in delivery, the queue
+	// takes the rest until it overflows
+	limit := registry.subscriberLimit()
+	for i := 0; i < limit+3; i++ {
+		registry.Register(&SuperNodeInfo{NodeID: "super-1", Region: "IN"})
+	}
+	close(stream.release)
+
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.ResourceExhausted {
+			t.Fatalf("slow watcher ended with %v, want ResourceExhausted", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("stream of slow watcher was not ended")
+	}
+	if stream.sent > limit+2 {
+		t.Fatalf("slow watcher was sent %d events, more than it could have buffered", stream.sent)
+	}
+}
+
+func TestSubscriberCoalescesUpdatesOfLargeRegion(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := 3 * subscriberBuffer
+	for i := 0; i < nodes; i++ {
+		registry.Register(&SuperNodeInfo{NodeID: fmt.Sprintf("super-%d", i), Region: "IN"})
+	}
+
+	events, cancel := registry.Subscribe()
+	defer cancel()
+
+	// Three liveness batches covering every node, while the subscriber
+	// does not read
+	for round := int32(1); round <= 3; round++ {
+		for i := 0; i < nodes; i++ {
+			registry.Heartbeat(fmt.Sprintf("super-%d", i), func(n *SuperNodeInfo) { n.ActivePeers = round })
+		}
+	}
+
+	// Each node's updates collapse into one, apart from the update that was
+	// already in delivery when the batches ran
+	latest := make(map[string]int32)
+	received := 0
+	for done := false; !done; {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				t.Fatalf("subscriber was dropped after %d events", received)
+			}
+			latest[ev.Node.NodeID] = ev.Node.ActivePeers
+			received++
+		case <-time.After(200 * time.Millisecond):
+			done = true
+		}
+	}
+	if received > nodes+1 {
+		t.Fatalf("got %d events for %d nodes, want them coalesced", received, nodes)
+	}
+	for i := 0; i < nodes; i++ {
+		if id := fmt.Sprintf("super-%d", i); latest[id] != 3 {
+			t.Fatalf("node %s last delivered with %d peers, want the latest (3)", id, latest[id])
+		}
+	}
+}
+
+func TestImportPublishesDifference(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry.Register(&SuperNodeInfo{NodeID: "gone", Region: "IN"})
+	registry.Register(&SuperNodeInfo{NodeID: "kept", Region: "IN"})
+	registry.Register(&SuperNodeInfo{NodeID: "changed", Region: "IN"})
+
+	other, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	kept, _ := registry.Get("kept")
+	other.Register(&kept)
+	other.Register(&SuperNodeInfo{NodeID: "changed", Region: "IN", ActivePeers: 5})
+	other.Register(&SuperNodeInfo{NodeID: "new", Region: "IN"})
+	var snap bytes.Buffer
+	if err := other.Export(&snap); err != nil {
+		t.Fatal(err)
+	}
+
+	events, cancel := registry.Subscribe()
+	defer cancel()
+	if err := registry.Import(&snap); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]NodeEventType{"gone": NodeEvicted, "changed": NodeUpdated, "new": NodeRegistered}
+	got := make(map[string]NodeEventType)
+	for len(got) < len(want) {
+		select {
+		case ev := <-events:
+			got[ev.Node.NodeID] = ev.Type
+		case <-time.After(5 * time.Second):
+			t.Fatalf("got events %v, want %v", got, want)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got events %v, want %v", got, want)
+	}
+}
+
+func TestConcurrentRegisterAndEvictPublishInOrder(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Enough nodes that every event below fits the subscriber's queue
+	for i := 0; i < 32*subscriberBuffer; i++ {
+		registry.Register(&SuperNodeInfo{NodeID: fmt.Sprintf("filler-%d", i), Region: "IN"})
+	}
+
+	events, cancel := registry.Subscribe()
+	defer cancel()
+
+	// Registered long ago, so an eviction succeeds until a heartbeat lands
+	var wg sync.WaitGroup
+	for w := 0; w < 16; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 40; i++ {
+				registry.Register(&SuperNodeInfo{NodeID: "super-1", Region: "IN", LastHeartbeat: time.Now().Add(-time.Hour)})
+				registry.Heartbeat("super-1", nil)
+				registry.Evict("super-1", time.Now().Add(time.Hour))
+			}
+		}()
+	}
+	wg.Wait()
+	registry.Register(&SuperNodeInfo{NodeID: "done", Region: "IN"})
+
+	// Events of super-1 must follow a valid lifecycle and end in the state
+	// the registry is left in
+	present := false
+	for {
+		var ev NodeEvent
+		select {
+		case ev = <-events:
+		case <-time.After(5 * time.Second):
+			t.Fatal("events stopped before the last registration")
+		}
+		if ev.Node.NodeID == "done" {
+			break
+		}
+		switch ev.Type {
+		case NodeRegistered:
+			present = true
+		case NodeUpdated, NodeRecovered:
+			if !present {
+				t.Fatalf("%s event for super-1 after it was evicted", ev.Type)
+			}
+		case NodeEvicted:
+			if !present {
+				t.Fatal("super-1 evicted twice without a registration in between")
+			}
+			present = false
+		}
+	}
+	if _, ok := registry.Get("super-1"); ok != present {
+		t.Fatalf("events leave super-1 registered=%v, registry has it registered=%v", present, ok)
+	}
+}
+
+func TestStopEndsWatchStreamsAndMonitoring(t *testing.T) {
+	registry, err := NewSuperNodeRegistry(NewMemoryStore(), time.Minute, 2*time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewBaseNodeServer("IN", registry)
+	s.StartSuperNodeMonitoring()
+
+	// The watcher's client stays connected, as a following client peer does
+	stream := &blockedWatchStream{
+		ctx:      context.Background(),
+		snapshot: make(chan struct{}),
+		release:  make(chan struct{}),
+	}
+	done := make(chan error, 1)
+	go func() { done <- s.WatchSuperNodes(nil, stream) }()
+	<-stream.snapshot
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("monitoring loops did not stop")
+	}
+	select {
+	case err := <-done:
+		if status.Code(err) != codes.Unavailable {
+			t.Fatalf("watch ended with %v, want Unavailable", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("watch stream outlived the server")
+	}
+}
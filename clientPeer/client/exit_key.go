@@ -0,0 +1,52 @@
+package client
+
+import (
+	"Client_peer/crypto"
+	"Client_peer/pb"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"time"
+)
+
+// signClientKey signs the WireGuard key in req with our identity key, so the
+// exit can check it is the key we asked with.
+func (cp *ClientPeer) signClientKey(req *pb.ExitRequest) error {
+	priv, pub, err := crypto.LoadOrCreateKeypair()
+	if err != nil {
+		return fmt.Errorf("failed to load identity key: %w", err)
+	}
+
+	req.ClientIdentityKey = base64.StdEncoding.EncodeToString(pub)
+	req.ClientKeySignedAt = time.Now().Unix()
+	req.ClientKeySignature = crypto.SignKeyPayload(priv, crypto.ClientKeyPayload(
+		req.PeerId, req.ClientPublicKey, req.RequestedRegion, req.ClientKeySignedAt))
+	return nil
+}
+
+// verifyExitConfig checks that cfg is signed by the identity key the exit
+// registered with the base of region, and that the signature covers our
+// session and WireGuard key.
+func (cp *ClientPeer) verifyExitConfig(cfg *pb.WireguardConfig, region, clientWGKey string) error {
+	if cfg.ExitSignature == "" {
+		return fmt.Errorf("exit answer is not signed")
+	}
+	pub, err := base64.StdEncoding.DecodeString(cfg.ExitIdentityKey)
+	if err != nil || crypto.DerivePeerID(region, pub) != cfg.ExitPeerId {
+		return fmt.Errorf("exit peer ID does not match its key")
+	}
+
+	host, port, err := net.SplitHostPort(cfg.PeerEndpoint)
+	if err != nil {
+		return fmt.Errorf("invalid peer endpoint format: %v", err)
+	}
+	payload := crypto.ExitKeyPayload(cfg.ExitPeerId, cfg.PeerPublicKey, host, port,
+		cfg.InterfaceAddress, cfg.SessionId, clientWGKey)
+	if !crypto.VerifyKeyPayload(cfg.ExitIdentityKey, payload, cfg.ExitSignature) {
+		return fmt.Errorf("invalid exit signature")
+	}
+
+	// Anyone on the path can derive an ID from a key of their own; only the
+	// base knows which keys exits registered
+	return cp.verifyExitIdentity(cfg.ExitPeerId, region, cfg.ExitIdentityKey)
+}
@@ -0,0 +1,85 @@
+package client
+
+import (
+	"Client_peer/crypto"
+	"Client_peer/pb"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+)
+
+// identityRefresh is how often the identity is registered again with the
+// base node, well within the base's retention.
+const identityRefresh = 10 * time.Minute
+
+// SetBaseClient sets the base node the peer registers its identity with and
+// asks about the identity of exits.
+func (cp *ClientPeer) SetBaseClient(base pb.BaseNodeServiceClient) {
+	cp.baseClient = base
+}
+
+// RegisterIdentity registers our identity key with the base node, so exits
+// and clients can check it without trusting the supers in between.
+func (cp *ClientPeer) RegisterIdentity() error {
+	if cp.baseClient == nil {
+		return fmt.Errorf("no base node set")
+	}
+	priv, pub, err := crypto.LoadOrCreateKeypair()
+	if err != nil {
+		return fmt.Errorf("failed to load identity key: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	nonce := crypto.GenerateNonce()
+	signedAt := time.Now().Unix()
+	ack, err := cp.baseClient.RegisterPeerIdentity(ctx, &pb.PeerIdentity{
+		PeerId:    cp.id,
+		Region:    cp.region,
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		Nonce:     nonce,
+		SignedAt:  signedAt,
+		Signature: crypto.SignPeerIdentity(priv, cp.id, cp.region, nonce, signedAt),
+	})
+	if err != nil {
+		return err
+	}
+	if !ack.Received {
+		return fmt.Errorf("identity rejected by base node: %s", ack.Message)
+	}
+	return nil
+}
+
+// KeepIdentityRegistered registers the identity again every
+// identityRefresh until the process exits.
+func (cp *ClientPeer) KeepIdentityRegistered() {
+	for {
+		time.Sleep(identityRefresh)
+		if err := cp.RegisterIdentity(); err != nil {
+			log.Printf("⚠️ Failed to refresh identity with the base node: %v", err)
+		}
+	}
+}
+
+// verifyExitIdentity asks the base node whether the exit registered
+// identityKey under exitPeerID with the base of region.
+func (cp *ClientPeer) verifyExitIdentity(exitPeerID, region, identityKey string) error {
+	if cp.baseClient == nil {
+		return fmt.Errorf("no base node to verify exit %s", exitPeerID)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	ack, err := cp.baseClient.VerifyPeer(ctx, &pb.PeerIdentity{PeerId: exitPeerID, Region: region, PublicKey: identityKey})
+	if err != nil {
+		return fmt.Errorf("could not verify exit %s with the base: %w", exitPeerID, err)
+	}
+	if !ack.Received {
+		return fmt.Errorf("exit %s is not a registered peer: %s", exitPeerID, ack.Message)
+	}
+	return nil
+}
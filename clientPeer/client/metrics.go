@@ -0,0 +1,90 @@
+package client
+
+import (
+	"Client_peer/utils"
+	"sync"
+	"time"
+)
+
+// sessionMetrics measures what a peer reports in its session heartbeats:
+// the round trip of its last heartbeat to the super, the traffic through
+// its WireGuard interfaces since the previous heartbeat and how long it has
+// been registered with its current super. Packet loss is not measured.
+type sessionMetrics struct {
+	mu           sync.Mutex
+	rtt          time.Duration
+	registeredAt time.Time
+	lastBytes    map[string]uint64
+	lastSample   time.Time
+}
+
+func newSessionMetrics() *sessionMetrics {
+	return &sessionMetrics{lastBytes: make(map[string]uint64)}
+}
+
+// registered restarts the uptime from now, as the peer joined a new super.
+func (m *sessionMetrics) registered(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.registeredAt = now
+}
+
+// roundTrip records how long the last heartbeat took to be answered.
+func (m *sessionMetrics) roundTrip(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rtt = d
+}
+
+// latencyMs is the last heartbeat round trip, zero before the first one.
+func (m *sessionMetrics) latencyMs() int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.rtt > 0 && m.rtt < time.Millisecond {
+		return 1
+	}
+	return int32(m.rtt.Milliseconds())
+}
+
+// uptimeSecs is how long the peer has been registered with its super.
+func (m *sessionMetrics) uptimeSecs(now time.Time) int32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.registeredAt.IsZero() {
+		return 0
+	}
+	return int32(now.Sub(m.registeredAt).Seconds())
+}
+
+// throughputMbps reads the transfer counters of ifaces and returns the rate
+// since the previous call, zero on the first. Interfaces that are not up
+// are skipped.
+func (m *sessionMetrics) throughputMbps(now time.Time, ifaces ...string) float32 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var delta uint64
+	for _, iface := range ifaces {
+		if iface == "" {
+			continue
+		}
+		bytes, err := utils.TransferBytes(iface)
+		if err != nil {
+			delete(m.lastBytes, iface)
+			continue
+		}
+		// Counters restart when the interface or its peers are replaced
+		if prev, ok := m.lastBytes[iface]; ok && bytes >= prev {
+			delta += bytes - prev
+		}
+		m.lastBytes[iface] = bytes
+	}
+
+	elapsed := now.Sub(m.lastSample)
+	first := m.lastSample.IsZero()
+	m.lastSample = now
+	if first || elapsed <= 0 {
+		return 0
+	}
+	return float32(float64(delta*8) / elapsed.Seconds() / 1e6)
+}
@@ -6,6 +6,7 @@ import (
 	"Client_peer/utils"
 	"context"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 	"net"
@@ -17,31 +18,138 @@ import (
 	"google.golang.org/grpc"
 )
 
+// exitRequest is what the peer last asked its super for, kept so the
+// session can be requested again from another super.
+type exitRequest struct {
+	region     string
+	minBW      float32
+	maxLatency float32
+}
+
 type ClientPeer struct {
 	client          pb.SuperNodeServiceClient
+	conn            *grpc.ClientConn
+	superID         string
 	id              string
 	region          string
 	originalDNS     string
 	originalGateway string
 	ifaceName       string
+	grpcPort        string
+	tunnelIface     string
+	tunnelPort      int
+	tunnelAddress   string
+	dns             string
+	exitSubnet      *net.IPNet
+	exitIface       string
+	exitAd          *pb.ExitAdvertisement
+	sessionID       string
+	exitPeerID      string
+	exitReq         *exitRequest
+	baseClient      pb.BaseNodeServiceClient
+	metrics         *sessionMetrics
 	mu              sync.Mutex
 }
 
 func NewClientPeer(conn *grpc.ClientConn, id string, region string) *ClientPeer {
 	return &ClientPeer{
-		client: pb.NewSuperNodeServiceClient(conn),
-		id:     id,
-		region: region,
+		client:        pb.NewSuperNodeServiceClient(conn),
+		conn:          conn,
+		id:            id,
+		region:        region,
+		tunnelIface:   "wg-client",
+		tunnelPort:    51821,
+		tunnelAddress: "10.101.0.2/32",
+		dns:           "1.1.1.1",
+		metrics:       newSessionMetrics(),
+	}
+}
+
+// SetExitPort sets the port this peer's exit server listens on, advertised
+// to the super node at registration. Peers that are not exits leave it
+// empty.
+func (cp *ClientPeer) SetExitPort(port string) {
+	cp.grpcPort = port
+}
+
+// SetExitAdvertisement makes the peer offer itself as an exit to every super
+// node it registers with.
+func (cp *ClientPeer) SetExitAdvertisement(ad *pb.ExitAdvertisement) {
+	ad.PeerId = cp.id
+	cp.exitAd = ad
+}
+
+// SetTunnel sets the WireGuard interface, listen port, fallback address and
+// fallback DNS server used when this peer opens a tunnel to an exit. They
+// must not clash with the exit interface when the peer is both.
+func (cp *ClientPeer) SetTunnel(iface string, listenPort int, address string, dns string) {
+	cp.tunnelIface = iface
+	cp.tunnelPort = listenPort
+	cp.tunnelAddress = address
+	cp.dns = dns
+}
+
+// SetExitInterface names this peer's own exit interface, whose traffic is
+// reported in session heartbeats.
+func (cp *ClientPeer) SetExitInterface(iface string) {
+	cp.exitIface = iface
+}
+
+// SetExitSubnet records the address space of this peer's own exit
+// interface. An exit that assigns the client an address inside it is
+// refused, since the two interfaces would route the same subnet.
+func (cp *ClientPeer) SetExitSubnet(address string) {
+	if _, subnet, err := net.ParseCIDR(address); err == nil {
+		cp.exitSubnet = subnet
 	}
 }
 
+// SetSuperID records which super node conn points at, so the peer can tell
+// when it disappears.
+func (cp *ClientPeer) SetSuperID(id string) {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	cp.superID = id
+}
+
+func (cp *ClientPeer) superClient() pb.SuperNodeServiceClient {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	return cp.client
+}
+
+// RedirectError is returned when a super node at capacity turns the peer
+// away and names another super to use.
+type RedirectError struct {
+	Node    *pb.SuperNode
+	Message string
+}
+
+func (e *RedirectError) Error() string {
+	return fmt.Sprintf("registration redirected to %s: %s", e.Node.NodeId, e.Message)
+}
+
+// Register registers with the current super node, following a redirect to
+// a less loaded super if the current one is full.
 func (cp *ClientPeer) Register() error {
+	err := cp.registerWith(cp.superClient())
+
+	var redirect *RedirectError
+	if !errors.As(err, &redirect) {
+		return err
+	}
+	log.Printf("↪️ %v", redirect)
+	return cp.switchSuper(map[string]*pb.SuperNode{redirect.Node.NodeId: redirect.Node})
+}
+
+func (cp *ClientPeer) registerWith(client pb.SuperNodeServiceClient) error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
 	priv, pub, _ := crypto.LoadOrCreateKeypair()
 	nonce := crypto.GenerateNonce()
-	signature := crypto.SignPeerPayload(priv, cp.id, cp.region, "Linux", "symmetric", nonce)
+	signedAt := time.Now().Unix()
+	signature := crypto.SignPeerPayload(priv, cp.id, cp.region, "Linux", "symmetric", nonce, signedAt)
 
 	req := &pb.PeerRegistrationRequest{
 		PeerId:    cp.id,
@@ -52,20 +160,56 @@ func (cp *ClientPeer) Register() error {
 		NatType:   "symmetric",
 		Signature: signature,
 		Nonce:     nonce,
+		SignedAt:  signedAt,
 		Ip:        utils.GetLocalIP(),
-		GrpcPort:  "6000",
+		GrpcPort:  cp.grpcPort,
 	}
 
-	res, err := cp.client.RegisterClientPeer(ctx, req)
+	res, err := client.RegisterClientPeer(ctx, req)
 	if err != nil {
 		return err
 	}
 
 	if !res.Success {
+		if res.Redirect != nil {
+			return &RedirectError{Node: res.Redirect, Message: res.Message}
+		}
 		return fmt.Errorf("registration failed: %s", res.Message)
 	}
 
 	log.Printf("✅ Registered to Super Node: %s | ID: %s", res.Message, res.AssignedId)
+	cp.metrics.registered(time.Now())
+
+	if cp.exitAd != nil {
+		ack, err := client.AdvertiseExit(ctx, cp.exitAd)
+		if err != nil {
+			return fmt.Errorf("exit advertisement failed: %w", err)
+		}
+		if !ack.Received {
+			return fmt.Errorf("exit advertisement rejected: %s", ack.Message)
+		}
+		log.Printf("🚪 Advertised as exit at %s:%s", cp.exitAd.EndpointIp, cp.exitAd.EndpointPort)
+	}
+	return nil
+}
+
+// WithdrawExit tells the super node this peer no longer serves as an exit.
+func (cp *ClientPeer) WithdrawExit() error {
+	if cp.exitAd == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	ack, err := cp.superClient().WithdrawExit(ctx, &pb.ExitWithdrawal{PeerId: cp.id})
+	if err != nil {
+		return err
+	}
+	if !ack.Received {
+		return fmt.Errorf("exit withdrawal rejected: %s", ack.Message)
+	}
+	log.Println("🚪 Withdrew as exit")
 	return nil
 }
 
@@ -76,31 +220,38 @@ func (cp *ClientPeer) StartHeartbeat() {
 	for range ticker.C {
 		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 
+		cp.mu.Lock()
+		exitPeerID := cp.exitPeerID
+		tunnelIface := cp.ifaceName
+		cp.mu.Unlock()
+
+		// Packet loss is not measured and left at zero
+		now := time.Now()
 		req := &pb.PeerSessionHeartbeatRequest{
 			PeerId:            cp.id,
-			ExitPeerId:        "1535748",
-			LatencyMs:         57,
-			PacketLoss:        0.2,
-			ThroughputMbps:    12.3,
-			SessionUptimeSecs: 300,
+			ExitPeerId:        exitPeerID,
+			LatencyMs:         cp.metrics.latencyMs(),
+			ThroughputMbps:    cp.metrics.throughputMbps(now, tunnelIface, cp.exitIface),
+			SessionUptimeSecs: cp.metrics.uptimeSecs(now),
 		}
 
-		res, err := cp.client.PeerSessionHeartbeat(ctx, req)
+		res, err := cp.superClient().PeerSessionHeartbeat(ctx, req)
 		cancel()
 		if err != nil {
 			log.Printf("Heartbeat failed: %v", err)
 			continue
 		}
+		cp.metrics.roundTrip(time.Since(now))
 
 		log.Printf("Heartbeat sent: %s", res.Message)
 	}
 }
 
-func (cp *ClientPeer) RequestExitEndpoint(region string, minBW float32, maxLatency float32) error {
+func (cp *ClientPeer) RequestExitEndpoint(region string, minBW float32, maxLatency float32) (err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
 
-	wgPriv, wgPub, _ := utils.LoadOrCreateWGKeypair()
+	wgPriv, wgPub, _ := utils.LoadOrCreateClientWGKeypair()
 	pubB64 := utils.PublicKeyBase64(wgPub)
 
 	// DEBUG: Log the keys to see what's being sent
@@ -115,33 +266,52 @@ func (cp *ClientPeer) RequestExitEndpoint(region string, minBW float32, maxLaten
 		MinBandwidthMbps: minBW,
 		MaxLatencyMs:     maxLatency,
 	}
+	if err := cp.signClientKey(req); err != nil {
+		return err
+	}
 
-	wgCfg, err := cp.client.RequestExit(ctx, req)
+	wgCfg, err := cp.superClient().RequestExit(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to request exit: %w", err)
 	}
+	// From here on the session is ours; give it back if the tunnel does
+	// not come up
+	defer func() {
+		if err != nil {
+			cp.releaseSession(wgCfg.SessionId)
+		}
+	}()
+
+	// Only use an exit whose identity key vouches for what we were given
+	if err := cp.verifyExitConfig(wgCfg, region, pubB64); err != nil {
+		return fmt.Errorf("exit %s failed verification: %w", wgCfg.ExitPeerId, err)
+	}
+	if ip, _, err := net.ParseCIDR(wgCfg.InterfaceAddress); err == nil && cp.exitSubnet != nil && cp.exitSubnet.Contains(ip) {
+		return fmt.Errorf("exit %s assigned %s, inside this peer's own exit subnet %s; set a distinct exit.address",
+			wgCfg.ExitPeerId, wgCfg.InterfaceAddress, cp.exitSubnet)
+	}
 
 	log.Printf("✅ Received WG config from SuperNode. Setting up interface...")
 
 	log.Println("🎯 Received WireGuard Config:")
-	log.Printf("Interface Private Key: %s", wgCfg.InterfacePrivateKey)
 	log.Printf("Interface Address:    %s", wgCfg.InterfaceAddress)
 	log.Printf("DNS:                  %s", wgCfg.Dns)
 	log.Printf("Peer Public Key:      %s", wgCfg.PeerPublicKey)
 	log.Printf("Peer Endpoint:        %s", wgCfg.PeerEndpoint)
 	log.Printf("Allowed IPs:          %s", wgCfg.AllowedIps)
 	log.Printf("Keepalive:            %d", wgCfg.Keepalive)
+	log.Printf("Session:              %s", wgCfg.SessionId)
 
 	originalDNS, originalGateway, err := utils.StoreOriginalSettings()
 	if err != nil {
 		log.Printf("Warning: Failed to store original settings: %v", err)
 	}
 
-	const ifaceName = "wg-exit"
+	ifaceName := cp.tunnelIface
 	// Use the ALLOWED_IPS as the interface address, not a generated one
 	interfaceAddress := wgCfg.InterfaceAddress
 	if interfaceAddress == "" || interfaceAddress == "0.0.0.0/0" {
-		interfaceAddress = "10.100.0.2/32" // Fallback for one-to-one setup
+		interfaceAddress = cp.tunnelAddress // Fallback for one-to-one setup
 		log.Printf("⚠️ Invalid interface address from config (%s), using fallback: %s", wgCfg.InterfaceAddress, interfaceAddress)
 	}
 	// Validate CIDR format
@@ -149,7 +319,6 @@ func (cp *ClientPeer) RequestExitEndpoint(region string, minBW float32, maxLaten
 		return fmt.Errorf("invalid interface address %s: %v", interfaceAddress, err)
 	}
 
-	// Remove any existing IPs on wg-exit
 	_ = utils.RunCmd("ip", "addr", "flush", "dev", ifaceName)
 	if err := utils.SetInterfaceAddress(ifaceName, interfaceAddress); err != nil {
 		return fmt.Errorf("failed to assign IP %s: %v", interfaceAddress, err)
@@ -228,7 +397,7 @@ func (cp *ClientPeer) RequestExitEndpoint(region string, minBW float32, maxLaten
 	log.Printf("   Allowed IPs: %s", allowedNet.String())
 	log.Printf("   Keepalive: %v", keepalive)
 
-	if err := utils.ConfigureWG(ifaceName, ifacePrivKey, 51820, []wgtypes.PeerConfig{peer}); err != nil {
+	if err := utils.ConfigureWG(ifaceName, ifacePrivKey, cp.tunnelPort, []wgtypes.PeerConfig{peer}); err != nil {
 		log.Printf("❌ Failed to configure WireGuard: %v", err)
 		return fmt.Errorf("failed to configure WireGuard interface: %v", err)
 	}
@@ -241,7 +410,7 @@ func (cp *ClientPeer) RequestExitEndpoint(region string, minBW float32, maxLaten
 	// Configure DNS
 	dnsServer := wgCfg.Dns
 	if dnsServer == "" {
-		dnsServer = "1.1.1.1" // Default fallback DNS
+		dnsServer = cp.dns // Default fallback DNS
 	}
 	if err := utils.ConfigureDNS(ifaceName, dnsServer); err != nil {
 		log.Printf("Warning: Failed to configure DNS: %v", err)
@@ -252,14 +421,78 @@ func (cp *ClientPeer) RequestExitEndpoint(region string, minBW float32, maxLaten
 		return fmt.Errorf("failed to setup default route: %v", err)
 	}
 
+	cp.mu.Lock()
 	cp.originalDNS = originalDNS
 	cp.originalGateway = originalGateway
 	cp.ifaceName = ifaceName
+	cp.sessionID = wgCfg.SessionId
+	cp.exitPeerID = wgCfg.ExitPeerId
+	cp.exitReq = &exitRequest{region: region, minBW: minBW, maxLatency: maxLatency}
+	cp.mu.Unlock()
 
 	log.Println("🎉 WireGuard tunnel is up! You should now be able to route traffic through the Exit Peer.")
 	return nil
 }
 
+// ReleaseExit ends the exit session opened by RequestExitEndpoint, so the
+// exit drops this peer.
+func (cp *ClientPeer) ReleaseExit() error {
+	cp.mu.Lock()
+	sessionID := cp.sessionID
+	cp.sessionID = ""
+	cp.exitPeerID = ""
+	cp.mu.Unlock()
+
+	if sessionID == "" {
+		return nil
+	}
+	return cp.releaseSession(sessionID)
+}
+
+// moveSession hands an open exit session over to the current super after a
+// switch. The session is released on old, which would otherwise end it and
+// remove us from the exit once our heartbeats stop reaching it, and
+// requested again through the current super.
+func (cp *ClientPeer) moveSession(old pb.SuperNodeServiceClient) error {
+	cp.mu.Lock()
+	sessionID := cp.sessionID
+	req := cp.exitReq
+	cp.sessionID = ""
+	cp.exitPeerID = ""
+	cp.mu.Unlock()
+
+	if sessionID == "" || req == nil {
+		return nil
+	}
+
+	// The old super may already be gone, in which case it ends the session
+	// itself
+	if err := cp.releaseSessionOn(old, sessionID); err != nil {
+		log.Printf("⚠️ Could not release exit session %s on the old Super Node: %v", sessionID, err)
+	}
+	log.Printf("📨 Requesting exit to region %s from the new Super Node...", req.region)
+	return cp.RequestExitEndpoint(req.region, req.minBW, req.maxLatency)
+}
+
+func (cp *ClientPeer) releaseSession(sessionID string) error {
+	return cp.releaseSessionOn(cp.superClient(), sessionID)
+}
+
+func (cp *ClientPeer) releaseSessionOn(client pb.SuperNodeServiceClient, sessionID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	ack, err := client.ReleaseExit(ctx, &pb.ReleaseExitRequest{PeerId: cp.id, SessionId: sessionID})
+	if err != nil {
+		return err
+	}
+	if !ack.Received {
+		return fmt.Errorf("exit release rejected: %s", ack.Message)
+	}
+	log.Printf("🔚 Released exit session %s: %s", sessionID, ack.Message)
+	return nil
+}
+
 func (cp *ClientPeer) Cleanup() {
 	cp.mu.Lock()
 	defer cp.mu.Unlock()
@@ -0,0 +1,137 @@
+package client
+
+import (
+	"Client_peer/pb"
+	"Client_peer/utils"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+const (
+	watchRetryMin = time.Second
+	watchRetryMax = 30 * time.Second
+)
+
+// FollowSuperNodes watches the base node's super node stream and moves this
+// peer to another live super when the current one goes stale or is removed.
+// It runs until the process exits, reconnecting the stream with backoff.
+func (cp *ClientPeer) FollowSuperNodes(base pb.BaseNodeServiceClient) {
+	retry := watchRetryMin
+	for {
+		connected, err := cp.watchOnce(base)
+		if connected {
+			// Back off from scratch after a stream that worked
+			retry = watchRetryMin
+		}
+		log.Printf("👀 Super Node watch ended: %v (retrying in %s)", err, retry)
+
+		time.Sleep(retry)
+		retry *= 2
+		if retry > watchRetryMax {
+			retry = watchRetryMax
+		}
+	}
+}
+
+// watchOnce follows one stream until it fails. connected reports whether
+// the stream got as far as its snapshot. Only supers of the peer's own
+// region are considered: a peer the base placed in another region is
+// served by supers this base does not track, so it is left where it is.
+func (cp *ClientPeer) watchOnce(base pb.BaseNodeServiceClient) (connected bool, err error) {
+	stream, err := base.WatchSuperNodes(context.Background(), &emptypb.Empty{})
+	if err != nil {
+		return false, err
+	}
+
+	alive := make(map[string]*pb.SuperNode)
+	// covered is set once the stream has shown a super of our region
+	covered := false
+	for {
+		ev, err := stream.Recv()
+		if err != nil {
+			return connected, err
+		}
+
+		switch ev.Type {
+		case pb.SuperNodeEvent_SNAPSHOT:
+			connected = true
+			alive = make(map[string]*pb.SuperNode)
+			covered = false
+			for _, n := range ev.Snapshot {
+				if n.Region != cp.region {
+					continue
+				}
+				covered = true
+				if n.IsAlive {
+					alive[n.NodeId] = n
+				}
+			}
+			log.Printf("👀 Watching %d live Super Nodes in region %s", len(alive), cp.region)
+		case pb.SuperNodeEvent_ADDED, pb.SuperNodeEvent_UPDATED:
+			if ev.Node.Region == cp.region {
+				covered = true
+				alive[ev.Node.NodeId] = ev.Node
+			}
+			continue
+		case pb.SuperNodeEvent_STALE, pb.SuperNodeEvent_REMOVED:
+			if ev.Node.Region != cp.region {
+				continue
+			}
+			covered = true
+			delete(alive, ev.Node.NodeId)
+			log.Printf("👀 Super Node %s is %s", ev.Node.NodeId, ev.Type)
+		}
+
+		cp.mu.Lock()
+		current := cp.superID
+		cp.mu.Unlock()
+
+		if _, ok := alive[current]; ok || current == "" || !covered {
+			continue
+		}
+		if err := cp.switchSuper(alive); err != nil {
+			log.Printf("⚠️ Could not move off Super Node %s: %v", current, err)
+		}
+	}
+}
+
+// switchSuper registers with the first reachable candidate and makes it the
+// peer's super node. An open exit session is moved along with the peer.
+func (cp *ClientPeer) switchSuper(candidates map[string]*pb.SuperNode) error {
+	for _, n := range candidates {
+		addr := fmt.Sprintf("%s:%s", n.Ip, n.Port)
+		conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleSuper))
+		if err != nil {
+			continue
+		}
+
+		client := pb.NewSuperNodeServiceClient(conn)
+		if err := cp.registerWith(client); err != nil {
+			log.Printf("⚠️ Registration with Super Node %s failed: %v", n.NodeId, err)
+			conn.Close()
+			continue
+		}
+
+		cp.mu.Lock()
+		old, oldClient := cp.conn, cp.client
+		cp.client = client
+		cp.conn = conn
+		cp.superID = n.NodeId
+		cp.mu.Unlock()
+		log.Printf("🔁 Switched to Super Node %s at %s", n.NodeId, addr)
+
+		if err := cp.moveSession(oldClient); err != nil {
+			log.Printf("⚠️ Could not move exit session to Super Node %s: %v", n.NodeId, err)
+		}
+		if old != nil {
+			old.Close()
+		}
+		return nil
+	}
+	return fmt.Errorf("no live Super Node accepted us")
+}
@@ -0,0 +1,101 @@
+package client
+
+import (
+	"Client_peer/pb"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// fakeSuper records the session calls a client peer makes.
+type fakeSuper struct {
+	pb.UnimplementedSuperNodeServiceServer
+	mu       sync.Mutex
+	released []string
+	requests []*pb.ExitRequest
+}
+
+func (f *fakeSuper) RegisterClientPeer(ctx context.Context, req *pb.PeerRegistrationRequest) (*pb.RegisterResponse, error) {
+	return &pb.RegisterResponse{Success: true, AssignedId: req.PeerId}, nil
+}
+
+func (f *fakeSuper) ReleaseExit(ctx context.Context, req *pb.ReleaseExitRequest) (*pb.Ack, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.released = append(f.released, req.SessionId)
+	return &pb.Ack{Received: true}, nil
+}
+
+func (f *fakeSuper) RequestExit(ctx context.Context, req *pb.ExitRequest) (*pb.WireguardConfig, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, req)
+	return nil, fmt.Errorf("no exit in this test")
+}
+
+// startFakeSuper serves f on a local port and returns it as a watch
+// candidate.
+func startFakeSuper(t *testing.T, id string, f *fakeSuper) *pb.SuperNode {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	pb.RegisterSuperNodeServiceServer(srv, f)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	host, port, _ := net.SplitHostPort(lis.Addr().String())
+	return &pb.SuperNode{NodeId: id, Region: "IN", Ip: host, Port: port, IsAlive: true}
+}
+
+func TestSwitchSuperMovesActiveSession(t *testing.T) {
+	// Identity and WireGuard keys are created in the working directory
+	wd, _ := os.Getwd()
+	if err := os.Chdir(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	oldSuper, newSuper := &fakeSuper{}, &fakeSuper{}
+	oldNode := startFakeSuper(t, "super-old", oldSuper)
+	newNode := startFakeSuper(t, "super-new", newSuper)
+
+	conn, err := grpc.NewClient(net.JoinHostPort(oldNode.Ip, oldNode.Port),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp := NewClientPeer(conn, "peer-1", "IN")
+	cp.SetSuperID(oldNode.NodeId)
+	cp.sessionID = "sess-1"
+	cp.exitPeerID = "exit-1"
+	cp.exitReq = &exitRequest{region: "US", minBW: 10, maxLatency: 200}
+
+	if err := cp.switchSuper(map[string]*pb.SuperNode{newNode.NodeId: newNode}); err != nil {
+		t.Fatalf("switch failed: %v", err)
+	}
+
+	if cp.superID != newNode.NodeId {
+		t.Fatalf("expected to be on %s, got %s", newNode.NodeId, cp.superID)
+	}
+	if len(oldSuper.released) != 1 || oldSuper.released[0] != "sess-1" {
+		t.Fatalf("expected the old super to release sess-1, got %v", oldSuper.released)
+	}
+	if len(newSuper.requests) != 1 {
+		t.Fatalf("expected the session to be requested again from the new super, got %d requests", len(newSuper.requests))
+	}
+	if req := newSuper.requests[0]; req.RequestedRegion != "US" || req.MinBandwidthMbps != 10 || req.MaxLatencyMs != 200 {
+		t.Fatalf("expected the original exit request, got %+v", req)
+	}
+	if cp.sessionID != "" {
+		t.Fatalf("expected no session recorded after the new request failed, got %s", cp.sessionID)
+	}
+}
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+)
+
+// envPrefix starts every environment override, e.g. DVPN_CLIENT_REGION.
+const envPrefix = "DVPN_CLIENT"
+
+// Roles a peer can run: using a remote exit, serving as an exit, or both.
+const (
+	RoleClient = "client"
+	RoleExit   = "exit"
+	RoleBoth   = "both"
+)
+
+// Config holds every setting of a client peer, including the exit peer it
+// runs alongside.
+type Config struct {
+	Role   string       `yaml:"role"`
+	Region string       `yaml:"region"`
+	Base   BaseConfig   `yaml:"base"`
+	Exit   ExitConfig   `yaml:"exit"`
+	Client ClientConfig `yaml:"client"`
+	TLS    TLSConfig    `yaml:"tls"`
+}
+
+// BaseConfig locates the base node. Port wins when set; otherwise the port is
+// looked up by region in RegionPorts, falling back to DefaultPort.
+type BaseConfig struct {
+	IP          string         `yaml:"ip"`
+	Port        int            `yaml:"port"`
+	RegionPorts map[string]int `yaml:"region_ports"`
+	DefaultPort int            `yaml:"default_port"`
+}
+
+// ExitConfig is the exit peer side: its gRPC port and the WireGuard
+// interface clients are attached to. Client addresses are handed out from
+// Address's subnet. The rest is advertised to the super node: MaxSessions of
+// zero means no limit, BandwidthMbps of zero means unknown and an empty
+// AllowedRegions accepts clients from every region. The exit admits clients
+// only while their granted bandwidth fits in BandwidthMbps.
+type ExitConfig struct {
+	GrpcPort       string  `yaml:"grpc_port"`
+	Interface      string  `yaml:"interface"`
+	ListenPort     int     `yaml:"listen_port"`
+	Address        string  `yaml:"address"`
+	MaxSessions    int     `yaml:"max_sessions"`
+	BandwidthMbps  float32 `yaml:"bandwidth_mbps"`
+	AllowedRegions List    `yaml:"allowed_regions"`
+}
+
+// ClientConfig is the tunnel this peer opens when it requests an exit.
+// Address is used when the exit does not assign one.
+type ClientConfig struct {
+	RequestRegion    string  `yaml:"request_region"`
+	MinBandwidthMbps float32 `yaml:"min_bandwidth_mbps"`
+	MaxLatencyMs     float32 `yaml:"max_latency_ms"`
+	Interface        string  `yaml:"interface"`
+	ListenPort       int     `yaml:"listen_port"`
+	Address          string  `yaml:"address"`
+	DNS              string  `yaml:"dns"`
+}
+
+type TLSConfig struct {
+	CA       string `yaml:"ca"`
+	Cert     string `yaml:"cert"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Role: RoleBoth,
+		Base: BaseConfig{
+			IP:          "127.0.0.1",
+			RegionPorts: map[string]int{"IN": 50051, "US": 50053},
+			DefaultPort: 50051,
+		},
+		Exit: ExitConfig{
+			GrpcPort:   "6000",
+			Interface:  "wg-exit",
+			ListenPort: 51820,
+			Address:    "10.100.0.1/24",
+		},
+		Client: ClientConfig{
+			MinBandwidthMbps: 10,
+			MaxLatencyMs:     100,
+			Interface:        "wg-client",
+			ListenPort:       51821,
+			Address:          "10.101.0.2/32",
+			DNS:              "1.1.1.1",
+		},
+		TLS: TLSConfig{
+			CA:   ".keys/ca.crt",
+			Cert: ".keys/node.crt",
+		},
+	}
+}
+
+// Validate reports the first setting that cannot work.
+func (c *Config) Validate() error {
+	switch c.Role {
+	case RoleClient, RoleExit, RoleBoth:
+	default:
+		return fmt.Errorf("role must be %s, %s or %s, got %q", RoleClient, RoleExit, RoleBoth, c.Role)
+	}
+	if c.Role == RoleExit && c.Client.RequestRegion != "" {
+		return fmt.Errorf("client.request_region needs role %s or %s", RoleClient, RoleBoth)
+	}
+	if c.Role == RoleBoth {
+		if c.Client.Interface == c.Exit.Interface {
+			return fmt.Errorf("role both needs different client and exit interfaces, both are %q", c.Client.Interface)
+		}
+		if c.Client.ListenPort == c.Exit.ListenPort {
+			return fmt.Errorf("role both needs different client and exit listen ports, both are %d", c.Client.ListenPort)
+		}
+		if overlaps(c.Client.Address, c.Exit.Address) {
+			return fmt.Errorf("role both needs separate address spaces, %s is inside %s", c.Client.Address, c.Exit.Address)
+		}
+	}
+	if c.Base.IP == "" {
+		return fmt.Errorf("base.ip is required")
+	}
+	if err := validPort(strconv.Itoa(c.Base.BasePort(c.Region))); err != nil {
+		return fmt.Errorf("base: %w", err)
+	}
+	if err := validPort(c.Exit.GrpcPort); err != nil {
+		return fmt.Errorf("exit.grpc_port: %w", err)
+	}
+	if c.Exit.Interface == "" || c.Client.Interface == "" {
+		return fmt.Errorf("exit.interface and client.interface are required")
+	}
+	if err := validPort(strconv.Itoa(c.Exit.ListenPort)); err != nil {
+		return fmt.Errorf("exit.listen_port: %w", err)
+	}
+	if err := validPort(strconv.Itoa(c.Client.ListenPort)); err != nil {
+		return fmt.Errorf("client.listen_port: %w", err)
+	}
+	if ip, _, err := net.ParseCIDR(c.Exit.Address); err != nil || ip.To4() == nil {
+		return fmt.Errorf("exit.address: %q is not an IPv4 CIDR", c.Exit.Address)
+	}
+	if _, _, err := net.ParseCIDR(c.Client.Address); err != nil {
+		return fmt.Errorf("client.address: %q is not a CIDR", c.Client.Address)
+	}
+	if c.Exit.MaxSessions < 0 || c.Exit.BandwidthMbps < 0 {
+		return fmt.Errorf("exit: max_sessions and bandwidth_mbps cannot be negative")
+	}
+	if c.Client.MinBandwidthMbps < 0 || c.Client.MaxLatencyMs <= 0 {
+		return fmt.Errorf("client: need min_bandwidth_mbps >= 0 and max_latency_ms > 0")
+	}
+	if !c.TLS.Insecure && (c.TLS.CA == "" || c.TLS.Cert == "") {
+		return fmt.Errorf("tls: ca and cert are required unless insecure is set")
+	}
+	return nil
+}
+
+// RunsExit reports whether the role serves as an exit.
+func (c *Config) RunsExit() bool {
+	return c.Role == RoleExit || c.Role == RoleBoth
+}
+
+// RunsClient reports whether the role may request a remote exit.
+func (c *Config) RunsClient() bool {
+	return c.Role == RoleClient || c.Role == RoleBoth
+}
+
+// overlaps reports whether two CIDRs share addresses. Invalid input never
+// overlaps; it is reported by the address checks instead.
+func overlaps(a, b string) bool {
+	_, na, errA := net.ParseCIDR(a)
+	_, nb, errB := net.ParseCIDR(b)
+	if errA != nil || errB != nil {
+		return false
+	}
+	return na.Contains(nb.IP) || nb.Contains(na.IP)
+}
+
+// BasePort returns the base node port to dial for region.
+func (b BaseConfig) BasePort(region string) int {
+	if b.Port != 0 {
+		return b.Port
+	}
+	if p := b.RegionPorts[region]; p != 0 {
+		return p
+	}
+	return b.DefaultPort
+}
+
+func validPort(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q is not a valid port", s)
+	}
+	return nil
+}
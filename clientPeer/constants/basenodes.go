@@ -1,8 +0,0 @@
-package constants
-
-// Base Node IPs for local deployment
-// Format: "IP:PORT"
-var BaseNodeIPs = []string{
-	"192.168.1.104:50051",   // India Base Node
-	"192.168.1.43:50053",    // US Base Node
-}
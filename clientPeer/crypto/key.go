@@ -3,7 +3,9 @@ package crypto
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -53,8 +55,32 @@ func GenerateNonce() string {
 	}
 	return base64.StdEncoding.EncodeToString(b)
 }
-func SignPeerPayload(priv ed25519.PrivateKey, id, region, os, nat, nonce string) string {
-	msg := fmt.Sprintf("%s|%s|%s|%s|%s", id, region, os, nat, nonce)
+
+// SignPeerPayload signs id|region|os|nat|nonce|signedAt for registration with
+// a super node, which rejects stale timestamps and reused nonces.
+func SignPeerPayload(priv ed25519.PrivateKey, id, region, os, nat, nonce string, signedAt int64) string {
+	msg := fmt.Sprintf("%s|%s|%s|%s|%s|%d", id, region, os, nat, nonce, signedAt)
 	sign := ed25519.Sign(priv, []byte(msg))
 	return base64.StdEncoding.EncodeToString(sign)
 }
+
+// DerivePeerID returns the client peer ID for pub in region. Super nodes only
+// accept an ID that matches the registering key.
+func DerivePeerID(region string, pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return fmt.Sprintf("peer-%s-%s", region, hex.EncodeToString(sum[:10]))
+}
+
+// DeriveSuperNodeID returns the super node ID for pub in region, the same way
+// super nodes derive their own.
+func DeriveSuperNodeID(region string, pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return fmt.Sprintf("super-%s-%s", region, hex.EncodeToString(sum[:10]))
+}
+
+// SignPeerIdentity signs peer-identity|id|region|nonce|signedAt for
+// registering the identity key with the base node of the region.
+func SignPeerIdentity(priv ed25519.PrivateKey, id, region, nonce string, signedAt int64) string {
+	msg := fmt.Sprintf("peer-identity|%s|%s|%s|%d", id, region, nonce, signedAt)
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(msg)))
+}
@@ -0,0 +1,32 @@
+package crypto
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// ExitTicketPayload is the message a super node signs when it issues an exit
+// ticket. Exit peers rebuild it to check the signature.
+func ExitTicketPayload(sessionID, clientID, clientPublicKey, exitPeerID string, expiresAt int64, bandwidthMbps float32, issuerID, issuerRegion string) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%d|%g|%s|%s", sessionID, clientID, clientPublicKey, exitPeerID, expiresAt, bandwidthMbps, issuerID, issuerRegion)
+}
+
+// SignExitTicket signs an exit ticket payload.
+func SignExitTicket(priv ed25519.PrivateKey, payload string) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(payload)))
+}
+
+// VerifyExitTicket checks a base64 signature over payload by the base64
+// ed25519 key pubKeyBase64.
+func VerifyExitTicket(pubKeyBase64, payload, signatureBase64 string) bool {
+	pub, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signatureBase64)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(pub), []byte(payload), sig)
+}
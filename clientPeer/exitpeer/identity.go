@@ -0,0 +1,87 @@
+package exitpeer
+
+import (
+	"Client_peer/crypto"
+	"Client_peer/pb"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"time"
+)
+
+// maxKeySignatureAge bounds how old a client's key signature may be, and
+// how far its clock may run ahead of ours.
+const maxKeySignatureAge = 5 * time.Minute
+
+// SetIdentityKey sets the identity key the exit signs its WireGuard key and
+// endpoint with. It must be the key behind the exit's peer ID.
+func (e *ExitPeerServer) SetIdentityKey(priv ed25519.PrivateKey) {
+	e.identity = priv
+}
+
+// verifyClientKey checks that the client's WireGuard key in req is signed by
+// the identity key the requester registered with the base of its region.
+func (e *ExitPeerServer) verifyClientKey(ctx context.Context, req *pb.ExitPeerInfoRequest) error {
+	if req.ClientKeySignature == "" {
+		return fmt.Errorf("client key is not signed")
+	}
+	pub, err := base64.StdEncoding.DecodeString(req.ClientIdentityKey)
+	if err != nil || crypto.DerivePeerID(req.RequesterRegion, pub) != req.RequesterId {
+		return fmt.Errorf("requester %s does not match its key", req.RequesterId)
+	}
+
+	age := time.Since(time.Unix(req.ClientKeySignedAt, 0))
+	if age > maxKeySignatureAge || age < -maxKeySignatureAge {
+		return fmt.Errorf("client key signature is stale")
+	}
+
+	payload := crypto.ClientKeyPayload(req.RequesterId, req.ClientPublicKey, req.Region, req.ClientKeySignedAt)
+	if !crypto.VerifyKeyPayload(req.ClientIdentityKey, payload, req.ClientKeySignature) {
+		return fmt.Errorf("invalid client key signature")
+	}
+
+	// A self-derived ID proves nothing on its own; the base knows which keys
+	// peers registered
+	return e.verifyRequester(ctx, req.RequesterId, req.RequesterRegion, req.ClientIdentityKey)
+}
+
+func (e *ExitPeerServer) verifyRequester(ctx context.Context, id, region, pubKey string) error {
+	key := id + "|" + pubKey
+	e.sessionsMu.Lock()
+	known := time.Now().Before(e.requesters[key])
+	e.sessionsMu.Unlock()
+	if known {
+		return nil
+	}
+
+	if e.baseClient == nil {
+		return fmt.Errorf("no base node to verify requester %s", id)
+	}
+	ack, err := e.baseClient.VerifyPeer(ctx, &pb.PeerIdentity{PeerId: id, Region: region, PublicKey: pubKey})
+	if err != nil {
+		return fmt.Errorf("could not verify requester %s with the base: %w", id, err)
+	}
+	if !ack.Received {
+		return fmt.Errorf("requester %s is not a registered peer: %s", id, ack.Message)
+	}
+
+	e.sessionsMu.Lock()
+	e.requesters[key] = time.Now().Add(issuerVerifyTTL)
+	e.sessionsMu.Unlock()
+	return nil
+}
+
+// signAnswer signs our WireGuard key and endpoint in resp for the session
+// and client key of req.
+func (e *ExitPeerServer) signAnswer(resp *pb.ExitPeerInfoResponse, req *pb.ExitPeerInfoRequest) error {
+	if e.identity == nil {
+		return fmt.Errorf("exit identity key not set")
+	}
+
+	resp.ExitPeerId = e.peerID
+	resp.ExitIdentityKey = base64.StdEncoding.EncodeToString(e.identity.Public().(ed25519.PublicKey))
+	resp.Signature = crypto.SignKeyPayload(e.identity, crypto.ExitKeyPayload(
+		resp.ExitPeerId, resp.PublicKey, resp.EndpointIp, resp.EndpointPort, resp.ClientIp, req.SessionId, req.ClientPublicKey))
+	return nil
+}
@@ -4,32 +4,66 @@ import (
 	"Client_peer/pb"
 	"Client_peer/utils"
 	"context"
+	"crypto/ed25519"
 	"encoding/base64"
+	"encoding/binary"
 	"fmt"
 	"log"
 	"net"
 	"sync"
+	"time"
 
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
-const (
-	ifaceName  = "wg-exit"
-	listenPort = 51820
-	exitPeerIP = "10.100.0.1/24"
-	clientCIDR = "10.100.0.%d/32"
-)
-
 type ExitPeerServer struct {
 	pb.UnimplementedExitPeerServiceServer
-	privKey   wgtypes.Key
-	pubKey    wgtypes.Key
-	ipAlloc   int
-	ipAllocMu sync.Mutex
-	allocMap  map[string]string
+	privKey    wgtypes.Key
+	pubKey     wgtypes.Key
+	ifaceName  string
+	listenPort int
+	subnet     *net.IPNet
+	ipAlloc    int
+	ipAllocMu  sync.Mutex
+	allocMap   map[string]string
+	sessionsMu sync.Mutex
+	sessions   map[string]*exitSession
+	peerID     string
+	baseClient pb.BaseNodeServiceClient
+	issuers    map[string]time.Time
+	requesters map[string]time.Time
+	identity   ed25519.PrivateKey
+	// bandwidthMbps is the exit's advertised capacity; zero means unknown.
+	// It is guarded by sessionsMu.
+	bandwidthMbps float32
 }
 
-func NewExitPeerServer() *ExitPeerServer {
+// exitSession is a client served under a session ID handed out by a super.
+// The client's key is only on the interface while it holds an unexpired
+// ticket, and traffic to it is shaped to the bandwidth its ticket grants.
+// Only tickets issued by superID admit the client, and only the super that
+// reserved the session (by its certificate key, when on mutual TLS) may
+// pass them on or end it.
+type exitSession struct {
+	requesterID   string
+	superID       string
+	reserver      string
+	clientKey     wgtypes.Key
+	clientIP      string
+	admitted      bool
+	expires       time.Time
+	bandwidthMbps float32
+}
+
+// NewExitPeerServer brings up the exit WireGuard interface ifaceName on
+// listenPort with address (a CIDR such as 10.100.0.1/24). Clients are given
+// /32 addresses from the same subnet.
+func NewExitPeerServer(ifaceName string, listenPort int, address string) *ExitPeerServer {
+	_, subnet, err := net.ParseCIDR(address)
+	if err != nil {
+		log.Fatalf("❌ Invalid exit peer address %s: %v", address, err)
+	}
+
 	priv, pub, err := utils.LoadOrCreateWGKeypair()
 	if err != nil {
 		log.Fatalf("❌ Failed to generate exit peer key: %v", err)
@@ -43,7 +77,7 @@ func NewExitPeerServer() *ExitPeerServer {
 		log.Fatalf("❌ Failed to ensure Exit WG interface: %v", err)
 	}
 
-	if err := utils.SetInterfaceAddress(ifaceName, exitPeerIP); err != nil {
+	if err := utils.SetInterfaceAddress(ifaceName, address); err != nil {
 		log.Fatalf("❌ Failed to set Exit peer IP: %v", err)
 	}
 
@@ -70,40 +104,79 @@ func NewExitPeerServer() *ExitPeerServer {
 		log.Fatalf("❌ Failed to setup forward rules: %v", err)
 	}
 
+	if err := utils.SetupShaping(ifaceName); err != nil {
+		log.Fatalf("❌ Failed to setup traffic shaping: %v", err)
+	}
+
 	log.Printf("🚀 Exit Peer ready — PublicKey: %s | Listening on %d | LAN NAT via %s",
 		pub.String(), listenPort, publicIface)
 
 	return &ExitPeerServer{
-		privKey:  priv,
-		pubKey:   pub,
-		ipAlloc:  2,
-		allocMap: make(map[string]string),
+		privKey:    priv,
+		pubKey:     pub,
+		ifaceName:  ifaceName,
+		listenPort: listenPort,
+		subnet:     subnet,
+		ipAlloc:    2,
+		allocMap:   make(map[string]string),
+		sessions:   make(map[string]*exitSession),
+		issuers:    make(map[string]time.Time),
+		requesters: make(map[string]time.Time),
 	}
 }
 
-func (e *ExitPeerServer) allocateIPForPeer(peerID string) string {
+// SetBandwidth sets the capacity the exit advertises. Tickets are only
+// admitted while the bandwidth they grant fits in it.
+func (e *ExitPeerServer) SetBandwidth(mbps float32) {
+	e.sessionsMu.Lock()
+	defer e.sessionsMu.Unlock()
+	e.bandwidthMbps = mbps
+}
+
+// PublicKey returns the exit's WireGuard public key.
+func (e *ExitPeerServer) PublicKey() string {
+	return e.pubKey.String()
+}
+
+func (e *ExitPeerServer) allocateIPForPeer(peerID string) (string, error) {
 	e.ipAllocMu.Lock()
 	defer e.ipAllocMu.Unlock()
 
 	// If already allocated, return existing
 	if ip, ok := e.allocMap[peerID]; ok {
-		return ip
+		return ip, nil
 	}
 
-	ip := fmt.Sprintf(clientCIDR, e.ipAlloc)
+	base := e.subnet.IP.To4()
+	ones, bits := e.subnet.Mask.Size()
+	if base == nil || e.ipAlloc >= 1<<(bits-ones)-1 {
+		return "", fmt.Errorf("exit subnet %s has no free addresses", e.subnet)
+	}
+
+	n := binary.BigEndian.Uint32(base) + uint32(e.ipAlloc)
+	addr := make(net.IP, 4)
+	binary.BigEndian.PutUint32(addr, n)
+
+	ip := addr.String() + "/32"
 	e.ipAlloc++
 	e.allocMap[peerID] = ip
 
-	return ip
+	return ip, nil
 }
 
 func (e *ExitPeerServer) GetWireGuardInfo(ctx context.Context, req *pb.ExitPeerInfoRequest) (*pb.ExitPeerInfoResponse, error) {
 	log.Printf("📡 Exit peer received request from %s", req.RequesterId)
 
-	// Must receive client public key
+	// Must receive client public key and the session it is for
+	if req.SessionId == "" {
+		return nil, fmt.Errorf("session ID missing in request")
+	}
 	if req.ClientPublicKey == "" {
 		return nil, fmt.Errorf("client public key missing in request")
 	}
+	if req.SuperId == "" {
+		return nil, fmt.Errorf("super ID missing in request")
+	}
 
 	// Proper base64 decoding
 	clientKeyBytes, err := base64.StdEncoding.DecodeString(req.ClientPublicKey)
@@ -123,39 +196,91 @@ func (e *ExitPeerServer) GetWireGuardInfo(ctx context.Context, req *pb.ExitPeerI
 
 	log.Printf("✅ Client public key parsed successfully: %s", clientPubKey.String())
 
-	clientIP := e.allocateIPForPeer(req.RequesterId)
-
-	// Allow all traffic (0.0.0.0/0) through the tunnel for VPN functionality
-	_, allowAllNet, _ := net.ParseCIDR("0.0.0.0/0")
-
-	// DEBUG: Log the peer configuration
-	log.Printf("🔧 SERVER Adding Peer:")
-	log.Printf("   Client Public Key: %s", clientPubKey.String())
-	log.Printf("   Client IP: %s", clientIP)
-	log.Printf("   Allowed IPs: %s (routing all traffic)", allowAllNet.String())
-
-	peerCfg := wgtypes.PeerConfig{
-		PublicKey:  clientPubKey,
-		AllowedIPs: []net.IPNet{*allowAllNet},
+	if err := e.verifyClientKey(ctx, req); err != nil {
+		log.Printf("❌ Rejected client key of %s: %v", req.RequesterId, err)
+		return nil, fmt.Errorf("client key not verified: %v", err)
 	}
 
-	if err := utils.ConfigureWG(ifaceName, e.privKey, listenPort, []wgtypes.PeerConfig{peerCfg}); err != nil {
-		log.Printf("❌ Failed to configure WireGuard on server: %v", err)
-		return nil, fmt.Errorf("failed to add peer to WG interface: %v", err)
+	// The peer is added once the client's super sends a ticket for the
+	// session. A session ID is never reused: that would leave the peer of
+	// the first session on the interface.
+	e.sessionsMu.Lock()
+	if _, ok := e.sessions[req.SessionId]; ok {
+		e.sessionsMu.Unlock()
+		log.Printf("❌ Refused session %s for %s: already reserved", req.SessionId, req.RequesterId)
+		return nil, fmt.Errorf("session %s is already reserved", req.SessionId)
 	}
-
-	// Verify server configuration
-	if err := utils.DebugWGStatus(ifaceName); err != nil {
-		log.Printf("❌ Server WireGuard status check failed: %v", err)
+	clientIP, err := e.allocateIPForPeer(req.RequesterId)
+	if err != nil {
+		e.sessionsMu.Unlock()
+		return nil, err
 	}
+	e.sessions[req.SessionId] = &exitSession{
+		requesterID: req.RequesterId,
+		superID:     req.SuperId,
+		reserver:    callerKey(ctx),
+		clientKey:   clientPubKey,
+		clientIP:    clientIP,
+		expires:     time.Now().Add(pendingSessionTTL),
+	}
+	capacity := e.bandwidthMbps
+	e.sessionsMu.Unlock()
+	log.Printf("🔗 Session %s reserved %s for %s, waiting for its ticket", req.SessionId, clientIP, req.RequesterId)
 
-	return &pb.ExitPeerInfoResponse{
+	resp := &pb.ExitPeerInfoResponse{
 		PublicKey:     e.pubKey.String(),
 		EndpointIp:    utils.GetLocalIP(),
-		EndpointPort:  fmt.Sprintf("%d", listenPort),
+		EndpointPort:  fmt.Sprintf("%d", e.listenPort),
 		AllowedIps:    "0.0.0.0/0",
-		BandwidthMbps: 85.0,
+		BandwidthMbps: capacity,
 		LatencyMs:     15.0,
 		ClientIp:      clientIP,
-	}, nil
+	}
+	if err := e.signAnswer(resp, req); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// EndSession removes the client of a session from the exit interface, if it
+// was admitted.
+func (e *ExitPeerServer) EndSession(ctx context.Context, req *pb.EndSessionRequest) (*pb.Ack, error) {
+	e.sessionsMu.Lock()
+	sess, ok := e.sessions[req.SessionId]
+	if !ok || sess.requesterID != req.RequesterId {
+		e.sessionsMu.Unlock()
+		return &pb.Ack{Received: false, Message: "Session not found"}, nil
+	}
+	if !sess.reservedBy(ctx) {
+		e.sessionsMu.Unlock()
+		log.Printf("❌ Refused to end session %s: caller did not reserve it", req.SessionId)
+		return &pb.Ack{Received: false, Message: "Session was reserved by another super node"}, nil
+	}
+	delete(e.sessions, req.SessionId)
+	admitted := sess.admitted
+	e.sessionsMu.Unlock()
+
+	if admitted {
+		if err := e.removePeer(sess); err != nil {
+			log.Printf("❌ Failed to remove peer of session %s: %v", req.SessionId, err)
+			return nil, fmt.Errorf("failed to remove peer from WG interface: %v", err)
+		}
+		log.Printf("🔚 Session %s of %s ended (%s), removed peer %s", req.SessionId, req.RequesterId, req.Reason, sess.clientKey.String())
+	} else {
+		log.Printf("🔚 Session %s of %s ended (%s) before it was admitted", req.SessionId, req.RequesterId, req.Reason)
+	}
+	return &pb.Ack{Received: true, Message: "Session ended"}, nil
+}
+
+// callerKey returns the certificate key of the super calling on ctx, or ""
+// without mutual TLS.
+func callerKey(ctx context.Context) string {
+	id, _ := utils.PeerIdentity(ctx)
+	return id.PublicKey
+}
+
+// reservedBy reports whether the caller on ctx is the super that reserved
+// the session.
+func (s *exitSession) reservedBy(ctx context.Context) bool {
+	return s.reserver == callerKey(ctx)
 }
@@ -0,0 +1,131 @@
+package exitpeer
+
+import (
+	"Client_peer/crypto"
+	"Client_peer/pb"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"net"
+	"testing"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+// newTestExit returns an exit that never touches a WireGuard interface and
+// trusts the given requesters and issuers without asking a base.
+func newTestExit(t *testing.T) *ExitPeerServer {
+	t.Helper()
+	_, identity, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, subnet, _ := net.ParseCIDR("10.100.0.1/24")
+	return &ExitPeerServer{
+		subnet:     subnet,
+		ipAlloc:    2,
+		allocMap:   make(map[string]string),
+		sessions:   make(map[string]*exitSession),
+		issuers:    make(map[string]time.Time),
+		requesters: make(map[string]time.Time),
+		identity:   identity,
+		peerID:     "exit-1",
+	}
+}
+
+// testNode is a client or super with its identity key.
+type testNode struct {
+	id     string
+	region string
+	pub    string
+	priv   ed25519.PrivateKey
+}
+
+func newTestNode(t *testing.T, region string, derive func(string, ed25519.PublicKey) string) testNode {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return testNode{id: derive(region, pub), region: region, pub: base64.StdEncoding.EncodeToString(pub), priv: priv}
+}
+
+// infoRequest is a signed request of client for sessionID, reserved for the
+// super superID.
+func infoRequest(t *testing.T, e *ExitPeerServer, client testNode, sessionID, superID string) *pb.ExitPeerInfoRequest {
+	t.Helper()
+	wgKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := &pb.ExitPeerInfoRequest{
+		RequesterId:       client.id,
+		ClientPublicKey:   base64.StdEncoding.EncodeToString([]byte(wgKey.PublicKey().String())),
+		Region:            "US",
+		SessionId:         sessionID,
+		RequesterRegion:   client.region,
+		ClientIdentityKey: client.pub,
+		ClientKeySignedAt: time.Now().Unix(),
+		SuperId:           superID,
+	}
+	req.ClientKeySignature = crypto.SignKeyPayload(client.priv,
+		crypto.ClientKeyPayload(req.RequesterId, req.ClientPublicKey, req.Region, req.ClientKeySignedAt))
+	e.requesters[client.id+"|"+client.pub] = time.Now().Add(time.Hour)
+	return req
+}
+
+func TestGetWireGuardInfoRefusesSessionIDInUse(t *testing.T) {
+	e := newTestExit(t)
+	client := newTestNode(t, "EU", crypto.DerivePeerID)
+	other := newTestNode(t, "EU", crypto.DerivePeerID)
+
+	first, err := e.GetWireGuardInfo(context.Background(), infoRequest(t, e, client, "sess-1", "super-1"))
+	if err != nil {
+		t.Fatalf("first reservation: %v", err)
+	}
+	if _, err := e.GetWireGuardInfo(context.Background(), infoRequest(t, e, other, "sess-1", "super-1")); err == nil {
+		t.Fatal("a second reservation took over session sess-1")
+	}
+
+	sess := e.sessions["sess-1"]
+	if sess.requesterID != client.id || sess.clientIP != first.ClientIp {
+		t.Fatalf("session sess-1 is now %s at %s, want %s at %s", sess.requesterID, sess.clientIP, client.id, first.ClientIp)
+	}
+}
+
+func TestAdmitClientOnlyWithTicketOfSessionSuper(t *testing.T) {
+	e := newTestExit(t)
+	client := newTestNode(t, "EU", crypto.DerivePeerID)
+	owner := newTestNode(t, "EU", crypto.DeriveSuperNodeID)
+	other := newTestNode(t, "EU", crypto.DeriveSuperNodeID)
+
+	req := infoRequest(t, e, client, "sess-1", owner.id)
+	if _, err := e.GetWireGuardInfo(context.Background(), req); err != nil {
+		t.Fatalf("reservation: %v", err)
+	}
+
+	// other is a registered super, but not the one the session is for
+	e.issuers[other.id+"|"+other.pub] = time.Now().Add(time.Hour)
+	ticket := &pb.ExitTicket{
+		SessionId:       "sess-1",
+		ClientId:        client.id,
+		ClientPublicKey: req.ClientPublicKey,
+		ExitPeerId:      e.peerID,
+		ExpiresAt:       time.Now().Add(time.Minute).Unix(),
+		IssuerId:        other.id,
+		IssuerRegion:    other.region,
+		IssuerPublicKey: other.pub,
+	}
+	ticket.Signature = crypto.SignExitTicket(other.priv, crypto.ExitTicketPayload(ticket.SessionId, ticket.ClientId,
+		ticket.ClientPublicKey, ticket.ExitPeerId, ticket.ExpiresAt, ticket.BandwidthMbps, ticket.IssuerId, ticket.IssuerRegion))
+
+	ack, err := e.AdmitClient(context.Background(), ticket)
+	if err != nil || ack.Received {
+		t.Fatalf("ticket of another super admitted the client: %v, %v", ack, err)
+	}
+	if e.sessions["sess-1"].admitted {
+		t.Fatal("session sess-1 admitted by a ticket of another super")
+	}
+}
@@ -0,0 +1,286 @@
+package exitpeer
+
+import (
+	"Client_peer/crypto"
+	"Client_peer/pb"
+	"Client_peer/utils"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+const (
+	// pendingSessionTTL is how long a reserved session waits for its ticket.
+	pendingSessionTTL = 2 * time.Minute
+	// maxTicketLifetime bounds how far in the future a ticket may expire.
+	maxTicketLifetime = 10 * time.Minute
+	// issuerVerifyTTL is how long a super or peer confirmed by the base is
+	// trusted before the base is asked again.
+	issuerVerifyTTL     = time.Minute
+	ticketSweepInterval = 5 * time.Second
+)
+
+// SetPeerID tells the exit its own peer ID, which tickets must name.
+func (e *ExitPeerServer) SetPeerID(id string) {
+	e.peerID = id
+}
+
+// SetBaseClient sets the base node asked whether ticket issuers are
+// registered super nodes.
+func (e *ExitPeerServer) SetBaseClient(c pb.BaseNodeServiceClient) {
+	e.baseClient = c
+}
+
+// AdmitClient adds the client of a reserved session to the exit interface,
+// or extends its stay, once its ticket checks out.
+func (e *ExitPeerServer) AdmitClient(ctx context.Context, t *pb.ExitTicket) (*pb.Ack, error) {
+	if err := e.verifyTicket(ctx, t); err != nil {
+		log.Printf("❌ Rejected ticket for session %s: %v", t.SessionId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+
+	e.sessionsMu.Lock()
+	defer e.sessionsMu.Unlock()
+
+	sess, ok := e.sessions[t.SessionId]
+	if !ok || sess.requesterID != t.ClientId {
+		return &pb.Ack{Received: false, Message: "Session not found"}, nil
+	}
+	if t.IssuerId != sess.superID {
+		log.Printf("❌ Rejected ticket for session %s: issued by %s, not by its super %s", t.SessionId, t.IssuerId, sess.superID)
+		return &pb.Ack{Received: false, Message: "Ticket is not issued by the super of the session"}, nil
+	}
+	if !sess.reservedBy(ctx) {
+		log.Printf("❌ Rejected ticket for session %s: not passed on by the super that reserved it", t.SessionId)
+		return &pb.Ack{Received: false, Message: "Session was reserved by another super node"}, nil
+	}
+	if base64.StdEncoding.EncodeToString([]byte(sess.clientKey.String())) != t.ClientPublicKey {
+		return &pb.Ack{Received: false, Message: "Ticket is for a different client key"}, nil
+	}
+
+	if sess.admitted && t.BandwidthMbps != sess.bandwidthMbps {
+		return &pb.Ack{Received: false, Message: fmt.Sprintf("Ticket changes the granted bandwidth from %.1f Mbps", sess.bandwidthMbps)}, nil
+	}
+	if !sess.admitted {
+		if err := e.checkGrant(t); err != nil {
+			log.Printf("❌ Rejected ticket for session %s: %v", t.SessionId, err)
+			return &pb.Ack{Received: false, Message: err.Error()}, nil
+		}
+		if err := e.addPeer(sess.clientKey); err != nil {
+			log.Printf("❌ Failed to configure WireGuard on server: %v", err)
+			return nil, fmt.Errorf("failed to add peer to WG interface: %v", err)
+		}
+		sess.bandwidthMbps = t.BandwidthMbps
+		if err := e.shape(sess); err != nil {
+			log.Printf("❌ Failed to shape session %s: %v", t.SessionId, err)
+			if err := e.removePeer(sess); err != nil {
+				log.Printf("❌ Failed to remove unshaped peer of session %s: %v", t.SessionId, err)
+			}
+			return nil, fmt.Errorf("failed to limit session bandwidth: %v", err)
+		}
+		sess.admitted = true
+	}
+	sess.expires = time.Unix(t.ExpiresAt, 0)
+
+	log.Printf("🎫 Session %s admitted %s until %s at %.1f Mbps (issued by %s)",
+		t.SessionId, t.ClientId, sess.expires.Format(time.RFC3339), sess.bandwidthMbps, t.IssuerId)
+	return &pb.Ack{Received: true, Message: "Client admitted"}, nil
+}
+
+// checkGrant reports a ticket whose bandwidth does not fit in what is left
+// of the exit's capacity once the admitted sessions are served. The caller
+// holds sessionsMu.
+func (e *ExitPeerServer) checkGrant(t *pb.ExitTicket) error {
+	if t.BandwidthMbps < 0 {
+		return fmt.Errorf("ticket grants negative bandwidth")
+	}
+	if e.bandwidthMbps <= 0 {
+		return nil
+	}
+	if t.BandwidthMbps == 0 {
+		return fmt.Errorf("ticket grants no bandwidth on an exit of %.1f Mbps", e.bandwidthMbps)
+	}
+
+	var granted float32
+	for _, sess := range e.sessions {
+		if sess.admitted {
+			granted += sess.bandwidthMbps
+		}
+	}
+	if granted+t.BandwidthMbps > e.bandwidthMbps {
+		return fmt.Errorf("ticket grants %.1f Mbps, exit has %.1f of %.1f Mbps left",
+			t.BandwidthMbps, e.bandwidthMbps-granted, e.bandwidthMbps)
+	}
+	return nil
+}
+
+// verifyTicket checks that t is for this exit, unexpired, signed by the key
+// behind its issuer's node ID, and that the base knows the issuer as a
+// registered super node.
+func (e *ExitPeerServer) verifyTicket(ctx context.Context, t *pb.ExitTicket) error {
+	now := time.Now()
+	expires := time.Unix(t.ExpiresAt, 0)
+	switch {
+	case t.ExitPeerId != e.peerID:
+		return fmt.Errorf("ticket is for exit %s", t.ExitPeerId)
+	case !expires.After(now):
+		return fmt.Errorf("ticket expired at %s", expires.Format(time.RFC3339))
+	case expires.Sub(now) > maxTicketLifetime:
+		return fmt.Errorf("ticket lifetime exceeds %s", maxTicketLifetime)
+	}
+
+	pub, err := base64.StdEncoding.DecodeString(t.IssuerPublicKey)
+	if err != nil || crypto.DeriveSuperNodeID(t.IssuerRegion, pub) != t.IssuerId {
+		return fmt.Errorf("issuer %s does not match its key", t.IssuerId)
+	}
+	payload := crypto.ExitTicketPayload(t.SessionId, t.ClientId, t.ClientPublicKey, t.ExitPeerId,
+		t.ExpiresAt, t.BandwidthMbps, t.IssuerId, t.IssuerRegion)
+	if !crypto.VerifyExitTicket(t.IssuerPublicKey, payload, t.Signature) {
+		return fmt.Errorf("invalid ticket signature")
+	}
+
+	return e.verifyIssuer(ctx, t.IssuerId, t.IssuerRegion, t.IssuerPublicKey)
+}
+
+func (e *ExitPeerServer) verifyIssuer(ctx context.Context, id, region, pubKey string) error {
+	key := id + "|" + pubKey
+	e.sessionsMu.Lock()
+	known := time.Now().Before(e.issuers[key])
+	e.sessionsMu.Unlock()
+	if known {
+		return nil
+	}
+
+	if e.baseClient == nil {
+		return fmt.Errorf("no base node to verify issuer %s", id)
+	}
+	ack, err := e.baseClient.VerifySuperNode(ctx, &pb.SuperNodeIdentity{NodeId: id, Region: region, PublicKey: pubKey})
+	if err != nil {
+		return fmt.Errorf("could not verify issuer %s with the base: %w", id, err)
+	}
+	if !ack.Received {
+		return fmt.Errorf("issuer %s is not a registered super node: %s", id, ack.Message)
+	}
+
+	e.sessionsMu.Lock()
+	e.issuers[key] = time.Now().Add(issuerVerifyTTL)
+	e.sessionsMu.Unlock()
+	return nil
+}
+
+func (e *ExitPeerServer) addPeer(clientKey wgtypes.Key) error {
+	// Allow all traffic (0.0.0.0/0) through the tunnel for VPN functionality
+	_, allowAllNet, _ := net.ParseCIDR("0.0.0.0/0")
+
+	log.Printf("🔧 SERVER Adding Peer:")
+	log.Printf("   Client Public Key: %s", clientKey.String())
+	log.Printf("   Allowed IPs: %s (routing all traffic)", allowAllNet.String())
+
+	peerCfg := wgtypes.PeerConfig{
+		PublicKey:  clientKey,
+		AllowedIPs: []net.IPNet{*allowAllNet},
+	}
+	if err := utils.ConfigureWG(e.ifaceName, e.privKey, e.listenPort, []wgtypes.PeerConfig{peerCfg}); err != nil {
+		return err
+	}
+
+	// Verify server configuration
+	if err := utils.DebugWGStatus(e.ifaceName); err != nil {
+		log.Printf("❌ Server WireGuard status check failed: %v", err)
+	}
+	return nil
+}
+
+func (e *ExitPeerServer) removePeer(sess *exitSession) error {
+	if sess.bandwidthMbps > 0 {
+		if id, err := e.hostIndex(sess.clientIP); err == nil {
+			if err := utils.UnshapePeer(e.ifaceName, id); err != nil {
+				log.Printf("⚠️ Failed to remove bandwidth limit of %s: %v", sess.clientIP, err)
+			}
+		}
+	}
+
+	peerCfg := wgtypes.PeerConfig{
+		PublicKey: sess.clientKey,
+		Remove:    true,
+	}
+	return utils.ConfigureWG(e.ifaceName, e.privKey, e.listenPort, []wgtypes.PeerConfig{peerCfg})
+}
+
+// shape limits traffic to the client of sess to its granted bandwidth. A
+// session granted nothing, on an exit of unknown capacity, is not shaped.
+func (e *ExitPeerServer) shape(sess *exitSession) error {
+	if sess.bandwidthMbps <= 0 {
+		return nil
+	}
+	id, err := e.hostIndex(sess.clientIP)
+	if err != nil {
+		return err
+	}
+	return utils.ShapePeer(e.ifaceName, id, strings.TrimSuffix(sess.clientIP, "/32"), sess.bandwidthMbps)
+}
+
+// hostIndex returns the position of clientIP (a /32) in the exit subnet,
+// which is unique per client and names its traffic class.
+func (e *ExitPeerServer) hostIndex(clientIP string) (int, error) {
+	ip := net.ParseIP(strings.TrimSuffix(clientIP, "/32")).To4()
+	base := e.subnet.IP.To4()
+	if ip == nil || base == nil || !e.subnet.Contains(ip) {
+		return 0, fmt.Errorf("client address %s is not in exit subnet %s", clientIP, e.subnet)
+	}
+	return int(binary.BigEndian.Uint32(ip) - binary.BigEndian.Uint32(base)), nil
+}
+
+// RunTicketExpiry removes clients whose ticket ran out and drops sessions
+// that never got one, until stop is closed.
+func (e *ExitPeerServer) RunTicketExpiry(stop <-chan struct{}) {
+	ticker := time.NewTicker(ticketSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			e.expireSessions(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (e *ExitPeerServer) expireSessions(now time.Time) {
+	e.sessionsMu.Lock()
+	defer e.sessionsMu.Unlock()
+
+	for id, sess := range e.sessions {
+		if now.Before(sess.expires) {
+			continue
+		}
+		if sess.admitted {
+			if err := e.removePeer(sess); err != nil {
+				log.Printf("❌ Failed to remove peer of expired session %s: %v", id, err)
+				continue
+			}
+			log.Printf("⌛ Ticket of session %s expired, removed %s", id, sess.requesterID)
+		} else {
+			log.Printf("⌛ Session %s of %s never got a ticket, dropped", id, sess.requesterID)
+		}
+		delete(e.sessions, id)
+	}
+	for key, until := range e.issuers {
+		if !now.Before(until) {
+			delete(e.issuers, key)
+		}
+	}
+	for key, until := range e.requesters {
+		if !now.Before(until) {
+			delete(e.requesters, key)
+		}
+	}
+}
@@ -2,43 +2,66 @@ package main
 
 import (
 	"Client_peer/client"
+	"Client_peer/config"
+	"Client_peer/crypto"
 	"Client_peer/exitpeer"
 	basepb "Client_peer/pb"
 	"Client_peer/utils"
 	"context"
-	"crypto/rand"
-	"encoding/hex"
+	"encoding/base64"
 	"flag"
 	"fmt"
 	"log"
 	"net"
 	"os"
 	"os/signal"
+	"strconv"
 	"sync"
 	"syscall"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
-func generateRandomID(region string) string {
-	b := make([]byte, 3)
-	if _, err := rand.Read(b); err != nil {
-		return region + "000"
-	}
-	return fmt.Sprintf("peer-%s-%s", region, hex.EncodeToString(b))
-}
-
 func main() {
-	baseIP := flag.String("base-ip", "127.0.0.1", "IP address of the Base Node")
-	region := flag.String("region", "IN", "Region code for Super Node")
-	exitPeerPort := flag.String("exit-port", "6000", "Port to run Exit Peer gRPC Server")
-	reqRegion := flag.String("req-region", "", "Region code to request exit (optional)")
+	cfg := config.Default()
+	configPath := flag.String("config", "", "Path to a YAML config file")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration and exit")
+	flag.StringVar(&cfg.Base.IP, "base-ip", cfg.Base.IP, "IP address of the Base Node")
+	flag.StringVar(&cfg.Region, "region", cfg.Region, "Region code (optional, discovered from the Base Node when empty)")
+	flag.IntVar(&cfg.Base.Port, "base-port", cfg.Base.Port, "Port of the Base Node (optional, derived from --region when 0)")
+	flag.StringVar(&cfg.Exit.GrpcPort, "exit-port", cfg.Exit.GrpcPort, "Port to run Exit Peer gRPC Server")
+	flag.StringVar(&cfg.Role, "role", cfg.Role, "What this peer does: client (use a remote exit), exit (serve as an exit) or both")
+	flag.StringVar(&cfg.Client.RequestRegion, "req-region", cfg.Client.RequestRegion, "Region code to request exit (optional)")
+	flag.StringVar(&cfg.TLS.CA, "tls-ca", cfg.TLS.CA, "Network CA certificate for mutual TLS")
+	flag.StringVar(&cfg.TLS.Cert, "tls-cert", cfg.TLS.Cert, "Certificate issued to this peer's identity key")
+	flag.BoolVar(&cfg.TLS.Insecure, "insecure", cfg.TLS.Insecure, "Disable mutual TLS on all gRPC links (development only)")
 	flag.Parse()
 
+	if err := config.Resolve(flag.CommandLine, *configPath, &cfg); err != nil {
+		log.Fatalf("❌ Invalid configuration: %v", err)
+	}
+	if *printConfig {
+		if err := config.Print(os.Stdout, &cfg); err != nil {
+			log.Fatalf("❌ Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	// 🔒 Mutual TLS with a certificate issued to the identity key
+	priv, pub, err := crypto.LoadOrCreateKeypair()
+	if err != nil {
+		log.Fatalf("❌ Failed to load identity key: %v", err)
+	}
+	if cfg.TLS.Insecure {
+		log.Println("⚠️ Mutual TLS disabled, gRPC traffic is unencrypted")
+	} else if err := utils.LoadMutualTLSWithKey(cfg.TLS.CA, cfg.TLS.Cert, priv); err != nil {
+		log.Fatalf("❌ Failed to load TLS credentials (use --insecure to run without TLS): %v", err)
+	}
+
 	ip := utils.GetLocalIP()
-	addr := fmt.Sprintf("%s:%s", ip, *exitPeerPort)
-	id := generateRandomID(*region)
+	addr := fmt.Sprintf("%s:%s", ip, cfg.Exit.GrpcPort)
 
 	// Setup signal handling for clean shutdown
 	sigChan := make(chan os.Signal, 1)
@@ -53,6 +76,12 @@ func main() {
 		cleanupOnce.Do(func() {
 			log.Println("🛑 Shutdown signal received. Cleaning up...")
 			if peer != nil {
+				if err := peer.ReleaseExit(); err != nil {
+					log.Printf("⚠️ Failed to release exit session: %v", err)
+				}
+				if err := peer.WithdrawExit(); err != nil {
+					log.Printf("⚠️ Failed to withdraw exit: %v", err)
+				}
 				peer.Cleanup()
 				log.Println("✅ Client peer cleanup completed")
 			}
@@ -71,47 +100,10 @@ func main() {
 		cleanup()
 	}()
 
-	// 🛰 Start Exit Peer gRPC server in a goroutine
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		lis, err := net.Listen("tcp", addr)
-		if err != nil {
-			log.Fatalf("❌ Failed to listen on exit peer port %s: %v", *exitPeerPort, err)
-		}
-		grpcServer := grpc.NewServer()
-		basepb.RegisterExitPeerServiceServer(grpcServer, exitpeer.NewExitPeerServer())
-		log.Printf("🚪 Exit Peer gRPC server running on port %s", *exitPeerPort)
-
-		// Start server in a goroutine so we can stop it on signal
-		serverErr := make(chan error, 1)
-		go func() {
-			serverErr <- grpcServer.Serve(lis)
-		}()
-
-		// Wait for shutdown signal or server error
-		select {
-		case <-sigChan:
-			log.Println("🛑 Gracefully stopping Exit Peer server...")
-			grpcServer.GracefulStop()
-		case err := <-serverErr:
-			if err != nil {
-				log.Printf("❌ Exit Peer server failed: %v", err)
-			}
-		}
-	}()
-
-	basePort := map[string]int{
-		"IN": 50051,
-		"US": 50053,
-	}[*region]
-	if basePort == 0 {
-		basePort = 50051
-	}
-	baseAddr := fmt.Sprintf("%s:%d", *baseIP, basePort)
+	baseAddr := fmt.Sprintf("%s:%d", cfg.Base.IP, cfg.Base.BasePort(cfg.Region))
 
 	// 🌐 Connect to Base Node
-	baseConn, err := grpc.Dial(baseAddr, grpc.WithInsecure())
+	baseConn, err := grpc.Dial(baseAddr, utils.DialOption(utils.RoleBase))
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to base node: %v", err)
 	}
@@ -119,17 +111,29 @@ func main() {
 
 	baseClient := basepb.NewBaseNodeServiceClient(baseConn)
 
-	// 🔍 Get Super Nodes
-	res, err := baseClient.GetActiveSuperNodes(context.Background(), &emptypb.Empty{})
-	if err != nil {
-		log.Fatalf("❌ Failed to get active super nodes: %v", err)
+	// 🔍 Get Super Nodes, discovering our region first if none was given
+	var candidates []*basepb.SuperNode
+	if cfg.Region == "" {
+		discovered, err := discoverRegion(baseClient)
+		if err != nil {
+			log.Fatalf("❌ Region discovery failed: %v", err)
+		}
+		cfg.Region = discovered.Region
+		candidates = discovered.Nodes
+		log.Printf("🌍 Base Node assigned region %s: %s", discovered.Region, discovered.Message)
+	} else {
+		res, err := baseClient.GetActiveSuperNodes(context.Background(), &emptypb.Empty{})
+		if err != nil {
+			log.Fatalf("❌ Failed to get active super nodes: %v", err)
+		}
+		candidates = res.Nodes
 	}
-	if len(res.Nodes) == 0 {
+	if len(candidates) == 0 {
 		log.Fatalf("❌ No active super nodes found")
 	}
 
 	var chosen *basepb.SuperNode
-	for _, node := range res.Nodes {
+	for _, node := range candidates {
 		if node.IsAlive {
 			chosen = node
 			break
@@ -139,16 +143,88 @@ func main() {
 		log.Fatalf("❌ No alive super nodes found")
 	}
 
+	id := crypto.DerivePeerID(cfg.Region, pub)
+
+	// 🛰 Start Exit Peer gRPC server in a goroutine when serving as an exit
+	var exitServer *exitpeer.ExitPeerServer
+	if cfg.RunsExit() {
+		exitServer = exitpeer.NewExitPeerServer(cfg.Exit.Interface, cfg.Exit.ListenPort, cfg.Exit.Address)
+		// Clients are only admitted with a ticket from a registered super, so
+		// the exit is fully set up before it serves
+		exitServer.SetPeerID(id)
+		exitServer.SetIdentityKey(priv)
+		exitServer.SetBaseClient(baseClient)
+		exitServer.SetBandwidth(cfg.Exit.BandwidthMbps)
+		go exitServer.RunTicketExpiry(nil)
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			lis, err := net.Listen("tcp", addr)
+			if err != nil {
+				log.Fatalf("❌ Failed to listen on exit peer port %s: %v", cfg.Exit.GrpcPort, err)
+			}
+			// Only super nodes may ask an exit peer for its WireGuard details
+			grpcServer := grpc.NewServer(
+				utils.ServerOption(),
+				grpc.UnaryInterceptor(utils.RequireRole(utils.RoleSuper, "dvpn.ExitPeerService")),
+			)
+			basepb.RegisterExitPeerServiceServer(grpcServer, exitServer)
+			log.Printf("🚪 Exit Peer gRPC server running on port %s", cfg.Exit.GrpcPort)
+
+			// Start server in a goroutine so we can stop it on signal
+			serverErr := make(chan error, 1)
+			go func() {
+				serverErr <- grpcServer.Serve(lis)
+			}()
+
+			// Wait for shutdown signal or server error
+			select {
+			case <-sigChan:
+				log.Println("🛑 Gracefully stopping Exit Peer server...")
+				grpcServer.GracefulStop()
+			case err := <-serverErr:
+				if err != nil {
+					log.Printf("❌ Exit Peer server failed: %v", err)
+				}
+			}
+		}()
+	}
+
 	log.Printf("🎉 Connecting to Super Node: %s at %s", chosen.NodeId, chosen.Ip)
 
 	saddr := fmt.Sprintf("%s:%s", chosen.Ip, chosen.Port)
-	superConn, err := grpc.Dial(saddr, grpc.WithInsecure())
+	superConn, err := grpc.Dial(saddr, utils.DialOption(utils.RoleSuper))
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to super node: %v", err)
 	}
 	defer superConn.Close()
 
-	peer = client.NewClientPeer(superConn, id, *region)
+	peer = client.NewClientPeer(superConn, id, cfg.Region)
+	peer.SetSuperID(chosen.NodeId)
+	peer.SetBaseClient(baseClient)
+	peer.SetTunnel(cfg.Client.Interface, cfg.Client.ListenPort, cfg.Client.Address, cfg.Client.DNS)
+	if cfg.RunsExit() {
+		peer.SetExitPort(cfg.Exit.GrpcPort)
+		peer.SetExitInterface(cfg.Exit.Interface)
+		peer.SetExitSubnet(cfg.Exit.Address)
+		peer.SetExitAdvertisement(&basepb.ExitAdvertisement{
+			PublicKey:      exitServer.PublicKey(),
+			EndpointIp:     ip,
+			EndpointPort:   strconv.Itoa(cfg.Exit.ListenPort),
+			GrpcPort:       cfg.Exit.GrpcPort,
+			AllowedIps:     "0.0.0.0/0",
+			MaxSessions:    int32(cfg.Exit.MaxSessions),
+			BandwidthMbps:  cfg.Exit.BandwidthMbps,
+			AllowedRegions: cfg.Exit.AllowedRegions,
+		})
+	}
+
+	// Exits and clients check each other's identity with the base
+	if err := peer.RegisterIdentity(); err != nil {
+		log.Fatalf("❌ Failed to register identity with base node: %v", err)
+	}
+	go peer.KeepIdentityRegistered()
 
 	if err := peer.Register(); err != nil {
 		log.Fatalf("❌ Failed to register peer: %v", err)
@@ -157,15 +233,44 @@ func main() {
 	log.Println("✅ Peer registered. Starting heartbeat...")
 	go peer.StartHeartbeat()
 
-	if *reqRegion != "" {
-		log.Printf("📨 Requesting exit to region %s...", *reqRegion)
-		if err := peer.RequestExitEndpoint(*reqRegion, 10.0, 100.0); err != nil {
+	// 👀 Follow the region's Super Nodes and move if ours disappears
+	go peer.FollowSuperNodes(baseClient)
+
+	if cfg.Client.RequestRegion != "" {
+		log.Printf("📨 Requesting exit to region %s...", cfg.Client.RequestRegion)
+		if err := peer.RequestExitEndpoint(cfg.Client.RequestRegion, cfg.Client.MinBandwidthMbps, cfg.Client.MaxLatencyMs); err != nil {
 			log.Fatalf("❌ Failed to request exit: %v", err)
 		}
-	} else {
+	} else if cfg.RunsClient() {
 		log.Println("ℹ️ No --req-region specified, skipping exit peer request.")
 	}
 
 	// Wait for shutdown signal
 	wg.Wait()
 }
+
+// discoverRegion asks the Base Node which region this peer belongs to and
+// which super nodes it should use there.
+func discoverRegion(baseClient basepb.BaseNodeServiceClient) (*basepb.DiscoveryResponse, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, pub, err := crypto.LoadOrCreateKeypair()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load identity key: %w", err)
+	}
+
+	res, err := baseClient.DiscoverClientRegion(ctx, &basepb.DiscoverRequest{
+		PublicKey: base64.StdEncoding.EncodeToString(pub),
+		Os:        "Linux",
+		Version:   "0.1",
+		NatType:   "symmetric",
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !res.Accepted {
+		return nil, fmt.Errorf("rejected by base node: %s", res.Message)
+	}
+	return res, nil
+}
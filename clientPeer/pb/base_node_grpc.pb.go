@@ -20,10 +20,15 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	BaseNodeService_RegisterSuperNode_FullMethodName   = "/dvpn.BaseNodeService/RegisterSuperNode"
-	BaseNodeService_SuperNodeHeartbeat_FullMethodName  = "/dvpn.BaseNodeService/SuperNodeHeartbeat"
-	BaseNodeService_GetActiveSuperNodes_FullMethodName = "/dvpn.BaseNodeService/GetActiveSuperNodes"
-	BaseNodeService_RequestExitRegion_FullMethodName   = "/dvpn.BaseNodeService/RequestExitRegion"
+	BaseNodeService_RegisterSuperNode_FullMethodName    = "/dvpn.BaseNodeService/RegisterSuperNode"
+	BaseNodeService_SuperNodeHeartbeat_FullMethodName   = "/dvpn.BaseNodeService/SuperNodeHeartbeat"
+	BaseNodeService_GetActiveSuperNodes_FullMethodName  = "/dvpn.BaseNodeService/GetActiveSuperNodes"
+	BaseNodeService_RequestExitRegion_FullMethodName    = "/dvpn.BaseNodeService/RequestExitRegion"
+	BaseNodeService_DiscoverClientRegion_FullMethodName = "/dvpn.BaseNodeService/DiscoverClientRegion"
+	BaseNodeService_WatchSuperNodes_FullMethodName      = "/dvpn.BaseNodeService/WatchSuperNodes"
+	BaseNodeService_VerifySuperNode_FullMethodName      = "/dvpn.BaseNodeService/VerifySuperNode"
+	BaseNodeService_RegisterPeerIdentity_FullMethodName = "/dvpn.BaseNodeService/RegisterPeerIdentity"
+	BaseNodeService_VerifyPeer_FullMethodName           = "/dvpn.BaseNodeService/VerifyPeer"
 )
 
 // BaseNodeServiceClient is the client API for BaseNodeService service.
@@ -34,6 +39,11 @@ type BaseNodeServiceClient interface {
 	SuperNodeHeartbeat(ctx context.Context, in *HeartbeatRequest, opts ...grpc.CallOption) (*Ack, error)
 	GetActiveSuperNodes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*SuperNodeList, error)
 	RequestExitRegion(ctx context.Context, in *ExitRegionRequest, opts ...grpc.CallOption) (*SuperNodeList, error)
+	DiscoverClientRegion(ctx context.Context, in *DiscoverRequest, opts ...grpc.CallOption) (*DiscoveryResponse, error)
+	WatchSuperNodes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SuperNodeEvent], error)
+	VerifySuperNode(ctx context.Context, in *SuperNodeIdentity, opts ...grpc.CallOption) (*Ack, error)
+	RegisterPeerIdentity(ctx context.Context, in *PeerIdentity, opts ...grpc.CallOption) (*Ack, error)
+	VerifyPeer(ctx context.Context, in *PeerIdentity, opts ...grpc.CallOption) (*Ack, error)
 }
 
 type baseNodeServiceClient struct {
@@ -84,6 +94,65 @@ func (c *baseNodeServiceClient) RequestExitRegion(ctx context.Context, in *ExitR
 	return out, nil
 }
 
+func (c *baseNodeServiceClient) DiscoverClientRegion(ctx context.Context, in *DiscoverRequest, opts ...grpc.CallOption) (*DiscoveryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(DiscoveryResponse)
+	err := c.cc.Invoke(ctx, BaseNodeService_DiscoverClientRegion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *baseNodeServiceClient) WatchSuperNodes(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (grpc.ServerStreamingClient[SuperNodeEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BaseNodeService_ServiceDesc.Streams[0], BaseNodeService_WatchSuperNodes_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[emptypb.Empty, SuperNodeEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BaseNodeService_WatchSuperNodesClient = grpc.ServerStreamingClient[SuperNodeEvent]
+
+func (c *baseNodeServiceClient) VerifySuperNode(ctx context.Context, in *SuperNodeIdentity, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, BaseNodeService_VerifySuperNode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *baseNodeServiceClient) RegisterPeerIdentity(ctx context.Context, in *PeerIdentity, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, BaseNodeService_RegisterPeerIdentity_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *baseNodeServiceClient) VerifyPeer(ctx context.Context, in *PeerIdentity, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, BaseNodeService_VerifyPeer_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BaseNodeServiceServer is the server API for BaseNodeService service.
 // All implementations must embed UnimplementedBaseNodeServiceServer
 // for forward compatibility.
@@ -92,6 +161,11 @@ type BaseNodeServiceServer interface {
 	SuperNodeHeartbeat(context.Context, *HeartbeatRequest) (*Ack, error)
 	GetActiveSuperNodes(context.Context, *emptypb.Empty) (*SuperNodeList, error)
 	RequestExitRegion(context.Context, *ExitRegionRequest) (*SuperNodeList, error)
+	DiscoverClientRegion(context.Context, *DiscoverRequest) (*DiscoveryResponse, error)
+	WatchSuperNodes(*emptypb.Empty, grpc.ServerStreamingServer[SuperNodeEvent]) error
+	VerifySuperNode(context.Context, *SuperNodeIdentity) (*Ack, error)
+	RegisterPeerIdentity(context.Context, *PeerIdentity) (*Ack, error)
+	VerifyPeer(context.Context, *PeerIdentity) (*Ack, error)
 	mustEmbedUnimplementedBaseNodeServiceServer()
 }
 
@@ -114,6 +188,21 @@ func (UnimplementedBaseNodeServiceServer) GetActiveSuperNodes(context.Context, *
 func (UnimplementedBaseNodeServiceServer) RequestExitRegion(context.Context, *ExitRegionRequest) (*SuperNodeList, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RequestExitRegion not implemented")
 }
+func (UnimplementedBaseNodeServiceServer) DiscoverClientRegion(context.Context, *DiscoverRequest) (*DiscoveryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DiscoverClientRegion not implemented")
+}
+func (UnimplementedBaseNodeServiceServer) WatchSuperNodes(*emptypb.Empty, grpc.ServerStreamingServer[SuperNodeEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchSuperNodes not implemented")
+}
+func (UnimplementedBaseNodeServiceServer) VerifySuperNode(context.Context, *SuperNodeIdentity) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifySuperNode not implemented")
+}
+func (UnimplementedBaseNodeServiceServer) RegisterPeerIdentity(context.Context, *PeerIdentity) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegisterPeerIdentity not implemented")
+}
+func (UnimplementedBaseNodeServiceServer) VerifyPeer(context.Context, *PeerIdentity) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyPeer not implemented")
+}
 func (UnimplementedBaseNodeServiceServer) mustEmbedUnimplementedBaseNodeServiceServer() {}
 func (UnimplementedBaseNodeServiceServer) testEmbeddedByValue()                         {}
 
@@ -207,6 +296,89 @@ func _BaseNodeService_RequestExitRegion_Handler(srv interface{}, ctx context.Con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BaseNodeService_DiscoverClientRegion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DiscoverRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BaseNodeServiceServer).DiscoverClientRegion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BaseNodeService_DiscoverClientRegion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BaseNodeServiceServer).DiscoverClientRegion(ctx, req.(*DiscoverRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BaseNodeService_WatchSuperNodes_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(emptypb.Empty)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BaseNodeServiceServer).WatchSuperNodes(m, &grpc.GenericServerStream[emptypb.Empty, SuperNodeEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BaseNodeService_WatchSuperNodesServer = grpc.ServerStreamingServer[SuperNodeEvent]
+
+func _BaseNodeService_VerifySuperNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SuperNodeIdentity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BaseNodeServiceServer).VerifySuperNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BaseNodeService_VerifySuperNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BaseNodeServiceServer).VerifySuperNode(ctx, req.(*SuperNodeIdentity))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BaseNodeService_RegisterPeerIdentity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeerIdentity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BaseNodeServiceServer).RegisterPeerIdentity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BaseNodeService_RegisterPeerIdentity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BaseNodeServiceServer).RegisterPeerIdentity(ctx, req.(*PeerIdentity))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BaseNodeService_VerifyPeer_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PeerIdentity)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BaseNodeServiceServer).VerifyPeer(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BaseNodeService_VerifyPeer_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BaseNodeServiceServer).VerifyPeer(ctx, req.(*PeerIdentity))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BaseNodeService_ServiceDesc is the grpc.ServiceDesc for BaseNodeService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -230,7 +402,29 @@ var BaseNodeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RequestExitRegion",
 			Handler:    _BaseNodeService_RequestExitRegion_Handler,
 		},
+		{
+			MethodName: "DiscoverClientRegion",
+			Handler:    _BaseNodeService_DiscoverClientRegion_Handler,
+		},
+		{
+			MethodName: "VerifySuperNode",
+			Handler:    _BaseNodeService_VerifySuperNode_Handler,
+		},
+		{
+			MethodName: "RegisterPeerIdentity",
+			Handler:    _BaseNodeService_RegisterPeerIdentity_Handler,
+		},
+		{
+			MethodName: "VerifyPeer",
+			Handler:    _BaseNodeService_VerifyPeer_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchSuperNodes",
+			Handler:       _BaseNodeService_WatchSuperNodes_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "base_node.proto",
 }
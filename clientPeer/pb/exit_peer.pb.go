@@ -28,8 +28,18 @@ type ExitPeerInfoRequest struct {
 	MinBandwidthMbps float32                `protobuf:"fixed32,3,opt,name=min_bandwidth_mbps,json=minBandwidthMbps,proto3" json:"min_bandwidth_mbps,omitempty"`
 	MaxLatencyMs     float32                `protobuf:"fixed32,4,opt,name=max_latency_ms,json=maxLatencyMs,proto3" json:"max_latency_ms,omitempty"`
 	Region           string                 `protobuf:"bytes,5,opt,name=region,proto3" json:"region,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	SessionId        string                 `protobuf:"bytes,6,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// The client signs its WireGuard key with its identity key, so the exit
+	// can tell the key was not swapped on the way.
+	RequesterRegion    string `protobuf:"bytes,7,opt,name=requester_region,json=requesterRegion,proto3" json:"requester_region,omitempty"`
+	ClientIdentityKey  string `protobuf:"bytes,8,opt,name=client_identity_key,json=clientIdentityKey,proto3" json:"client_identity_key,omitempty"`
+	ClientKeySignedAt  int64  `protobuf:"varint,9,opt,name=client_key_signed_at,json=clientKeySignedAt,proto3" json:"client_key_signed_at,omitempty"`
+	ClientKeySignature string `protobuf:"bytes,10,opt,name=client_key_signature,json=clientKeySignature,proto3" json:"client_key_signature,omitempty"`
+	// The super the session is reserved for. Only its tickets admit the
+	// client, and only it may end the session.
+	SuperId       string `protobuf:"bytes,11,opt,name=super_id,json=superId,proto3" json:"super_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
 }
 
 func (x *ExitPeerInfoRequest) Reset() {
@@ -97,6 +107,48 @@ func (x *ExitPeerInfoRequest) GetRegion() string {
 	return ""
 }
 
+func (x *ExitPeerInfoRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ExitPeerInfoRequest) GetRequesterRegion() string {
+	if x != nil {
+		return x.RequesterRegion
+	}
+	return ""
+}
+
+func (x *ExitPeerInfoRequest) GetClientIdentityKey() string {
+	if x != nil {
+		return x.ClientIdentityKey
+	}
+	return ""
+}
+
+func (x *ExitPeerInfoRequest) GetClientKeySignedAt() int64 {
+	if x != nil {
+		return x.ClientKeySignedAt
+	}
+	return 0
+}
+
+func (x *ExitPeerInfoRequest) GetClientKeySignature() string {
+	if x != nil {
+		return x.ClientKeySignature
+	}
+	return ""
+}
+
+func (x *ExitPeerInfoRequest) GetSuperId() string {
+	if x != nil {
+		return x.SuperId
+	}
+	return ""
+}
+
 type ExitPeerInfoResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	PublicKey     string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
@@ -106,8 +158,13 @@ type ExitPeerInfoResponse struct {
 	BandwidthMbps float32                `protobuf:"fixed32,5,opt,name=bandwidth_mbps,json=bandwidthMbps,proto3" json:"bandwidth_mbps,omitempty"`
 	LatencyMs     float32                `protobuf:"fixed32,6,opt,name=latency_ms,json=latencyMs,proto3" json:"latency_ms,omitempty"`
 	ClientIp      string                 `protobuf:"bytes,7,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	// The exit signs its WireGuard key and endpoint together with the
+	// session and the client's key, so the client can check them.
+	ExitPeerId      string `protobuf:"bytes,8,opt,name=exit_peer_id,json=exitPeerId,proto3" json:"exit_peer_id,omitempty"`
+	ExitIdentityKey string `protobuf:"bytes,9,opt,name=exit_identity_key,json=exitIdentityKey,proto3" json:"exit_identity_key,omitempty"`
+	Signature       string `protobuf:"bytes,10,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *ExitPeerInfoResponse) Reset() {
@@ -189,17 +246,281 @@ func (x *ExitPeerInfoResponse) GetClientIp() string {
 	return ""
 }
 
+func (x *ExitPeerInfoResponse) GetExitPeerId() string {
+	if x != nil {
+		return x.ExitPeerId
+	}
+	return ""
+}
+
+func (x *ExitPeerInfoResponse) GetExitIdentityKey() string {
+	if x != nil {
+		return x.ExitIdentityKey
+	}
+	return ""
+}
+
+func (x *ExitPeerInfoResponse) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+// EndSessionRequest ends an exit session. Supers pass it along the same
+// chain that opened the session, down to the exit peer, which drops the
+// client's WireGuard peer.
+type EndSessionRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	SessionId   string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	RequesterId string                 `protobuf:"bytes,2,opt,name=requester_id,json=requesterId,proto3" json:"requester_id,omitempty"`
+	Reason      string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"`
+	// Set when one super asks another to end a session it requested there;
+	// signed like ExitPeerRequest.
+	CallerId        string `protobuf:"bytes,4,opt,name=caller_id,json=callerId,proto3" json:"caller_id,omitempty"`
+	CallerRegion    string `protobuf:"bytes,5,opt,name=caller_region,json=callerRegion,proto3" json:"caller_region,omitempty"`
+	CallerPublicKey string `protobuf:"bytes,6,opt,name=caller_public_key,json=callerPublicKey,proto3" json:"caller_public_key,omitempty"`
+	Nonce           string `protobuf:"bytes,7,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	SignedAt        int64  `protobuf:"varint,8,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	Signature       string `protobuf:"bytes,9,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *EndSessionRequest) Reset() {
+	*x = EndSessionRequest{}
+	mi := &file_exit_peer_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EndSessionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EndSessionRequest) ProtoMessage() {}
+
+func (x *EndSessionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_exit_peer_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EndSessionRequest.ProtoReflect.Descriptor instead.
+func (*EndSessionRequest) Descriptor() ([]byte, []int) {
+	return file_exit_peer_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EndSessionRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *EndSessionRequest) GetRequesterId() string {
+	if x != nil {
+		return x.RequesterId
+	}
+	return ""
+}
+
+func (x *EndSessionRequest) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+func (x *EndSessionRequest) GetCallerId() string {
+	if x != nil {
+		return x.CallerId
+	}
+	return ""
+}
+
+func (x *EndSessionRequest) GetCallerRegion() string {
+	if x != nil {
+		return x.CallerRegion
+	}
+	return ""
+}
+
+func (x *EndSessionRequest) GetCallerPublicKey() string {
+	if x != nil {
+		return x.CallerPublicKey
+	}
+	return ""
+}
+
+func (x *EndSessionRequest) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *EndSessionRequest) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
+func (x *EndSessionRequest) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+// ExitTicket is issued and signed by the super of the client. It lets the
+// client use one exit until expires_at; the exit only adds the client's
+// WireGuard key while it holds a valid ticket, and removes it once the
+// ticket expires without being renewed. bandwidth_mbps is the rate the
+// super of the exit granted the session; the exit admits the client only
+// while its grants fit the exit's capacity, and shapes it to that rate.
+type ExitTicket struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	SessionId       string                 `protobuf:"bytes,1,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ClientId        string                 `protobuf:"bytes,2,opt,name=client_id,json=clientId,proto3" json:"client_id,omitempty"`
+	ClientPublicKey string                 `protobuf:"bytes,3,opt,name=client_public_key,json=clientPublicKey,proto3" json:"client_public_key,omitempty"`
+	ExitPeerId      string                 `protobuf:"bytes,4,opt,name=exit_peer_id,json=exitPeerId,proto3" json:"exit_peer_id,omitempty"`
+	ExpiresAt       int64                  `protobuf:"varint,5,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	BandwidthMbps   float32                `protobuf:"fixed32,6,opt,name=bandwidth_mbps,json=bandwidthMbps,proto3" json:"bandwidth_mbps,omitempty"`
+	IssuerId        string                 `protobuf:"bytes,7,opt,name=issuer_id,json=issuerId,proto3" json:"issuer_id,omitempty"`
+	IssuerRegion    string                 `protobuf:"bytes,8,opt,name=issuer_region,json=issuerRegion,proto3" json:"issuer_region,omitempty"`
+	IssuerPublicKey string                 `protobuf:"bytes,9,opt,name=issuer_public_key,json=issuerPublicKey,proto3" json:"issuer_public_key,omitempty"`
+	Signature       string                 `protobuf:"bytes,10,opt,name=signature,proto3" json:"signature,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ExitTicket) Reset() {
+	*x = ExitTicket{}
+	mi := &file_exit_peer_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExitTicket) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExitTicket) ProtoMessage() {}
+
+func (x *ExitTicket) ProtoReflect() protoreflect.Message {
+	mi := &file_exit_peer_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExitTicket.ProtoReflect.Descriptor instead.
+func (*ExitTicket) Descriptor() ([]byte, []int) {
+	return file_exit_peer_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *ExitTicket) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ExitTicket) GetClientId() string {
+	if x != nil {
+		return x.ClientId
+	}
+	return ""
+}
+
+func (x *ExitTicket) GetClientPublicKey() string {
+	if x != nil {
+		return x.ClientPublicKey
+	}
+	return ""
+}
+
+func (x *ExitTicket) GetExitPeerId() string {
+	if x != nil {
+		return x.ExitPeerId
+	}
+	return ""
+}
+
+func (x *ExitTicket) GetExpiresAt() int64 {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return 0
+}
+
+func (x *ExitTicket) GetBandwidthMbps() float32 {
+	if x != nil {
+		return x.BandwidthMbps
+	}
+	return 0
+}
+
+func (x *ExitTicket) GetIssuerId() string {
+	if x != nil {
+		return x.IssuerId
+	}
+	return ""
+}
+
+func (x *ExitTicket) GetIssuerRegion() string {
+	if x != nil {
+		return x.IssuerRegion
+	}
+	return ""
+}
+
+func (x *ExitTicket) GetIssuerPublicKey() string {
+	if x != nil {
+		return x.IssuerPublicKey
+	}
+	return ""
+}
+
+func (x *ExitTicket) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
 var File_exit_peer_proto protoreflect.FileDescriptor
 
 const file_exit_peer_proto_rawDesc = "" +
 	"\n" +
-	"\x0fexit_peer.proto\x12\x04dvpn\"\xd0\x01\n" +
+	"\x0fexit_peer.proto\x12\x04dvpn\x1a\x0fbase_node.proto\"\xc8\x03\n" +
 	"\x13ExitPeerInfoRequest\x12!\n" +
 	"\frequester_id\x18\x01 \x01(\tR\vrequesterId\x12*\n" +
 	"\x11client_public_key\x18\x02 \x01(\tR\x0fclientPublicKey\x12,\n" +
 	"\x12min_bandwidth_mbps\x18\x03 \x01(\x02R\x10minBandwidthMbps\x12$\n" +
 	"\x0emax_latency_ms\x18\x04 \x01(\x02R\fmaxLatencyMs\x12\x16\n" +
-	"\x06region\x18\x05 \x01(\tR\x06region\"\xff\x01\n" +
+	"\x06region\x18\x05 \x01(\tR\x06region\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x06 \x01(\tR\tsessionId\x12)\n" +
+	"\x10requester_region\x18\a \x01(\tR\x0frequesterRegion\x12.\n" +
+	"\x13client_identity_key\x18\b \x01(\tR\x11clientIdentityKey\x12/\n" +
+	"\x14client_key_signed_at\x18\t \x01(\x03R\x11clientKeySignedAt\x120\n" +
+	"\x14client_key_signature\x18\n" +
+	" \x01(\tR\x12clientKeySignature\x12\x19\n" +
+	"\bsuper_id\x18\v \x01(\tR\asuperId\"\xeb\x02\n" +
 	"\x14ExitPeerInfoResponse\x12\x1d\n" +
 	"\n" +
 	"public_key\x18\x01 \x01(\tR\tpublicKey\x12\x1f\n" +
@@ -211,9 +532,44 @@ const file_exit_peer_proto_rawDesc = "" +
 	"\x0ebandwidth_mbps\x18\x05 \x01(\x02R\rbandwidthMbps\x12\x1d\n" +
 	"\n" +
 	"latency_ms\x18\x06 \x01(\x02R\tlatencyMs\x12\x1b\n" +
-	"\tclient_ip\x18\a \x01(\tR\bclientIp2\\\n" +
+	"\tclient_ip\x18\a \x01(\tR\bclientIp\x12 \n" +
+	"\fexit_peer_id\x18\b \x01(\tR\n" +
+	"exitPeerId\x12*\n" +
+	"\x11exit_identity_key\x18\t \x01(\tR\x0fexitIdentityKey\x12\x1c\n" +
+	"\tsignature\x18\n" +
+	" \x01(\tR\tsignature\"\xac\x02\n" +
+	"\x11EndSessionRequest\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12!\n" +
+	"\frequester_id\x18\x02 \x01(\tR\vrequesterId\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\x12\x1b\n" +
+	"\tcaller_id\x18\x04 \x01(\tR\bcallerId\x12#\n" +
+	"\rcaller_region\x18\x05 \x01(\tR\fcallerRegion\x12*\n" +
+	"\x11caller_public_key\x18\x06 \x01(\tR\x0fcallerPublicKey\x12\x14\n" +
+	"\x05nonce\x18\a \x01(\tR\x05nonce\x12\x1b\n" +
+	"\tsigned_at\x18\b \x01(\x03R\bsignedAt\x12\x1c\n" +
+	"\tsignature\x18\t \x01(\tR\tsignature\"\xe8\x02\n" +
+	"\n" +
+	"ExitTicket\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x01 \x01(\tR\tsessionId\x12\x1b\n" +
+	"\tclient_id\x18\x02 \x01(\tR\bclientId\x12*\n" +
+	"\x11client_public_key\x18\x03 \x01(\tR\x0fclientPublicKey\x12 \n" +
+	"\fexit_peer_id\x18\x04 \x01(\tR\n" +
+	"exitPeerId\x12\x1d\n" +
+	"\n" +
+	"expires_at\x18\x05 \x01(\x03R\texpiresAt\x12%\n" +
+	"\x0ebandwidth_mbps\x18\x06 \x01(\x02R\rbandwidthMbps\x12\x1b\n" +
+	"\tissuer_id\x18\a \x01(\tR\bissuerId\x12#\n" +
+	"\rissuer_region\x18\b \x01(\tR\fissuerRegion\x12*\n" +
+	"\x11issuer_public_key\x18\t \x01(\tR\x0fissuerPublicKey\x12\x1c\n" +
+	"\tsignature\x18\n" +
+	" \x01(\tR\tsignature2\xba\x01\n" +
 	"\x0fExitPeerService\x12I\n" +
-	"\x10GetWireGuardInfo\x12\x19.dvpn.ExitPeerInfoRequest\x1a\x1a.dvpn.ExitPeerInfoResponseB\x10Z\x0eClient_peer/pbb\x06proto3"
+	"\x10GetWireGuardInfo\x12\x19.dvpn.ExitPeerInfoRequest\x1a\x1a.dvpn.ExitPeerInfoResponse\x120\n" +
+	"\n" +
+	"EndSession\x12\x17.dvpn.EndSessionRequest\x1a\t.dvpn.Ack\x12*\n" +
+	"\vAdmitClient\x12\x10.dvpn.ExitTicket\x1a\t.dvpn.AckB\x10Z\x0eClient_peer/pbb\x06proto3"
 
 var (
 	file_exit_peer_proto_rawDescOnce sync.Once
@@ -227,16 +583,23 @@ func file_exit_peer_proto_rawDescGZIP() []byte {
 	return file_exit_peer_proto_rawDescData
 }
 
-var file_exit_peer_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_exit_peer_proto_msgTypes = make([]protoimpl.MessageInfo, 4)
 var file_exit_peer_proto_goTypes = []any{
 	(*ExitPeerInfoRequest)(nil),  // 0: dvpn.ExitPeerInfoRequest
 	(*ExitPeerInfoResponse)(nil), // 1: dvpn.ExitPeerInfoResponse
+	(*EndSessionRequest)(nil),    // 2: dvpn.EndSessionRequest
+	(*ExitTicket)(nil),           // 3: dvpn.ExitTicket
+	(*Ack)(nil),                  // 4: dvpn.Ack
 }
 var file_exit_peer_proto_depIdxs = []int32{
 	0, // 0: dvpn.ExitPeerService.GetWireGuardInfo:input_type -> dvpn.ExitPeerInfoRequest
-	1, // 1: dvpn.ExitPeerService.GetWireGuardInfo:output_type -> dvpn.ExitPeerInfoResponse
-	1, // [1:2] is the sub-list for method output_type
-	0, // [0:1] is the sub-list for method input_type
+	2, // 1: dvpn.ExitPeerService.EndSession:input_type -> dvpn.EndSessionRequest
+	3, // 2: dvpn.ExitPeerService.AdmitClient:input_type -> dvpn.ExitTicket
+	1, // 3: dvpn.ExitPeerService.GetWireGuardInfo:output_type -> dvpn.ExitPeerInfoResponse
+	4, // 4: dvpn.ExitPeerService.EndSession:output_type -> dvpn.Ack
+	4, // 5: dvpn.ExitPeerService.AdmitClient:output_type -> dvpn.Ack
+	3, // [3:6] is the sub-list for method output_type
+	0, // [0:3] is the sub-list for method input_type
 	0, // [0:0] is the sub-list for extension type_name
 	0, // [0:0] is the sub-list for extension extendee
 	0, // [0:0] is the sub-list for field type_name
@@ -247,13 +610,14 @@ func file_exit_peer_proto_init() {
 	if File_exit_peer_proto != nil {
 		return
 	}
+	file_base_node_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_exit_peer_proto_rawDesc), len(file_exit_peer_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   2,
+			NumMessages:   4,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
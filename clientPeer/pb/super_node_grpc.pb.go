@@ -23,6 +23,11 @@ const (
 	SuperNodeService_PeerSessionHeartbeat_FullMethodName = "/dvpn.SuperNodeService/PeerSessionHeartbeat"
 	SuperNodeService_RequestExitPeer_FullMethodName      = "/dvpn.SuperNodeService/RequestExitPeer"
 	SuperNodeService_RequestExit_FullMethodName          = "/dvpn.SuperNodeService/RequestExit"
+	SuperNodeService_AdvertiseExit_FullMethodName        = "/dvpn.SuperNodeService/AdvertiseExit"
+	SuperNodeService_WithdrawExit_FullMethodName         = "/dvpn.SuperNodeService/WithdrawExit"
+	SuperNodeService_ReleaseExit_FullMethodName          = "/dvpn.SuperNodeService/ReleaseExit"
+	SuperNodeService_EndSession_FullMethodName           = "/dvpn.SuperNodeService/EndSession"
+	SuperNodeService_AdmitClient_FullMethodName          = "/dvpn.SuperNodeService/AdmitClient"
 )
 
 // SuperNodeServiceClient is the client API for SuperNodeService service.
@@ -33,6 +38,11 @@ type SuperNodeServiceClient interface {
 	PeerSessionHeartbeat(ctx context.Context, in *PeerSessionHeartbeatRequest, opts ...grpc.CallOption) (*Ack, error)
 	RequestExitPeer(ctx context.Context, in *ExitPeerRequest, opts ...grpc.CallOption) (*ExitPeerResponse, error)
 	RequestExit(ctx context.Context, in *ExitRequest, opts ...grpc.CallOption) (*WireguardConfig, error)
+	AdvertiseExit(ctx context.Context, in *ExitAdvertisement, opts ...grpc.CallOption) (*Ack, error)
+	WithdrawExit(ctx context.Context, in *ExitWithdrawal, opts ...grpc.CallOption) (*Ack, error)
+	ReleaseExit(ctx context.Context, in *ReleaseExitRequest, opts ...grpc.CallOption) (*Ack, error)
+	EndSession(ctx context.Context, in *EndSessionRequest, opts ...grpc.CallOption) (*Ack, error)
+	AdmitClient(ctx context.Context, in *ExitTicket, opts ...grpc.CallOption) (*Ack, error)
 }
 
 type superNodeServiceClient struct {
@@ -83,6 +93,56 @@ func (c *superNodeServiceClient) RequestExit(ctx context.Context, in *ExitReques
 	return out, nil
 }
 
+func (c *superNodeServiceClient) AdvertiseExit(ctx context.Context, in *ExitAdvertisement, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, SuperNodeService_AdvertiseExit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *superNodeServiceClient) WithdrawExit(ctx context.Context, in *ExitWithdrawal, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, SuperNodeService_WithdrawExit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *superNodeServiceClient) ReleaseExit(ctx context.Context, in *ReleaseExitRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, SuperNodeService_ReleaseExit_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *superNodeServiceClient) EndSession(ctx context.Context, in *EndSessionRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, SuperNodeService_EndSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *superNodeServiceClient) AdmitClient(ctx context.Context, in *ExitTicket, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, SuperNodeService_AdmitClient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SuperNodeServiceServer is the server API for SuperNodeService service.
 // All implementations must embed UnimplementedSuperNodeServiceServer
 // for forward compatibility.
@@ -91,6 +151,11 @@ type SuperNodeServiceServer interface {
 	PeerSessionHeartbeat(context.Context, *PeerSessionHeartbeatRequest) (*Ack, error)
 	RequestExitPeer(context.Context, *ExitPeerRequest) (*ExitPeerResponse, error)
 	RequestExit(context.Context, *ExitRequest) (*WireguardConfig, error)
+	AdvertiseExit(context.Context, *ExitAdvertisement) (*Ack, error)
+	WithdrawExit(context.Context, *ExitWithdrawal) (*Ack, error)
+	ReleaseExit(context.Context, *ReleaseExitRequest) (*Ack, error)
+	EndSession(context.Context, *EndSessionRequest) (*Ack, error)
+	AdmitClient(context.Context, *ExitTicket) (*Ack, error)
 	mustEmbedUnimplementedSuperNodeServiceServer()
 }
 
@@ -113,6 +178,21 @@ func (UnimplementedSuperNodeServiceServer) RequestExitPeer(context.Context, *Exi
 func (UnimplementedSuperNodeServiceServer) RequestExit(context.Context, *ExitRequest) (*WireguardConfig, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RequestExit not implemented")
 }
+func (UnimplementedSuperNodeServiceServer) AdvertiseExit(context.Context, *ExitAdvertisement) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdvertiseExit not implemented")
+}
+func (UnimplementedSuperNodeServiceServer) WithdrawExit(context.Context, *ExitWithdrawal) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method WithdrawExit not implemented")
+}
+func (UnimplementedSuperNodeServiceServer) ReleaseExit(context.Context, *ReleaseExitRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseExit not implemented")
+}
+func (UnimplementedSuperNodeServiceServer) EndSession(context.Context, *EndSessionRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EndSession not implemented")
+}
+func (UnimplementedSuperNodeServiceServer) AdmitClient(context.Context, *ExitTicket) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdmitClient not implemented")
+}
 func (UnimplementedSuperNodeServiceServer) mustEmbedUnimplementedSuperNodeServiceServer() {}
 func (UnimplementedSuperNodeServiceServer) testEmbeddedByValue()                          {}
 
@@ -206,6 +286,96 @@ func _SuperNodeService_RequestExit_Handler(srv interface{}, ctx context.Context,
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SuperNodeService_AdvertiseExit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExitAdvertisement)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuperNodeServiceServer).AdvertiseExit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SuperNodeService_AdvertiseExit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuperNodeServiceServer).AdvertiseExit(ctx, req.(*ExitAdvertisement))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SuperNodeService_WithdrawExit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExitWithdrawal)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuperNodeServiceServer).WithdrawExit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SuperNodeService_WithdrawExit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuperNodeServiceServer).WithdrawExit(ctx, req.(*ExitWithdrawal))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SuperNodeService_ReleaseExit_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseExitRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuperNodeServiceServer).ReleaseExit(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SuperNodeService_ReleaseExit_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuperNodeServiceServer).ReleaseExit(ctx, req.(*ReleaseExitRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SuperNodeService_EndSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EndSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuperNodeServiceServer).EndSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SuperNodeService_EndSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuperNodeServiceServer).EndSession(ctx, req.(*EndSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SuperNodeService_AdmitClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExitTicket)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SuperNodeServiceServer).AdmitClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SuperNodeService_AdmitClient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SuperNodeServiceServer).AdmitClient(ctx, req.(*ExitTicket))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // SuperNodeService_ServiceDesc is the grpc.ServiceDesc for SuperNodeService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -229,6 +399,26 @@ var SuperNodeService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RequestExit",
 			Handler:    _SuperNodeService_RequestExit_Handler,
 		},
+		{
+			MethodName: "AdvertiseExit",
+			Handler:    _SuperNodeService_AdvertiseExit_Handler,
+		},
+		{
+			MethodName: "WithdrawExit",
+			Handler:    _SuperNodeService_WithdrawExit_Handler,
+		},
+		{
+			MethodName: "ReleaseExit",
+			Handler:    _SuperNodeService_ReleaseExit_Handler,
+		},
+		{
+			MethodName: "EndSession",
+			Handler:    _SuperNodeService_EndSession_Handler,
+		},
+		{
+			MethodName: "AdmitClient",
+			Handler:    _SuperNodeService_AdmitClient_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "super_node.proto",
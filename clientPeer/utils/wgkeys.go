@@ -8,13 +8,27 @@ import (
 	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
 )
 
+// The exit interface and the client tunnel use separate keys so one node
+// can run both roles.
 const (
-	wgPrivPath = ".keys/wg_private.key"
-	wgPubPath  = ".keys/wg_public.key"
+	wgPrivPath       = ".keys/wg_private.key"
+	wgPubPath        = ".keys/wg_public.key"
+	wgClientPrivPath = ".keys/wg_client_private.key"
+	wgClientPubPath  = ".keys/wg_client_public.key"
 )
 
+// LoadOrCreateWGKeypair returns the exit interface's WireGuard keys.
 func LoadOrCreateWGKeypair() (wgtypes.Key, wgtypes.Key, error) {
-	if _, err := os.Stat(wgPrivPath); os.IsNotExist(err) {
+	return loadOrCreateWGKeypair(wgPrivPath, wgPubPath)
+}
+
+// LoadOrCreateClientWGKeypair returns the client tunnel's WireGuard keys.
+func LoadOrCreateClientWGKeypair() (wgtypes.Key, wgtypes.Key, error) {
+	return loadOrCreateWGKeypair(wgClientPrivPath, wgClientPubPath)
+}
+
+func loadOrCreateWGKeypair(privPath, pubPath string) (wgtypes.Key, wgtypes.Key, error) {
+	if _, err := os.Stat(privPath); os.IsNotExist(err) {
 		priv, err := wgtypes.GeneratePrivateKey()
 		if err != nil {
 			return wgtypes.Key{}, wgtypes.Key{}, err
@@ -22,14 +36,14 @@ func LoadOrCreateWGKeypair() (wgtypes.Key, wgtypes.Key, error) {
 		pub := priv.PublicKey()
 
 		_ = os.MkdirAll(".keys", 0700)
-		_ = os.WriteFile(wgPrivPath, []byte(priv.String()), 0600)
-		_ = os.WriteFile(wgPubPath, []byte(pub.String()), 0644)
+		_ = os.WriteFile(privPath, []byte(priv.String()), 0600)
+		_ = os.WriteFile(pubPath, []byte(pub.String()), 0644)
 
 		return priv, pub, nil
 	}
 
-	privBytes, _ := os.ReadFile(wgPrivPath)
-	pubBytes, _ := os.ReadFile(wgPubPath)
+	privBytes, _ := os.ReadFile(privPath)
+	pubBytes, _ := os.ReadFile(pubPath)
 
 	priv, _ := wgtypes.ParseKey(string(privBytes))
 	pub, _ := wgtypes.ParseKey(string(pubBytes))
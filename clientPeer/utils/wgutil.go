@@ -224,6 +224,27 @@ func DebugWGStatus(iface string) error {
 	return nil
 }
 
+// TransferBytes returns the bytes received and sent by all peers of the
+// WireGuard interface iface.
+func TransferBytes(iface string) (uint64, error) {
+	client, err := wgctrl.New()
+	if err != nil {
+		return 0, err
+	}
+	defer client.Close()
+
+	device, err := client.Device(iface)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get device %s: %v", iface, err)
+	}
+
+	var total uint64
+	for _, peer := range device.Peers {
+		total += uint64(peer.ReceiveBytes + peer.TransmitBytes)
+	}
+	return total, nil
+}
+
 // SetupForwardRules adds iptables FORWARD rules for WireGuard traffic
 func SetupForwardRules(wgInterface, outInterface string) error {
 	// Allow traffic from WireGuard interface to outbound interface
@@ -253,6 +274,38 @@ func SetupForwardRules(wgInterface, outInterface string) error {
 	return nil
 }
 
+// SetupShaping puts an HTB root qdisc on iface, under which traffic to each
+// client is limited by ShapePeer. Unshaped traffic is not limited.
+func SetupShaping(iface string) error {
+	if err := RunCmd("tc", "qdisc", "replace", "dev", iface, "root", "handle", "1:", "htb"); err != nil {
+		return fmt.Errorf("failed to set up traffic shaping on %s: %v", iface, err)
+	}
+	return nil
+}
+
+// ShapePeer limits traffic sent to clientIP on iface to mbps, under HTB
+// class id (unique per client, at least 1).
+func ShapePeer(iface string, id int, clientIP string, mbps float32) error {
+	classID := fmt.Sprintf("1:%x", id)
+	rate := fmt.Sprintf("%dkbit", int(mbps*1000))
+	if err := RunCmd("tc", "class", "replace", "dev", iface, "parent", "1:", "classid", classID,
+		"htb", "rate", rate, "ceil", rate); err != nil {
+		return fmt.Errorf("failed to shape %s to %s: %v", clientIP, rate, err)
+	}
+	if err := RunCmd("tc", "filter", "replace", "dev", iface, "parent", "1:", "protocol", "ip", "prio", "1",
+		"handle", fmt.Sprintf("800::%x", id), "u32", "match", "ip", "dst", clientIP, "flowid", classID); err != nil {
+		return fmt.Errorf("failed to classify traffic to %s: %v", clientIP, err)
+	}
+	return nil
+}
+
+// UnshapePeer removes the limit ShapePeer set under class id.
+func UnshapePeer(iface string, id int) error {
+	_ = RunCmd("tc", "filter", "del", "dev", iface, "parent", "1:", "protocol", "ip", "prio", "1",
+		"handle", fmt.Sprintf("800::%x", id), "u32")
+	return RunCmd("tc", "class", "del", "dev", iface, "classid", fmt.Sprintf("1:%x", id))
+}
+
 // GetOutboundInterface detects the interface used for outbound internet traffic
 func GetOutboundInterface() (string, error) {
 	// Use ip route to find the default gateway interface
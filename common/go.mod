@@ -0,0 +1,3 @@
+module Dvpn_common
+
+go 1.23.0
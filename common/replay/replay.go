@@ -0,0 +1,132 @@
+// Package replay rejects replayed or stale signed requests. It is shared by
+// the base and super nodes.
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxClockSkew is how far a signed timestamp may be from our clock.
+	MaxClockSkew = 2 * time.Minute
+	// NoncesPerID bounds the nonces remembered for one signer. A signer
+	// that exceeds it only locks itself out until its nonces expire.
+	NoncesPerID = 64
+	// MaxSigners bounds the signers remembered at once. Beyond it the
+	// signer with the oldest remembered nonce is forgotten.
+	MaxSigners = 4096
+)
+
+var (
+	ErrStaleTimestamp  = errors.New("signed timestamp outside allowed clock skew")
+	ErrReplayedNonce   = errors.New("nonce already used")
+	ErrReplayCacheFull = errors.New("too many recent signed requests")
+)
+
+type seenNonce struct {
+	id    string
+	nonce string
+	at    time.Time
+}
+
+// Guard rejects signed requests whose timestamp is outside the
+// allowed clock skew or whose nonce was already seen. Nonces only need to
+// be remembered for twice the skew window, since anything older is
+// rejected by its timestamp.
+//
+// Nonces are bounded per signer rather than in total, so one signer cannot
+// crowd out the others. The number of signers is bounded too; forgetting
+// the oldest one lets its recent requests be replayed, so the bound should
+// sit well above the signers that are active at once. Callers check a
+// request only after its signer's identity is verified, and keep separate
+// guards for separate kinds of signer.
+type Guard struct {
+	mu     sync.Mutex
+	window time.Duration
+	perID  int
+	maxIDs int
+	seen   map[string]map[string]time.Time
+	order  []seenNonce
+}
+
+// NewGuard returns a guard accepting timestamps within window of our clock
+// and remembering up to perID nonces for each of up to maxIDs signers.
+func NewGuard(window time.Duration, perID, maxIDs int) *Guard {
+	return &Guard{
+		window: window,
+		perID:  perID,
+		maxIDs: maxIDs,
+		seen:   make(map[string]map[string]time.Time),
+	}
+}
+
+// Check records (id, nonce) and returns an error if the request is stale or
+// a replay. When id already has perID nonces that could still be replayed
+// the request is rejected rather than forgetting one of them.
+func (g *Guard) Check(id, nonce string, signedAt int64) error {
+	return g.check(id, nonce, signedAt, time.Now())
+}
+
+func (g *Guard) check(id, nonce string, signedAt int64, now time.Time) error {
+	ts := time.Unix(signedAt, 0)
+
+	skew := now.Sub(ts)
+	if skew < 0 {
+		skew = -skew
+	}
+	if signedAt == 0 || skew > g.window {
+		return fmt.Errorf("%w: signed at %s, skew %s exceeds %s", ErrStaleTimestamp, ts.UTC().Format(time.RFC3339), skew.Round(time.Second), g.window)
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.expire(now)
+
+	nonces := g.seen[id]
+	if _, ok := nonces[nonce]; ok {
+		return fmt.Errorf("%w: %s", ErrReplayedNonce, nonce)
+	}
+	if len(nonces) >= g.perID {
+		return fmt.Errorf("%w from %s", ErrReplayCacheFull, id)
+	}
+
+	if nonces == nil {
+		if len(g.seen) >= g.maxIDs {
+			g.forget(g.order[0].id)
+		}
+		nonces = make(map[string]time.Time)
+		g.seen[id] = nonces
+	}
+	nonces[nonce] = now
+	g.order = append(g.order, seenNonce{id: id, nonce: nonce, at: now})
+	return nil
+}
+
+func (g *Guard) expire(now time.Time) {
+	i := 0
+	for i < len(g.order) && now.Sub(g.order[i].at) > 2*g.window {
+		e := g.order[i]
+		delete(g.seen[e.id], e.nonce)
+		if len(g.seen[e.id]) == 0 {
+			delete(g.seen, e.id)
+		}
+		i++
+	}
+	g.order = g.order[i:]
+}
+
+// forget drops every nonce of id.
+func (g *Guard) forget(id string) {
+	delete(g.seen, id)
+	kept := g.order[:0]
+	for _, e := range g.order {
+		if e.id != id {
+			kept = append(kept, e)
+		}
+	}
+	g.order = kept
+}
@@ -0,0 +1,117 @@
+package replay
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestGuardRejectsReplayAndStale(t *testing.T) {
+	g := NewGuard(time.Minute, 10, MaxSigners)
+	now := time.Now()
+
+	if err := g.check("node", "n1", now.Unix(), now); err != nil {
+		t.Fatalf("fresh request rejected: %v", err)
+	}
+	if err := g.check("node", "n1", now.Unix(), now); !errors.Is(err, ErrReplayedNonce) {
+		t.Fatalf("replay: got %v, want %v", err, ErrReplayedNonce)
+	}
+	if err := g.check("other", "n1", now.Unix(), now); err != nil {
+		t.Fatalf("same nonce from another node rejected: %v", err)
+	}
+	if err := g.check("node", "n2", now.Add(-2*time.Minute).Unix(), now); !errors.Is(err, ErrStaleTimestamp) {
+		t.Fatalf("stale: got %v, want %v", err, ErrStaleTimestamp)
+	}
+	if err := g.check("node", "n3", 0, now); !errors.Is(err, ErrStaleTimestamp) {
+		t.Fatalf("zero timestamp: got %v, want %v", err, ErrStaleTimestamp)
+	}
+}
+
+func TestGuardBoundsNoncesPerSigner(t *testing.T) {
+	const perID = 5
+	g := NewGuard(time.Minute, perID, MaxSigners)
+	now := time.Now()
+
+	if err := g.check("node", "captured", now.Unix(), now); err != nil {
+		t.Fatalf("fresh request rejected: %v", err)
+	}
+	// Flooding one signer's nonces must not push out the captured nonce
+	for i := 0; i < perID-1; i++ {
+		if err := g.check("node", fmt.Sprintf("junk-%d", i), now.Unix(), now); err != nil {
+			t.Fatalf("junk %d rejected: %v", i, err)
+		}
+	}
+	if err := g.check("node", "junk-extra", now.Unix(), now); !errors.Is(err, ErrReplayCacheFull) {
+		t.Fatalf("full signer: got %v, want %v", err, ErrReplayCacheFull)
+	}
+	if err := g.check("node", "captured", now.Unix(), now); !errors.Is(err, ErrReplayedNonce) {
+		t.Fatalf("replay after flood: got %v, want %v", err, ErrReplayedNonce)
+	}
+}
+
+func TestGuardFloodDoesNotLockOutOthers(t *testing.T) {
+	g := NewGuard(time.Minute, 2, MaxSigners)
+	now := time.Now()
+
+	// Many signers, each with fresh keys, fill nothing but their own slots
+	for i := 0; i < 1000; i++ {
+		id := fmt.Sprintf("fresh-%d", i)
+		for j := 0; j < 3; j++ {
+			g.check(id, fmt.Sprintf("n%d", j), now.Unix(), now)
+		}
+	}
+	if err := g.check("honest", "n0", now.Unix(), now); err != nil {
+		t.Fatalf("honest signer locked out: %v", err)
+	}
+}
+
+func TestGuardFullSignerRecoversAfterExpiry(t *testing.T) {
+	const perID = 3
+	g := NewGuard(time.Minute, perID, MaxSigners)
+	start := time.Now()
+
+	for i := 0; i < perID; i++ {
+		if err := g.check("node", fmt.Sprintf("old-%d", i), start.Unix(), start); err != nil {
+			t.Fatalf("request %d rejected: %v", i, err)
+		}
+	}
+
+	// Once the old nonces are past twice the window they can no longer be
+	// replayed, so they make room
+	later := start.Add(2*time.Minute + time.Second)
+	if err := g.check("node", "new", later.Unix(), later); err != nil {
+		t.Fatalf("request after expiry rejected: %v", err)
+	}
+	if len(g.seen) != 1 || len(g.order) != 1 {
+		t.Fatalf("expired nonces kept: %d signers, %d nonces", len(g.seen), len(g.order))
+	}
+}
+
+func TestGuardBoundsSigners(t *testing.T) {
+	const maxIDs = 3
+	g := NewGuard(time.Minute, 2, maxIDs)
+	now := time.Now()
+
+	for i := 0; i < maxIDs; i++ {
+		at := now.Add(time.Duration(i) * time.Second)
+		if err := g.check(fmt.Sprintf("node-%d", i), "n0", at.Unix(), at); err != nil {
+			t.Fatalf("node-%d rejected: %v", i, err)
+		}
+	}
+
+	// A new signer pushes out the one with the oldest nonce, not the others
+	later := now.Add(maxIDs * time.Second)
+	if err := g.check("new", "n0", later.Unix(), later); err != nil {
+		t.Fatalf("new signer rejected: %v", err)
+	}
+	if len(g.seen) != maxIDs || len(g.order) != maxIDs {
+		t.Fatalf("guard holds %d signers, %d nonces, want %d of each", len(g.seen), len(g.order), maxIDs)
+	}
+	if _, ok := g.seen["node-0"]; ok {
+		t.Fatal("oldest signer was kept")
+	}
+	if err := g.check("node-1", "n0", later.Unix(), later); !errors.Is(err, ErrReplayedNonce) {
+		t.Fatalf("replay of a remembered signer: got %v, want %v", err, ErrReplayedNonce)
+	}
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"Super_node/utils"
+	"context"
+	"fmt"
+	"log"
+	"sync"
+
+	"google.golang.org/grpc"
+)
+
+// BaseLink is a connection to one of an ordered list of base node
+// endpoints. It implements grpc.ClientConnInterface, so gRPC clients built on
+// it keep working after Failover moves it to the next endpoint.
+type BaseLink struct {
+	mu        sync.Mutex
+	endpoints []string
+	idx       int
+	conn      *grpc.ClientConn
+}
+
+// NewBaseLink connects to the first endpoint.
+func NewBaseLink(endpoints []string) (*BaseLink, error) {
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("no base node endpoints configured")
+	}
+	conn, err := grpc.Dial(endpoints[0], utils.DialOption(utils.RoleBase))
+	if err != nil {
+		return nil, err
+	}
+	return &BaseLink{endpoints: endpoints, conn: conn}, nil
+}
+
+func (l *BaseLink) current() *grpc.ClientConn {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.conn
+}
+
+// Endpoint returns the address currently in use.
+func (l *BaseLink) Endpoint() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.endpoints[l.idx]
+}
+
+// Failover moves to the next endpoint in the list, wrapping around after the
+// last one.
+func (l *BaseLink) Failover() error {
+	l.mu.Lock()
+	next := (l.idx + 1) % len(l.endpoints)
+	l.mu.Unlock()
+
+	return l.switchTo(next)
+}
+
+// Rewind moves back to the first endpoint, so a node that failed over
+// returns to its primary base once that is reachable again. A link already
+// on the first endpoint moves to the second instead.
+func (l *BaseLink) Rewind() error {
+	l.mu.Lock()
+	onFirst := l.idx == 0
+	l.mu.Unlock()
+
+	if onFirst {
+		return l.Failover()
+	}
+	return l.switchTo(0)
+}
+
+func (l *BaseLink) switchTo(next int) error {
+	l.mu.Lock()
+	addr := l.endpoints[next]
+	l.mu.Unlock()
+
+	conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleBase))
+	if err != nil {
+		return fmt.Errorf("failed to dial base node %s: %w", addr, err)
+	}
+
+	l.mu.Lock()
+	old := l.conn
+	l.conn = conn
+	l.idx = next
+	l.mu.Unlock()
+
+	old.Close()
+	log.Printf("🔀 Switched to base node %s", addr)
+	return nil
+}
+
+func (l *BaseLink) Close() error {
+	return l.current().Close()
+}
+
+func (l *BaseLink) Invoke(ctx context.Context, method string, args, reply any, opts ...grpc.CallOption) error {
+	return l.current().Invoke(ctx, method, args, reply, opts...)
+}
+
+func (l *BaseLink) NewStream(ctx context.Context, desc *grpc.StreamDesc, method string, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	return l.current().NewStream(ctx, desc, method, opts...)
+}
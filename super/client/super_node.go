@@ -2,9 +2,11 @@ package client
 
 import (
 	super "Super_node/crypto"
+	"Super_node/metrics"
 	"Super_node/pb"
 	"Super_node/utils"
 	"context"
+	"crypto/ed25519"
 	"encoding/base64"
 	"fmt"
 	"log"
@@ -13,22 +15,47 @@ import (
 	"google.golang.org/grpc"
 )
 
+const (
+	// unknownNodeMessage is the ack a base sends for a node it has no
+	// record of, e.g. after the base restarted with an empty registry.
+	unknownNodeMessage = "Super Node not found"
+
+	joinRetryMin = time.Second
+	joinRetryMax = 30 * time.Second
+
+	// heartbeatAttempts is how many heartbeats in a row must fail, retried
+	// with backoff from heartbeatRetryMin, before the node leaves its base.
+	heartbeatAttempts = 3
+	heartbeatRetryMin = 2 * time.Second
+)
+
 type SuperNode struct {
-	client pb.BaseNodeServiceClient
-	id     string
-	port   string
-	region string
+	link     *BaseLink
+	client   pb.BaseNodeServiceClient
+	id       string
+	port     string
+	region   string
+	maxPeers int
+	metrics  metrics.Source
+	signKey  ed25519.PrivateKey
 }
 
-func NewSupreNode(conn *grpc.ClientConn, id string, port string, region string) *SuperNode {
+func NewSupreNode(link *BaseLink, id string, port string, region string, maxPeers int) *SuperNode {
 	return &SuperNode{
-		client: pb.NewBaseNodeServiceClient(conn),
-		id:     id,
-		port:   port,
-		region: region,
+		link:     link,
+		client:   pb.NewBaseNodeServiceClient(link),
+		id:       id,
+		port:     port,
+		region:   region,
+		maxPeers: maxPeers,
 	}
 }
 
+// SetMetricsSource sets where heartbeats read the node's load from.
+func (s *SuperNode) SetMetricsSource(src metrics.Source) {
+	s.metrics = src
+}
+
 func (s *SuperNode) Register() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
@@ -37,11 +64,13 @@ func (s *SuperNode) Register() error {
 	if err != nil {
 		log.Fatalf("❌ Failed to load/create keypair: %v", err)
 	}
+	s.signKey = priv
 
 	nonce := super.GenerateNonce()
 	ip := utils.GetLocalIP()
 	fmt.Println(nonce)
-	sign := super.SignPayload(priv, s.id, s.region, ip, nonce)
+	signedAt := time.Now().Unix()
+	sign := super.SignPayload(priv, s.id, s.region, ip, nonce, signedAt)
 
 	req := &pb.RegisterRequest{
 		NodeId:      s.id,
@@ -51,7 +80,8 @@ func (s *SuperNode) Register() error {
 		PublicKey:   base64.StdEncoding.EncodeToString(pub),
 		Signature:   sign,
 		Nonce:       nonce,
-		MaxPeers:    100,
+		SignedAt:    signedAt,
+		MaxPeers:    int32(s.maxPeers),
 		Version:     "0.1",
 		StartupTime: time.Now().Format(time.RFC3339),
 	}
@@ -70,26 +100,50 @@ func (s *SuperNode) Register() error {
 	return nil
 }
 
+// Join registers with the base, failing over through the configured base
+// endpoints with backoff until one accepts the node.
+func (s *SuperNode) Join() {
+	retry := joinRetryMin
+	for {
+		err := s.Register()
+		if err == nil {
+			return
+		}
+		log.Printf("⚠️ Registration with base node %s failed: %v (retrying in %s)", s.link.Endpoint(), err, retry)
+
+		time.Sleep(retry)
+		retry *= 2
+		if retry > joinRetryMax {
+			retry = joinRetryMax
+		}
+		if err := s.link.Failover(); err != nil {
+			log.Printf("⚠️ %v", err)
+		}
+	}
+}
+
+// StartHeartbeat sends a heartbeat every 30 seconds. A failed heartbeat is
+// retried on the same base with backoff; only after heartbeatAttempts
+// failures in a row does the node rejoin, trying the base endpoints in order
+// from the first.
 func (s *SuperNode) StartHeartbeat() {
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-
-		req := &pb.HeartbeatRequest{
-			NodeId:             s.id,
-			ActivePeers:        80,
-			AvgLatencyMs:       57,
-			ExitPeersAvailable: 10,
-			BandwidthUsageMbps: 72.6,
-			Timestamp:          time.Now().Format(time.RFC3339),
+		res, err := s.heartbeatWithRetry()
+		if err != nil {
+			log.Printf("The base node went down. %d heartbeats failed, last: %v", heartbeatAttempts, err)
+			if err := s.link.Rewind(); err != nil {
+				log.Printf("⚠️ %v", err)
+			}
+			s.Join()
+			continue
 		}
 
-		res, err := s.client.SuperNodeHeartbeat(ctx, req)
-		cancel()
-		if err != nil {
-			log.Printf("The base node went down. Heartbeat failed: %v", err)
+		if !res.Received && res.Message == unknownNodeMessage {
+			log.Printf("🔄 Base node %s does not know us, registering again", s.link.Endpoint())
+			s.Join()
 			continue
 		}
 
@@ -97,6 +151,47 @@ func (s *SuperNode) StartHeartbeat() {
 	}
 }
 
+func (s *SuperNode) heartbeatWithRetry() (*pb.Ack, error) {
+	retry := heartbeatRetryMin
+	for attempt := 1; ; attempt++ {
+		res, err := s.heartbeat()
+		if err == nil || attempt == heartbeatAttempts {
+			return res, err
+		}
+		log.Printf("⚠️ Heartbeat to base node %s failed (%d/%d): %v (retrying in %s)",
+			s.link.Endpoint(), attempt, heartbeatAttempts, err, retry)
+
+		time.Sleep(retry)
+		retry *= 2
+	}
+}
+
+func (s *SuperNode) heartbeat() (*pb.Ack, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var load metrics.Load
+	if s.metrics != nil {
+		load = s.metrics.Load()
+	}
+
+	req := &pb.HeartbeatRequest{
+		NodeId:             s.id,
+		ActivePeers:        load.ActivePeers,
+		AvgLatencyMs:       load.AvgLatencyMs,
+		ExitPeersAvailable: load.ExitPeersAvailable,
+		BandwidthUsageMbps: load.BandwidthMbps,
+		ExitBandwidthMbps:  load.ExitBandwidthMbps,
+		CpuPercent:         load.CPUPercent,
+		MemoryMb:           load.MemoryMB,
+		Timestamp:          time.Now().Format(time.RFC3339),
+		SignedAt:           time.Now().Unix(),
+	}
+	req.Signature = super.SignHeartbeat(s.signKey, req.NodeId, req.ActivePeers, req.ExitPeersAvailable,
+		req.AvgLatencyMs, req.BandwidthUsageMbps, req.ExitBandwidthMbps, req.CpuPercent, req.MemoryMb, req.SignedAt)
+	return s.client.SuperNodeHeartbeat(ctx, req)
+}
+
 func (s *SuperNode) RequestExitCandidates(region string, minBandwidth float32, maxLatency float32, count int32) ([]*pb.SuperNode, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
@@ -120,7 +215,7 @@ func (s *SuperNode) RequestExitCandidates(region string, minBandwidth float32, m
 func RequestExitPeerFromRemote(ip, port string, req *pb.ExitPeerRequest) (*pb.ExitPeerResponse, error) {
 	addr := fmt.Sprintf("%s:%s", ip, port)
 
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleSuper))
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial remote super node: %w", err)
 	}
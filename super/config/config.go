@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+)
+
+// envPrefix starts every environment override, e.g. DVPN_SUPER_REGION.
+const envPrefix = "DVPN_SUPER"
+
+// Config holds every setting of a super node.
+type Config struct {
+	Region   string      `yaml:"region"`
+	PeerPort string      `yaml:"peer_port"`
+	MaxPeers int         `yaml:"max_peers"`
+	Peers    PeersConfig `yaml:"peers"`
+	Base     BaseConfig  `yaml:"base"`
+	TLS      TLSConfig   `yaml:"tls"`
+}
+
+// PeersConfig sets how long a client peer may go without a session
+// heartbeat before it is stale (no longer chosen as an exit) and dead
+// (evicted).
+type PeersConfig struct {
+	StaleTTL Duration `yaml:"stale_ttl"`
+	DeadTTL  Duration `yaml:"dead_ttl"`
+}
+
+// BaseConfig locates the base nodes of this super's region. Endpoints is an
+// ordered list of "ip:port" addresses tried in turn; when it is empty the
+// single base at IP is used. Port wins when set; otherwise the port is looked
+// up by region in RegionPorts, falling back to DefaultPort.
+type BaseConfig struct {
+	Endpoints   List           `yaml:"endpoints"`
+	IP          string         `yaml:"ip"`
+	Port        int            `yaml:"port"`
+	RegionPorts map[string]int `yaml:"region_ports"`
+	DefaultPort int            `yaml:"default_port"`
+}
+
+type TLSConfig struct {
+	CA       string `yaml:"ca"`
+	Cert     string `yaml:"cert"`
+	Insecure bool   `yaml:"insecure"`
+}
+
+// Default returns the settings used when nothing else is configured.
+func Default() Config {
+	return Config{
+		Region:   "IN",
+		PeerPort: "50052",
+		MaxPeers: 100,
+		Peers: PeersConfig{
+			StaleTTL: Duration(2 * time.Minute),
+			DeadTTL:  Duration(10 * time.Minute),
+		},
+		Base: BaseConfig{
+			IP:          "127.0.0.1",
+			RegionPorts: map[string]int{"IN": 50051, "US": 50053},
+			DefaultPort: 50051,
+		},
+		TLS: TLSConfig{
+			CA:   ".keys/ca.crt",
+			Cert: ".keys/node.crt",
+		},
+	}
+}
+
+// Validate reports the first setting that cannot work.
+func (c *Config) Validate() error {
+	if c.Region == "" {
+		return fmt.Errorf("region is required")
+	}
+	if err := validPort(c.PeerPort); err != nil {
+		return fmt.Errorf("peer_port: %w", err)
+	}
+	if c.MaxPeers < 1 {
+		return fmt.Errorf("max_peers must be at least 1, got %d", c.MaxPeers)
+	}
+	if c.Peers.StaleTTL <= 0 || c.Peers.DeadTTL <= c.Peers.StaleTTL {
+		return fmt.Errorf("peers: need 0 < stale_ttl < dead_ttl, got %s and %s", c.Peers.StaleTTL, c.Peers.DeadTTL)
+	}
+	for _, ep := range c.Base.Endpoints {
+		if _, port, err := net.SplitHostPort(ep); err != nil || validPort(port) != nil {
+			return fmt.Errorf("base.endpoints: %q is not a valid ip:port", ep)
+		}
+	}
+	if len(c.Base.Endpoints) == 0 && c.Base.IP == "" {
+		return fmt.Errorf("base.ip or base.endpoints is required")
+	}
+	if err := validPort(strconv.Itoa(c.Base.BasePort(c.Region))); err != nil {
+		return fmt.Errorf("base: %w", err)
+	}
+	if !c.TLS.Insecure && (c.TLS.CA == "" || c.TLS.Cert == "") {
+		return fmt.Errorf("tls: ca and cert are required unless insecure is set")
+	}
+	return nil
+}
+
+// Addresses returns the base node endpoints to use for region, in order.
+func (b BaseConfig) Addresses(region string) []string {
+	if len(b.Endpoints) > 0 {
+		return b.Endpoints
+	}
+	return []string{net.JoinHostPort(b.IP, strconv.Itoa(b.BasePort(region)))}
+}
+
+// BasePort returns the base node port to dial for region.
+func (b BaseConfig) BasePort(region string) int {
+	if b.Port != 0 {
+		return b.Port
+	}
+	if p := b.RegionPorts[region]; p != 0 {
+		return p
+	}
+	return b.DefaultPort
+}
+
+func validPort(s string) error {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("%q is not a valid port", s)
+	}
+	return nil
+}
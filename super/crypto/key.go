@@ -3,7 +3,9 @@ package super
 import (
 	"crypto/ed25519"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -64,3 +66,9 @@ func LoadOrCreateKeypair() (ed25519.PrivateKey, ed25519.PublicKey, error) {
 	return ed25519.PrivateKey(privDecoded), ed25519.PublicKey(pubDecoded), nil
 }
 
+// DeriveNodeID returns the super node ID for pub in region. Base nodes only
+// accept an ID that matches the registering key.
+func DeriveNodeID(region string, pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return fmt.Sprintf("super-%s-%s", region, hex.EncodeToString(sum[:10]))
+}
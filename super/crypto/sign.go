@@ -7,8 +7,10 @@ import (
 	"fmt"
 )
 
-func SignPayload(priv ed25519.PrivateKey, id, region, ip, nonce string) (string) {
-	msg := fmt.Sprintf("%s|%s|%s|%s", id, region, ip, nonce)
+// SignPayload signs id|region|ip|nonce|signedAt. The timestamp lets the base
+// node reject stale or replayed registrations.
+func SignPayload(priv ed25519.PrivateKey, id, region, ip, nonce string, signedAt int64) string {
+	msg := fmt.Sprintf("%s|%s|%s|%s|%d", id, region, ip, nonce, signedAt)
 	sign := ed25519.Sign(priv, []byte(msg))
 	return base64.StdEncoding.EncodeToString(sign)
 }
@@ -20,4 +22,43 @@ func GenerateNonce() string {
 		panic("Failed to generate nonce: " + err.Error())
 	}
 	return base64.StdEncoding.EncodeToString(b)
-}
\ No newline at end of file
+}
+
+// SignExitRequest signs the fields of a super to super exit request, so the
+// receiving super can check which super is asking and for whom.
+func SignExitRequest(priv ed25519.PrivateKey, callerID, requesterID, requesterRegion, requestedRegion, clientPublicKey, sessionID, nonce string, signedAt int64) string {
+	msg := ExitRequestPayload(callerID, requesterID, requesterRegion, requestedRegion, clientPublicKey, sessionID, nonce, signedAt)
+	sign := ed25519.Sign(priv, []byte(msg))
+	return base64.StdEncoding.EncodeToString(sign)
+}
+
+// ExitRequestPayload is the message signed by SignExitRequest.
+func ExitRequestPayload(callerID, requesterID, requesterRegion, requestedRegion, clientPublicKey, sessionID, nonce string, signedAt int64) string {
+	return fmt.Sprintf("%s|%s|%s|%s|%s|%s|%s|%d", callerID, requesterID, requesterRegion, requestedRegion, clientPublicKey, sessionID, nonce, signedAt)
+}
+
+// SignEndSession signs the fields of a super to super request to end an
+// exit session, so only the super that opened the session can end it.
+func SignEndSession(priv ed25519.PrivateKey, callerID, sessionID, requesterID, reason, nonce string, signedAt int64) string {
+	msg := EndSessionPayload(callerID, sessionID, requesterID, reason, nonce, signedAt)
+	sign := ed25519.Sign(priv, []byte(msg))
+	return base64.StdEncoding.EncodeToString(sign)
+}
+
+// EndSessionPayload is the message signed by SignEndSession.
+func EndSessionPayload(callerID, sessionID, requesterID, reason, nonce string, signedAt int64) string {
+	return fmt.Sprintf("end|%s|%s|%s|%s|%s|%d", callerID, sessionID, requesterID, reason, nonce, signedAt)
+}
+
+// SignHeartbeat signs the fields of a heartbeat, so a base relaying it to
+// its replication leader cannot forge the node's liveness or load.
+func SignHeartbeat(priv ed25519.PrivateKey, nodeID string, activePeers, exitPeers int32, latencyMs, bandwidthMbps, exitBandwidthMbps, cpuPercent, memoryMB float32, signedAt int64) string {
+	msg := HeartbeatPayload(nodeID, activePeers, exitPeers, latencyMs, bandwidthMbps, exitBandwidthMbps, cpuPercent, memoryMB, signedAt)
+	sign := ed25519.Sign(priv, []byte(msg))
+	return base64.StdEncoding.EncodeToString(sign)
+}
+
+// HeartbeatPayload is the message signed by SignHeartbeat.
+func HeartbeatPayload(nodeID string, activePeers, exitPeers int32, latencyMs, bandwidthMbps, exitBandwidthMbps, cpuPercent, memoryMB float32, signedAt int64) string {
+	return fmt.Sprintf("heartbeat|%s|%d|%d|%g|%g|%g|%g|%g|%d", nodeID, activePeers, exitPeers, latencyMs, bandwidthMbps, exitBandwidthMbps, cpuPercent, memoryMB, signedAt)
+}
@@ -0,0 +1,62 @@
+package super
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+)
+
+func TestExitTicketSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubB64 := base64.StdEncoding.EncodeToString(pub)
+
+	payload := ExitTicketPayload("s1", "peer-IN-a", "key", "peer-US-b", 1700000000, 10, "super-IN-c", "IN")
+	sig := SignExitTicket(priv, payload)
+	if !VerifyExitTicket(pubB64, payload, sig) {
+		t.Fatal("valid ticket rejected")
+	}
+
+	tampered := ExitTicketPayload("s1", "peer-IN-a", "key", "peer-US-b", 1700003600, 10, "super-IN-c", "IN")
+	if VerifyExitTicket(pubB64, tampered, sig) {
+		t.Fatal("ticket with changed expiry accepted")
+	}
+	tampered = ExitTicketPayload("s1", "peer-IN-a", "key", "peer-US-b", 1700000000, 100, "super-IN-c", "IN")
+	if VerifyExitTicket(pubB64, tampered, sig) {
+		t.Fatal("ticket with changed bandwidth accepted")
+	}
+
+	other, _, _ := ed25519.GenerateKey(rand.Reader)
+	if VerifyExitTicket(base64.StdEncoding.EncodeToString(other), payload, sig) {
+		t.Fatal("ticket accepted under another key")
+	}
+	if VerifyExitTicket("not base64", payload, sig) {
+		t.Fatal("malformed key accepted")
+	}
+}
+
+func TestKeyPayloadSignature(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubB64 := base64.StdEncoding.EncodeToString(pub)
+
+	payload := ExitKeyPayload("peer-US-b", "wgkey", "1.2.3.4", "51820", "10.100.0.2/32", "s1", "clientkey")
+	sig := SignKeyPayload(priv, payload)
+	if !VerifyKeyPayload(pubB64, payload, sig) {
+		t.Fatal("valid exit answer rejected")
+	}
+
+	swapped := ExitKeyPayload("peer-US-b", "wgkey", "6.6.6.6", "51820", "10.100.0.2/32", "s1", "clientkey")
+	if VerifyKeyPayload(pubB64, swapped, sig) {
+		t.Fatal("exit answer with a swapped endpoint accepted")
+	}
+	replayed := ExitKeyPayload("peer-US-b", "wgkey", "1.2.3.4", "51820", "10.100.0.2/32", "s2", "clientkey")
+	if VerifyKeyPayload(pubB64, replayed, sig) {
+		t.Fatal("exit answer accepted for another session")
+	}
+}
@@ -0,0 +1,39 @@
+package super
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// ClientKeyPayload is the message a client peer signs with its identity key
+// to vouch for the WireGuard key it asks an exit with.
+func ClientKeyPayload(peerID, wgPublicKey, requestedRegion string, signedAt int64) string {
+	return fmt.Sprintf("client-wg|%s|%s|%s|%d", peerID, wgPublicKey, requestedRegion, signedAt)
+}
+
+// ExitKeyPayload is the message an exit peer signs with its identity key to
+// vouch for its WireGuard key and endpoint. It names the session and the
+// client's WireGuard key so the answer can't be replayed to another client.
+func ExitKeyPayload(exitPeerID, wgPublicKey, endpointIP, endpointPort, clientIP, sessionID, clientWGKey string) string {
+	return fmt.Sprintf("exit-wg|%s|%s|%s|%s|%s|%s|%s", exitPeerID, wgPublicKey, endpointIP, endpointPort, clientIP, sessionID, clientWGKey)
+}
+
+// SignKeyPayload signs a client or exit key payload.
+func SignKeyPayload(priv ed25519.PrivateKey, payload string) string {
+	return base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(payload)))
+}
+
+// VerifyKeyPayload checks a base64 signature over payload by the base64
+// ed25519 key pubKeyBase64.
+func VerifyKeyPayload(pubKeyBase64, payload, signatureBase64 string) bool {
+	pub, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(signatureBase64)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(pub), []byte(payload), sig)
+}
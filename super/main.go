@@ -2,63 +2,88 @@ package main
 
 import (
 	"Super_node/client"
+	"Super_node/config"
+	super "Super_node/crypto"
 	"Super_node/pb"
 	"Super_node/server"
 	"Super_node/utils"
-	"crypto/rand"
-	"encoding/hex"
 	"flag"
 	"fmt"
 	"log"
 	"net"
+	"os"
+	"time"
 
 	"google.golang.org/grpc"
 )
 
-func generateRandomID(region string) string {
-	b := make([]byte, 3)
-	if _, err := rand.Read(b); err != nil {
-		return region + "000"
-	}
-	return fmt.Sprintf("super-%s-%s", region, hex.EncodeToString(b))
-}
-
 func main() {
-	// 🏁 CLI flags
-	peerPort := flag.String("peer-port", "50052", "Port for Super Node Server")
-	nodeID := flag.String("id", "", "Node ID (optional)")
-	region := flag.String("region", "IN", "Region code for Super Node")
-	baseIP := flag.String("base-ip", "127.0.0.1", "Base Node IP address")
+	// 🏁 CLI flags, layered over the config file and environment
+	cfg := config.Default()
+	configPath := flag.String("config", "", "Path to a YAML config file")
+	printConfig := flag.Bool("print-config", false, "Print the effective configuration and exit")
+	flag.StringVar(&cfg.PeerPort, "peer-port", cfg.PeerPort, "Port for Super Node Server")
+	flag.StringVar(&cfg.Region, "region", cfg.Region, "Region code for Super Node")
+	flag.StringVar(&cfg.Base.IP, "base-ip", cfg.Base.IP, "Base Node IP address")
+	flag.Var(&cfg.Base.Endpoints, "base-endpoints", "Comma-separated IP:port list of base nodes to try in order (overrides --base-ip)")
+	flag.IntVar(&cfg.Base.Port, "base-port", cfg.Base.Port, "Base Node port (0 picks it from the region)")
+	flag.Var(&cfg.Peers.StaleTTL, "peer-stale-ttl", "Heartbeat age after which a client peer is stale and no longer used as an exit")
+	flag.Var(&cfg.Peers.DeadTTL, "peer-dead-ttl", "Heartbeat age after which a client peer is evicted")
+	flag.IntVar(&cfg.MaxPeers, "max-peers", cfg.MaxPeers, "Maximum number of client peers this Super Node serves")
+	flag.StringVar(&cfg.TLS.CA, "tls-ca", cfg.TLS.CA, "Network CA certificate for mutual TLS")
+	flag.StringVar(&cfg.TLS.Cert, "tls-cert", cfg.TLS.Cert, "Certificate issued to this node's identity key")
+	flag.BoolVar(&cfg.TLS.Insecure, "insecure", cfg.TLS.Insecure, "Disable mutual TLS on all gRPC links (development only)")
 	flag.Parse()
 
-	finalID := *nodeID
-	if finalID == "" {
-		finalID = generateRandomID(*region)
+	if err := config.Resolve(flag.CommandLine, *configPath, &cfg); err != nil {
+		log.Fatalf("❌ Invalid configuration: %v", err)
+	}
+	if *printConfig {
+		if err := config.Print(os.Stdout, &cfg); err != nil {
+			log.Fatalf("❌ Failed to print configuration: %v", err)
+		}
+		return
+	}
+
+	// 🔑 The node ID is derived from the identity key so it cannot be claimed by anyone else
+	priv, pub, err := super.LoadOrCreateKeypair()
+	if err != nil {
+		log.Fatalf("❌ Failed to load/create keypair: %v", err)
 	}
+	finalID := super.DeriveNodeID(cfg.Region, pub)
 
-	// 🌐 Choose Base Node port based on region
-	basePort := map[string]int{
-		"IN": 50051,
-		"US": 50053,
-	}[*region]
-	if basePort == 0 {
-		basePort = 50051
+	// 🔒 Mutual TLS with a certificate issued to the identity key
+	if cfg.TLS.Insecure {
+		log.Println("⚠️ Mutual TLS disabled, gRPC traffic is unencrypted")
+	} else if err := utils.LoadMutualTLSWithKey(cfg.TLS.CA, cfg.TLS.Cert, priv); err != nil {
+		log.Fatalf("❌ Failed to load TLS credentials (use --insecure to run without TLS): %v", err)
 	}
-	baseAddr := fmt.Sprintf("%s:%d", *baseIP, basePort)
 
 	// 🎯 Super Node's listen address
 	localIP := utils.GetLocalIP()
-	superNodeAddr := fmt.Sprintf("%s:%s", localIP, *peerPort)
+	superNodeAddr := fmt.Sprintf("%s:%s", localIP, cfg.PeerPort)
 
-	// 🌐 Dial base node
-	conn, err := grpc.Dial(baseAddr, grpc.WithInsecure())
+	// 🌐 Dial the first base node; the link fails over to the others
+	link, err := client.NewBaseLink(cfg.Base.Addresses(cfg.Region))
 	if err != nil {
 		log.Fatalf("❌ Failed to connect to base node: %v", err)
 	}
-	defer conn.Close()
+	defer link.Close()
 
 	// Create reusable gRPC client for base
-	baseClient := pb.NewBaseNodeServiceClient(conn)
+	baseClient := pb.NewBaseNodeServiceClient(link)
+
+	// ⬇️ Pass baseClient into server handler
+	superNodeServer := server.NewSupreNodeServer(baseClient, cfg.Region)
+	superNodeServer.SetNodeID(finalID)
+	superNodeServer.SetSigningKey(priv)
+	superNodeServer.SetMaxPeers(cfg.MaxPeers)
+	peers, err := server.NewPeerTable(time.Duration(cfg.Peers.StaleTTL), time.Duration(cfg.Peers.DeadTTL))
+	if err != nil {
+		log.Fatalf("❌ Invalid peer table settings: %v", err)
+	}
+	superNodeServer.SetPeerTable(peers)
+	superNodeServer.StartPeerMonitoring()
 
 	// 👂 Start gRPC server for client peers
 	go func() {
@@ -67,25 +92,27 @@ func main() {
 			log.Fatalf("Failed to listen: %v", err)
 		}
 
-		grpcServer := grpc.NewServer()
-
-		// ⬇️ Pass baseClient into server handler
-		superNodeServer := server.NewSupreNodeServer(baseClient, *region)
-		superNodeServer.StartPeerMonitoring()
+		// Client peers and exits use the rest of the service
+		grpcServer := grpc.NewServer(
+			utils.ServerOption(),
+			grpc.UnaryInterceptor(utils.RequireRole(utils.RoleSuper,
+				"dvpn.SuperNodeService/RequestExitPeer",
+				"dvpn.SuperNodeService/EndSession",
+				"dvpn.SuperNodeService/AdmitClient")),
+		)
 
 		pb.RegisterSuperNodeServiceServer(grpcServer, superNodeServer)
 
-		log.Printf("🚀 Super Node Server is live on port %s", *peerPort)
+		log.Printf("🚀 Super Node Server is live on port %s", cfg.PeerPort)
 		if err := grpcServer.Serve(lis); err != nil {
 			log.Fatalf("Failed to serve: %v", err)
 		}
 	}()
 
 	// 🔐 Register this Super Node to base
-	node := client.NewSupreNode(conn, finalID, *peerPort, *region)
-	if err := node.Register(); err != nil {
-		log.Fatalf("❌ Registration failed: %v", err)
-	}
+	node := client.NewSupreNode(link, finalID, cfg.PeerPort, cfg.Region, cfg.MaxPeers)
+	node.SetMetricsSource(superNodeServer)
+	node.Join()
 
 	log.Println("✅ Super Node registered to Base Node. Starting heartbeat...")
 	go node.StartHeartbeat()
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"runtime"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// ExitBandwidthUnknown is reported as Load.ExitBandwidthMbps when an exit
+// advertised no capacity, so what the super can grant is not known.
+const ExitBandwidthUnknown = -1
+
+// Load is the state a super node reports to its base in every heartbeat.
+type Load struct {
+	ActivePeers        int32
+	ExitPeersAvailable int32
+	AvgLatencyMs       float32
+	BandwidthMbps      float32
+	ExitBandwidthMbps  float32
+	CPUPercent         float32
+	MemoryMB           float32
+}
+
+// Source produces the current load. SuperNodeServer implements it from its
+// peer table; the heartbeat client reads from it.
+type Source interface {
+	Load() Load
+}
+
+// Process samples the resource usage of the running process. CPU usage is
+// measured between consecutive calls to Sample.
+type Process struct {
+	mu       sync.Mutex
+	lastCPU  time.Duration
+	lastWall time.Time
+}
+
+func NewProcess() *Process {
+	p := &Process{}
+	p.lastCPU, _ = cpuTime()
+	p.lastWall = time.Now()
+	return p
+}
+
+// Sample returns the CPU usage since the previous call, as a percentage of
+// one core, and the memory obtained from the OS by the Go runtime.
+func (p *Process) Sample() (cpuPercent, memoryMB float32) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := time.Now()
+	if cpu, err := cpuTime(); err == nil {
+		if wall := now.Sub(p.lastWall); wall > 0 {
+			cpuPercent = float32(100 * float64(cpu-p.lastCPU) / float64(wall))
+		}
+		p.lastCPU = cpu
+	}
+	p.lastWall = now
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	memoryMB = float32(m.Sys) / (1 << 20)
+
+	return cpuPercent, memoryMB
+}
+
+func cpuTime() (time.Duration, error) {
+	var ru syscall.Rusage
+	if err := syscall.Getrusage(syscall.RUSAGE_SELF, &ru); err != nil {
+		return 0, err
+	}
+	return time.Duration(ru.Utime.Nano() + ru.Stime.Nano()), nil
+}
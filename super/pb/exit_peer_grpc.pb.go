@@ -20,6 +20,8 @@ const _ = grpc.SupportPackageIsVersion9
 
 const (
 	ExitPeerService_GetWireGuardInfo_FullMethodName = "/dvpn.ExitPeerService/GetWireGuardInfo"
+	ExitPeerService_EndSession_FullMethodName       = "/dvpn.ExitPeerService/EndSession"
+	ExitPeerService_AdmitClient_FullMethodName      = "/dvpn.ExitPeerService/AdmitClient"
 )
 
 // ExitPeerServiceClient is the client API for ExitPeerService service.
@@ -27,6 +29,8 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type ExitPeerServiceClient interface {
 	GetWireGuardInfo(ctx context.Context, in *ExitPeerInfoRequest, opts ...grpc.CallOption) (*ExitPeerInfoResponse, error)
+	EndSession(ctx context.Context, in *EndSessionRequest, opts ...grpc.CallOption) (*Ack, error)
+	AdmitClient(ctx context.Context, in *ExitTicket, opts ...grpc.CallOption) (*Ack, error)
 }
 
 type exitPeerServiceClient struct {
@@ -47,11 +51,33 @@ func (c *exitPeerServiceClient) GetWireGuardInfo(ctx context.Context, in *ExitPe
 	return out, nil
 }
 
+func (c *exitPeerServiceClient) EndSession(ctx context.Context, in *EndSessionRequest, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, ExitPeerService_EndSession_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *exitPeerServiceClient) AdmitClient(ctx context.Context, in *ExitTicket, opts ...grpc.CallOption) (*Ack, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Ack)
+	err := c.cc.Invoke(ctx, ExitPeerService_AdmitClient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ExitPeerServiceServer is the server API for ExitPeerService service.
 // All implementations must embed UnimplementedExitPeerServiceServer
 // for forward compatibility.
 type ExitPeerServiceServer interface {
 	GetWireGuardInfo(context.Context, *ExitPeerInfoRequest) (*ExitPeerInfoResponse, error)
+	EndSession(context.Context, *EndSessionRequest) (*Ack, error)
+	AdmitClient(context.Context, *ExitTicket) (*Ack, error)
 	mustEmbedUnimplementedExitPeerServiceServer()
 }
 
@@ -65,6 +91,12 @@ type UnimplementedExitPeerServiceServer struct{}
 func (UnimplementedExitPeerServiceServer) GetWireGuardInfo(context.Context, *ExitPeerInfoRequest) (*ExitPeerInfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetWireGuardInfo not implemented")
 }
+func (UnimplementedExitPeerServiceServer) EndSession(context.Context, *EndSessionRequest) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EndSession not implemented")
+}
+func (UnimplementedExitPeerServiceServer) AdmitClient(context.Context, *ExitTicket) (*Ack, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AdmitClient not implemented")
+}
 func (UnimplementedExitPeerServiceServer) mustEmbedUnimplementedExitPeerServiceServer() {}
 func (UnimplementedExitPeerServiceServer) testEmbeddedByValue()                         {}
 
@@ -104,6 +136,42 @@ func _ExitPeerService_GetWireGuardInfo_Handler(srv interface{}, ctx context.Cont
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ExitPeerService_EndSession_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EndSessionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExitPeerServiceServer).EndSession(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExitPeerService_EndSession_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExitPeerServiceServer).EndSession(ctx, req.(*EndSessionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ExitPeerService_AdmitClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ExitTicket)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ExitPeerServiceServer).AdmitClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ExitPeerService_AdmitClient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ExitPeerServiceServer).AdmitClient(ctx, req.(*ExitTicket))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ExitPeerService_ServiceDesc is the grpc.ServiceDesc for ExitPeerService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -115,6 +183,14 @@ var ExitPeerService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetWireGuardInfo",
 			Handler:    _ExitPeerService_GetWireGuardInfo_Handler,
 		},
+		{
+			MethodName: "EndSession",
+			Handler:    _ExitPeerService_EndSession_Handler,
+		},
+		{
+			MethodName: "AdmitClient",
+			Handler:    _ExitPeerService_AdmitClient_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "exit_peer.proto",
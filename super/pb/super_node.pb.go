@@ -33,6 +33,7 @@ type PeerRegistrationRequest struct {
 	Nonce         string                 `protobuf:"bytes,8,opt,name=nonce,proto3" json:"nonce,omitempty"`
 	Ip            string                 `protobuf:"bytes,9,opt,name=ip,proto3" json:"ip,omitempty"`
 	GrpcPort      string                 `protobuf:"bytes,10,opt,name=grpc_port,json=grpcPort,proto3" json:"grpc_port,omitempty"`
+	SignedAt      int64                  `protobuf:"varint,11,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -137,6 +138,13 @@ func (x *PeerRegistrationRequest) GetGrpcPort() string {
 	return ""
 }
 
+func (x *PeerRegistrationRequest) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
 type PeerSessionHeartbeatRequest struct {
 	state             protoimpl.MessageState `protogen:"open.v1"`
 	PeerId            string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
@@ -228,8 +236,21 @@ type ExitPeerRequest struct {
 	MaxLatencyMs     float32                `protobuf:"fixed32,3,opt,name=max_latency_ms,json=maxLatencyMs,proto3" json:"max_latency_ms,omitempty"`
 	RequestedRegion  string                 `protobuf:"bytes,4,opt,name=requested_region,json=requestedRegion,proto3" json:"requested_region,omitempty"`
 	ClientPublicKey  string                 `protobuf:"bytes,5,opt,name=client_public_key,json=clientPublicKey,proto3" json:"client_public_key,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	RequesterRegion  string                 `protobuf:"bytes,6,opt,name=requester_region,json=requesterRegion,proto3" json:"requester_region,omitempty"`
+	SessionId        string                 `protobuf:"bytes,7,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	// The calling super signs the request with its identity key; the
+	// receiving super checks it is registered with a federated base.
+	CallerId           string `protobuf:"bytes,8,opt,name=caller_id,json=callerId,proto3" json:"caller_id,omitempty"`
+	CallerRegion       string `protobuf:"bytes,9,opt,name=caller_region,json=callerRegion,proto3" json:"caller_region,omitempty"`
+	CallerPublicKey    string `protobuf:"bytes,10,opt,name=caller_public_key,json=callerPublicKey,proto3" json:"caller_public_key,omitempty"`
+	Nonce              string `protobuf:"bytes,11,opt,name=nonce,proto3" json:"nonce,omitempty"`
+	SignedAt           int64  `protobuf:"varint,12,opt,name=signed_at,json=signedAt,proto3" json:"signed_at,omitempty"`
+	Signature          string `protobuf:"bytes,13,opt,name=signature,proto3" json:"signature,omitempty"`
+	ClientIdentityKey  string `protobuf:"bytes,14,opt,name=client_identity_key,json=clientIdentityKey,proto3" json:"client_identity_key,omitempty"`
+	ClientKeySignedAt  int64  `protobuf:"varint,15,opt,name=client_key_signed_at,json=clientKeySignedAt,proto3" json:"client_key_signed_at,omitempty"`
+	ClientKeySignature string `protobuf:"bytes,16,opt,name=client_key_signature,json=clientKeySignature,proto3" json:"client_key_signature,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *ExitPeerRequest) Reset() {
@@ -297,17 +318,101 @@ func (x *ExitPeerRequest) GetClientPublicKey() string {
 	return ""
 }
 
+func (x *ExitPeerRequest) GetRequesterRegion() string {
+	if x != nil {
+		return x.RequesterRegion
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetCallerId() string {
+	if x != nil {
+		return x.CallerId
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetCallerRegion() string {
+	if x != nil {
+		return x.CallerRegion
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetCallerPublicKey() string {
+	if x != nil {
+		return x.CallerPublicKey
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetNonce() string {
+	if x != nil {
+		return x.Nonce
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetSignedAt() int64 {
+	if x != nil {
+		return x.SignedAt
+	}
+	return 0
+}
+
+func (x *ExitPeerRequest) GetSignature() string {
+	if x != nil {
+		return x.Signature
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetClientIdentityKey() string {
+	if x != nil {
+		return x.ClientIdentityKey
+	}
+	return ""
+}
+
+func (x *ExitPeerRequest) GetClientKeySignedAt() int64 {
+	if x != nil {
+		return x.ClientKeySignedAt
+	}
+	return 0
+}
+
+func (x *ExitPeerRequest) GetClientKeySignature() string {
+	if x != nil {
+		return x.ClientKeySignature
+	}
+	return ""
+}
+
 type ExitPeerResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	PublicKey     string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
-	EndpointIp    string                 `protobuf:"bytes,2,opt,name=endpoint_ip,json=endpointIp,proto3" json:"endpoint_ip,omitempty"`
-	EndpointPort  string                 `protobuf:"bytes,3,opt,name=endpoint_port,json=endpointPort,proto3" json:"endpoint_port,omitempty"`
-	AllowedIps    string                 `protobuf:"bytes,4,opt,name=allowed_ips,json=allowedIps,proto3" json:"allowed_ips,omitempty"`
-	PeerId        string                 `protobuf:"bytes,5,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
-	Region        string                 `protobuf:"bytes,6,opt,name=region,proto3" json:"region,omitempty"`
-	ClientIp      string                 `protobuf:"bytes,7,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	PublicKey       string                 `protobuf:"bytes,1,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	EndpointIp      string                 `protobuf:"bytes,2,opt,name=endpoint_ip,json=endpointIp,proto3" json:"endpoint_ip,omitempty"`
+	EndpointPort    string                 `protobuf:"bytes,3,opt,name=endpoint_port,json=endpointPort,proto3" json:"endpoint_port,omitempty"`
+	AllowedIps      string                 `protobuf:"bytes,4,opt,name=allowed_ips,json=allowedIps,proto3" json:"allowed_ips,omitempty"`
+	PeerId          string                 `protobuf:"bytes,5,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	Region          string                 `protobuf:"bytes,6,opt,name=region,proto3" json:"region,omitempty"`
+	ClientIp        string                 `protobuf:"bytes,7,opt,name=client_ip,json=clientIp,proto3" json:"client_ip,omitempty"`
+	SessionId       string                 `protobuf:"bytes,8,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ExitIdentityKey string                 `protobuf:"bytes,9,opt,name=exit_identity_key,json=exitIdentityKey,proto3" json:"exit_identity_key,omitempty"`
+	ExitSignature   string                 `protobuf:"bytes,10,opt,name=exit_signature,json=exitSignature,proto3" json:"exit_signature,omitempty"`
+	// Rate the super of the exit reserved for the session; zero means the
+	// exit did not advertise its capacity and the client asked for no
+	// minimum.
+	GrantedBandwidthMbps float32 `protobuf:"fixed32,11,opt,name=granted_bandwidth_mbps,json=grantedBandwidthMbps,proto3" json:"granted_bandwidth_mbps,omitempty"`
+	unknownFields        protoimpl.UnknownFields
+	sizeCache            protoimpl.SizeCache
 }
 
 func (x *ExitPeerResponse) Reset() {
@@ -389,6 +494,34 @@ func (x *ExitPeerResponse) GetClientIp() string {
 	return ""
 }
 
+func (x *ExitPeerResponse) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *ExitPeerResponse) GetExitIdentityKey() string {
+	if x != nil {
+		return x.ExitIdentityKey
+	}
+	return ""
+}
+
+func (x *ExitPeerResponse) GetExitSignature() string {
+	if x != nil {
+		return x.ExitSignature
+	}
+	return ""
+}
+
+func (x *ExitPeerResponse) GetGrantedBandwidthMbps() float32 {
+	if x != nil {
+		return x.GrantedBandwidthMbps
+	}
+	return 0
+}
+
 type ExitRequest struct {
 	state            protoimpl.MessageState `protogen:"open.v1"`
 	PeerId           string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
@@ -396,8 +529,12 @@ type ExitRequest struct {
 	RequestedRegion  string                 `protobuf:"bytes,3,opt,name=requested_region,json=requestedRegion,proto3" json:"requested_region,omitempty"`
 	MinBandwidthMbps float32                `protobuf:"fixed32,4,opt,name=min_bandwidth_mbps,json=minBandwidthMbps,proto3" json:"min_bandwidth_mbps,omitempty"`
 	MaxLatencyMs     float32                `protobuf:"fixed32,5,opt,name=max_latency_ms,json=maxLatencyMs,proto3" json:"max_latency_ms,omitempty"`
-	unknownFields    protoimpl.UnknownFields
-	sizeCache        protoimpl.SizeCache
+	// Signature of client_public_key by the peer's identity key.
+	ClientIdentityKey  string `protobuf:"bytes,6,opt,name=client_identity_key,json=clientIdentityKey,proto3" json:"client_identity_key,omitempty"`
+	ClientKeySignedAt  int64  `protobuf:"varint,7,opt,name=client_key_signed_at,json=clientKeySignedAt,proto3" json:"client_key_signed_at,omitempty"`
+	ClientKeySignature string `protobuf:"bytes,8,opt,name=client_key_signature,json=clientKeySignature,proto3" json:"client_key_signature,omitempty"`
+	unknownFields      protoimpl.UnknownFields
+	sizeCache          protoimpl.SizeCache
 }
 
 func (x *ExitRequest) Reset() {
@@ -465,6 +602,27 @@ func (x *ExitRequest) GetMaxLatencyMs() float32 {
 	return 0
 }
 
+func (x *ExitRequest) GetClientIdentityKey() string {
+	if x != nil {
+		return x.ClientIdentityKey
+	}
+	return ""
+}
+
+func (x *ExitRequest) GetClientKeySignedAt() int64 {
+	if x != nil {
+		return x.ClientKeySignedAt
+	}
+	return 0
+}
+
+func (x *ExitRequest) GetClientKeySignature() string {
+	if x != nil {
+		return x.ClientKeySignature
+	}
+	return ""
+}
+
 type WireguardConfig struct {
 	state               protoimpl.MessageState `protogen:"open.v1"`
 	InterfacePrivateKey string                 `protobuf:"bytes,1,opt,name=interface_private_key,json=interfacePrivateKey,proto3" json:"interface_private_key,omitempty"`
@@ -474,8 +632,13 @@ type WireguardConfig struct {
 	PeerEndpoint        string                 `protobuf:"bytes,5,opt,name=peer_endpoint,json=peerEndpoint,proto3" json:"peer_endpoint,omitempty"`
 	AllowedIps          string                 `protobuf:"bytes,6,opt,name=allowed_ips,json=allowedIps,proto3" json:"allowed_ips,omitempty"`
 	Keepalive           int32                  `protobuf:"varint,7,opt,name=keepalive,proto3" json:"keepalive,omitempty"`
-	unknownFields       protoimpl.UnknownFields
-	sizeCache           protoimpl.SizeCache
+	SessionId           string                 `protobuf:"bytes,8,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	ExitPeerId          string                 `protobuf:"bytes,9,opt,name=exit_peer_id,json=exitPeerId,proto3" json:"exit_peer_id,omitempty"`
+	// Signature of the exit's key and endpoint by the exit's identity key.
+	ExitIdentityKey string `protobuf:"bytes,10,opt,name=exit_identity_key,json=exitIdentityKey,proto3" json:"exit_identity_key,omitempty"`
+	ExitSignature   string `protobuf:"bytes,11,opt,name=exit_signature,json=exitSignature,proto3" json:"exit_signature,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *WireguardConfig) Reset() {
@@ -557,11 +720,252 @@ func (x *WireguardConfig) GetKeepalive() int32 {
 	return 0
 }
 
+func (x *WireguardConfig) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
+func (x *WireguardConfig) GetExitPeerId() string {
+	if x != nil {
+		return x.ExitPeerId
+	}
+	return ""
+}
+
+func (x *WireguardConfig) GetExitIdentityKey() string {
+	if x != nil {
+		return x.ExitIdentityKey
+	}
+	return ""
+}
+
+func (x *WireguardConfig) GetExitSignature() string {
+	if x != nil {
+		return x.ExitSignature
+	}
+	return ""
+}
+
+// ExitAdvertisement opts a registered peer in as an exit. Only advertised
+// peers are handed out by RequestExitPeer.
+type ExitAdvertisement struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	PeerId string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	// WireGuard endpoint and key clients connect to.
+	PublicKey    string `protobuf:"bytes,2,opt,name=public_key,json=publicKey,proto3" json:"public_key,omitempty"`
+	EndpointIp   string `protobuf:"bytes,3,opt,name=endpoint_ip,json=endpointIp,proto3" json:"endpoint_ip,omitempty"`
+	EndpointPort string `protobuf:"bytes,4,opt,name=endpoint_port,json=endpointPort,proto3" json:"endpoint_port,omitempty"`
+	// Port of the peer's ExitPeerService.
+	GrpcPort string `protobuf:"bytes,5,opt,name=grpc_port,json=grpcPort,proto3" json:"grpc_port,omitempty"`
+	// Routes offered to clients, e.g. 0.0.0.0/0.
+	AllowedIps string `protobuf:"bytes,6,opt,name=allowed_ips,json=allowedIps,proto3" json:"allowed_ips,omitempty"`
+	// Most clients served at once; zero means no limit.
+	MaxSessions   int32   `protobuf:"varint,7,opt,name=max_sessions,json=maxSessions,proto3" json:"max_sessions,omitempty"`
+	BandwidthMbps float32 `protobuf:"fixed32,8,opt,name=bandwidth_mbps,json=bandwidthMbps,proto3" json:"bandwidth_mbps,omitempty"`
+	// Regions whose clients may use this exit; empty allows every region.
+	AllowedRegions []string `protobuf:"bytes,9,rep,name=allowed_regions,json=allowedRegions,proto3" json:"allowed_regions,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *ExitAdvertisement) Reset() {
+	*x = ExitAdvertisement{}
+	mi := &file_super_node_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExitAdvertisement) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExitAdvertisement) ProtoMessage() {}
+
+func (x *ExitAdvertisement) ProtoReflect() protoreflect.Message {
+	mi := &file_super_node_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExitAdvertisement.ProtoReflect.Descriptor instead.
+func (*ExitAdvertisement) Descriptor() ([]byte, []int) {
+	return file_super_node_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ExitAdvertisement) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *ExitAdvertisement) GetPublicKey() string {
+	if x != nil {
+		return x.PublicKey
+	}
+	return ""
+}
+
+func (x *ExitAdvertisement) GetEndpointIp() string {
+	if x != nil {
+		return x.EndpointIp
+	}
+	return ""
+}
+
+func (x *ExitAdvertisement) GetEndpointPort() string {
+	if x != nil {
+		return x.EndpointPort
+	}
+	return ""
+}
+
+func (x *ExitAdvertisement) GetGrpcPort() string {
+	if x != nil {
+		return x.GrpcPort
+	}
+	return ""
+}
+
+func (x *ExitAdvertisement) GetAllowedIps() string {
+	if x != nil {
+		return x.AllowedIps
+	}
+	return ""
+}
+
+func (x *ExitAdvertisement) GetMaxSessions() int32 {
+	if x != nil {
+		return x.MaxSessions
+	}
+	return 0
+}
+
+func (x *ExitAdvertisement) GetBandwidthMbps() float32 {
+	if x != nil {
+		return x.BandwidthMbps
+	}
+	return 0
+}
+
+func (x *ExitAdvertisement) GetAllowedRegions() []string {
+	if x != nil {
+		return x.AllowedRegions
+	}
+	return nil
+}
+
+type ExitWithdrawal struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ExitWithdrawal) Reset() {
+	*x = ExitWithdrawal{}
+	mi := &file_super_node_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ExitWithdrawal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ExitWithdrawal) ProtoMessage() {}
+
+func (x *ExitWithdrawal) ProtoReflect() protoreflect.Message {
+	mi := &file_super_node_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ExitWithdrawal.ProtoReflect.Descriptor instead.
+func (*ExitWithdrawal) Descriptor() ([]byte, []int) {
+	return file_super_node_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ExitWithdrawal) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+// ReleaseExitRequest is sent by a client peer that is done with the exit
+// session it was given by RequestExit.
+type ReleaseExitRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	PeerId        string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	SessionId     string                 `protobuf:"bytes,2,opt,name=session_id,json=sessionId,proto3" json:"session_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReleaseExitRequest) Reset() {
+	*x = ReleaseExitRequest{}
+	mi := &file_super_node_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReleaseExitRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseExitRequest) ProtoMessage() {}
+
+func (x *ReleaseExitRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_super_node_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseExitRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseExitRequest) Descriptor() ([]byte, []int) {
+	return file_super_node_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReleaseExitRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *ReleaseExitRequest) GetSessionId() string {
+	if x != nil {
+		return x.SessionId
+	}
+	return ""
+}
+
 var File_super_node_proto protoreflect.FileDescriptor
 
 const file_super_node_proto_rawDesc = "" +
 	"\n" +
-	"\x10super_node.proto\x12\x04dvpn\x1a\x0fbase_node.proto\"\x8f\x02\n" +
+	"\x10super_node.proto\x12\x04dvpn\x1a\x0fbase_node.proto\x1a\x0fexit_peer.proto\"\xac\x02\n" +
 	"\x17PeerRegistrationRequest\x12\x17\n" +
 	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x1d\n" +
 	"\n" +
@@ -574,7 +978,8 @@ const file_super_node_proto_rawDesc = "" +
 	"\x05nonce\x18\b \x01(\tR\x05nonce\x12\x0e\n" +
 	"\x02ip\x18\t \x01(\tR\x02ip\x12\x1b\n" +
 	"\tgrpc_port\x18\n" +
-	" \x01(\tR\bgrpcPort\"\xf1\x01\n" +
+	" \x01(\tR\bgrpcPort\x12\x1b\n" +
+	"\tsigned_at\x18\v \x01(\x03R\bsignedAt\"\xf1\x01\n" +
 	"\x1bPeerSessionHeartbeatRequest\x12\x17\n" +
 	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12 \n" +
 	"\fexit_peer_id\x18\x02 \x01(\tR\n" +
@@ -584,13 +989,26 @@ const file_super_node_proto_rawDesc = "" +
 	"\vpacket_loss\x18\x04 \x01(\x02R\n" +
 	"packetLoss\x12'\n" +
 	"\x0fthroughput_mbps\x18\x05 \x01(\x02R\x0ethroughputMbps\x12.\n" +
-	"\x13session_uptime_secs\x18\x06 \x01(\x05R\x11sessionUptimeSecs\"\xdf\x01\n" +
+	"\x13session_uptime_secs\x18\x06 \x01(\x05R\x11sessionUptimeSecs\"\xfb\x04\n" +
 	"\x0fExitPeerRequest\x12!\n" +
 	"\frequester_id\x18\x01 \x01(\tR\vrequesterId\x12,\n" +
 	"\x12min_bandwidth_mbps\x18\x02 \x01(\x02R\x10minBandwidthMbps\x12$\n" +
 	"\x0emax_latency_ms\x18\x03 \x01(\x02R\fmaxLatencyMs\x12)\n" +
 	"\x10requested_region\x18\x04 \x01(\tR\x0frequestedRegion\x12*\n" +
-	"\x11client_public_key\x18\x05 \x01(\tR\x0fclientPublicKey\"\xe6\x01\n" +
+	"\x11client_public_key\x18\x05 \x01(\tR\x0fclientPublicKey\x12)\n" +
+	"\x10requester_region\x18\x06 \x01(\tR\x0frequesterRegion\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\a \x01(\tR\tsessionId\x12\x1b\n" +
+	"\tcaller_id\x18\b \x01(\tR\bcallerId\x12#\n" +
+	"\rcaller_region\x18\t \x01(\tR\fcallerRegion\x12*\n" +
+	"\x11caller_public_key\x18\n" +
+	" \x01(\tR\x0fcallerPublicKey\x12\x14\n" +
+	"\x05nonce\x18\v \x01(\tR\x05nonce\x12\x1b\n" +
+	"\tsigned_at\x18\f \x01(\x03R\bsignedAt\x12\x1c\n" +
+	"\tsignature\x18\r \x01(\tR\tsignature\x12.\n" +
+	"\x13client_identity_key\x18\x0e \x01(\tR\x11clientIdentityKey\x12/\n" +
+	"\x14client_key_signed_at\x18\x0f \x01(\x03R\x11clientKeySignedAt\x120\n" +
+	"\x14client_key_signature\x18\x10 \x01(\tR\x12clientKeySignature\"\x8e\x03\n" +
 	"\x10ExitPeerResponse\x12\x1d\n" +
 	"\n" +
 	"public_key\x18\x01 \x01(\tR\tpublicKey\x12\x1f\n" +
@@ -601,13 +1019,22 @@ const file_super_node_proto_rawDesc = "" +
 	"allowedIps\x12\x17\n" +
 	"\apeer_id\x18\x05 \x01(\tR\x06peerId\x12\x16\n" +
 	"\x06region\x18\x06 \x01(\tR\x06region\x12\x1b\n" +
-	"\tclient_ip\x18\a \x01(\tR\bclientIp\"\xd1\x01\n" +
+	"\tclient_ip\x18\a \x01(\tR\bclientIp\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\b \x01(\tR\tsessionId\x12*\n" +
+	"\x11exit_identity_key\x18\t \x01(\tR\x0fexitIdentityKey\x12%\n" +
+	"\x0eexit_signature\x18\n" +
+	" \x01(\tR\rexitSignature\x124\n" +
+	"\x16granted_bandwidth_mbps\x18\v \x01(\x02R\x14grantedBandwidthMbps\"\xe4\x02\n" +
 	"\vExitRequest\x12\x17\n" +
 	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12*\n" +
 	"\x11client_public_key\x18\x02 \x01(\tR\x0fclientPublicKey\x12)\n" +
 	"\x10requested_region\x18\x03 \x01(\tR\x0frequestedRegion\x12,\n" +
 	"\x12min_bandwidth_mbps\x18\x04 \x01(\x02R\x10minBandwidthMbps\x12$\n" +
-	"\x0emax_latency_ms\x18\x05 \x01(\x02R\fmaxLatencyMs\"\x90\x02\n" +
+	"\x0emax_latency_ms\x18\x05 \x01(\x02R\fmaxLatencyMs\x12.\n" +
+	"\x13client_identity_key\x18\x06 \x01(\tR\x11clientIdentityKey\x12/\n" +
+	"\x14client_key_signed_at\x18\a \x01(\x03R\x11clientKeySignedAt\x120\n" +
+	"\x14client_key_signature\x18\b \x01(\tR\x12clientKeySignature\"\xa4\x03\n" +
 	"\x0fWireguardConfig\x122\n" +
 	"\x15interface_private_key\x18\x01 \x01(\tR\x13interfacePrivateKey\x12+\n" +
 	"\x11interface_address\x18\x02 \x01(\tR\x10interfaceAddress\x12\x10\n" +
@@ -616,12 +1043,44 @@ const file_super_node_proto_rawDesc = "" +
 	"\rpeer_endpoint\x18\x05 \x01(\tR\fpeerEndpoint\x12\x1f\n" +
 	"\vallowed_ips\x18\x06 \x01(\tR\n" +
 	"allowedIps\x12\x1c\n" +
-	"\tkeepalive\x18\a \x01(\x05R\tkeepalive2\xa0\x02\n" +
+	"\tkeepalive\x18\a \x01(\x05R\tkeepalive\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\b \x01(\tR\tsessionId\x12 \n" +
+	"\fexit_peer_id\x18\t \x01(\tR\n" +
+	"exitPeerId\x12*\n" +
+	"\x11exit_identity_key\x18\n" +
+	" \x01(\tR\x0fexitIdentityKey\x12%\n" +
+	"\x0eexit_signature\x18\v \x01(\tR\rexitSignature\"\xc2\x02\n" +
+	"\x11ExitAdvertisement\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x1d\n" +
+	"\n" +
+	"public_key\x18\x02 \x01(\tR\tpublicKey\x12\x1f\n" +
+	"\vendpoint_ip\x18\x03 \x01(\tR\n" +
+	"endpointIp\x12#\n" +
+	"\rendpoint_port\x18\x04 \x01(\tR\fendpointPort\x12\x1b\n" +
+	"\tgrpc_port\x18\x05 \x01(\tR\bgrpcPort\x12\x1f\n" +
+	"\vallowed_ips\x18\x06 \x01(\tR\n" +
+	"allowedIps\x12!\n" +
+	"\fmax_sessions\x18\a \x01(\x05R\vmaxSessions\x12%\n" +
+	"\x0ebandwidth_mbps\x18\b \x01(\x02R\rbandwidthMbps\x12'\n" +
+	"\x0fallowed_regions\x18\t \x03(\tR\x0eallowedRegions\")\n" +
+	"\x0eExitWithdrawal\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\"L\n" +
+	"\x12ReleaseExitRequest\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12\x1d\n" +
+	"\n" +
+	"session_id\x18\x02 \x01(\tR\tsessionId2\x98\x04\n" +
 	"\x10SuperNodeService\x12K\n" +
 	"\x12RegisterClientPeer\x12\x1d.dvpn.PeerRegistrationRequest\x1a\x16.dvpn.RegisterResponse\x12D\n" +
 	"\x14PeerSessionHeartbeat\x12!.dvpn.PeerSessionHeartbeatRequest\x1a\t.dvpn.Ack\x12@\n" +
 	"\x0fRequestExitPeer\x12\x15.dvpn.ExitPeerRequest\x1a\x16.dvpn.ExitPeerResponse\x127\n" +
-	"\vRequestExit\x12\x11.dvpn.ExitRequest\x1a\x15.dvpn.WireguardConfigB\x06Z\x04./pbb\x06proto3"
+	"\vRequestExit\x12\x11.dvpn.ExitRequest\x1a\x15.dvpn.WireguardConfig\x123\n" +
+	"\rAdvertiseExit\x12\x17.dvpn.ExitAdvertisement\x1a\t.dvpn.Ack\x12/\n" +
+	"\fWithdrawExit\x12\x14.dvpn.ExitWithdrawal\x1a\t.dvpn.Ack\x122\n" +
+	"\vReleaseExit\x12\x18.dvpn.ReleaseExitRequest\x1a\t.dvpn.Ack\x120\n" +
+	"\n" +
+	"EndSession\x12\x17.dvpn.EndSessionRequest\x1a\t.dvpn.Ack\x12*\n" +
+	"\vAdmitClient\x12\x10.dvpn.ExitTicket\x1a\t.dvpn.AckB\x06Z\x04./pbb\x06proto3"
 
 var (
 	file_super_node_proto_rawDescOnce sync.Once
@@ -635,7 +1094,7 @@ func file_super_node_proto_rawDescGZIP() []byte {
 	return file_super_node_proto_rawDescData
 }
 
-var file_super_node_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_super_node_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
 var file_super_node_proto_goTypes = []any{
 	(*PeerRegistrationRequest)(nil),     // 0: dvpn.PeerRegistrationRequest
 	(*PeerSessionHeartbeatRequest)(nil), // 1: dvpn.PeerSessionHeartbeatRequest
@@ -643,23 +1102,38 @@ var file_super_node_proto_goTypes = []any{
 	(*ExitPeerResponse)(nil),            // 3: dvpn.ExitPeerResponse
 	(*ExitRequest)(nil),                 // 4: dvpn.ExitRequest
 	(*WireguardConfig)(nil),             // 5: dvpn.WireguardConfig
-	(*RegisterResponse)(nil),            // 6: dvpn.RegisterResponse
-	(*Ack)(nil),                         // 7: dvpn.Ack
+	(*ExitAdvertisement)(nil),           // 6: dvpn.ExitAdvertisement
+	(*ExitWithdrawal)(nil),              // 7: dvpn.ExitWithdrawal
+	(*ReleaseExitRequest)(nil),          // 8: dvpn.ReleaseExitRequest
+	(*EndSessionRequest)(nil),           // 9: dvpn.EndSessionRequest
+	(*ExitTicket)(nil),                  // 10: dvpn.ExitTicket
+	(*RegisterResponse)(nil),            // 11: dvpn.RegisterResponse
+	(*Ack)(nil),                         // 12: dvpn.Ack
 }
 var file_super_node_proto_depIdxs = []int32{
-	0, // 0: dvpn.SuperNodeService.RegisterClientPeer:input_type -> dvpn.PeerRegistrationRequest
-	1, // 1: dvpn.SuperNodeService.PeerSessionHeartbeat:input_type -> dvpn.PeerSessionHeartbeatRequest
-	2, // 2: dvpn.SuperNodeService.RequestExitPeer:input_type -> dvpn.ExitPeerRequest
-	4, // 3: dvpn.SuperNodeService.RequestExit:input_type -> dvpn.ExitRequest
-	6, // 4: dvpn.SuperNodeService.RegisterClientPeer:output_type -> dvpn.RegisterResponse
-	7, // 5: dvpn.SuperNodeService.PeerSessionHeartbeat:output_type -> dvpn.Ack
-	3, // 6: dvpn.SuperNodeService.RequestExitPeer:output_type -> dvpn.ExitPeerResponse
-	5, // 7: dvpn.SuperNodeService.RequestExit:output_type -> dvpn.WireguardConfig
-	4, // [4:8] is the sub-list for method output_type
-	0, // [0:4] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	0,  // 0: dvpn.SuperNodeService.RegisterClientPeer:input_type -> dvpn.PeerRegistrationRequest
+	1,  // 1: dvpn.SuperNodeService.PeerSessionHeartbeat:input_type -> dvpn.PeerSessionHeartbeatRequest
+	2,  // 2: dvpn.SuperNodeService.RequestExitPeer:input_type -> dvpn.ExitPeerRequest
+	4,  // 3: dvpn.SuperNodeService.RequestExit:input_type -> dvpn.ExitRequest
+	6,  // 4: dvpn.SuperNodeService.AdvertiseExit:input_type -> dvpn.ExitAdvertisement
+	7,  // 5: dvpn.SuperNodeService.WithdrawExit:input_type -> dvpn.ExitWithdrawal
+	8,  // 6: dvpn.SuperNodeService.ReleaseExit:input_type -> dvpn.ReleaseExitRequest
+	9,  // 7: dvpn.SuperNodeService.EndSession:input_type -> dvpn.EndSessionRequest
+	10, // 8: dvpn.SuperNodeService.AdmitClient:input_type -> dvpn.ExitTicket
+	11, // 9: dvpn.SuperNodeService.RegisterClientPeer:output_type -> dvpn.RegisterResponse
+	12, // 10: dvpn.SuperNodeService.PeerSessionHeartbeat:output_type -> dvpn.Ack
+	3,  // 11: dvpn.SuperNodeService.RequestExitPeer:output_type -> dvpn.ExitPeerResponse
+	5,  // 12: dvpn.SuperNodeService.RequestExit:output_type -> dvpn.WireguardConfig
+	12, // 13: dvpn.SuperNodeService.AdvertiseExit:output_type -> dvpn.Ack
+	12, // 14: dvpn.SuperNodeService.WithdrawExit:output_type -> dvpn.Ack
+	12, // 15: dvpn.SuperNodeService.ReleaseExit:output_type -> dvpn.Ack
+	12, // 16: dvpn.SuperNodeService.EndSession:output_type -> dvpn.Ack
+	12, // 17: dvpn.SuperNodeService.AdmitClient:output_type -> dvpn.Ack
+	9,  // [9:18] is the sub-list for method output_type
+	0,  // [0:9] is the sub-list for method input_type
+	0,  // [0:0] is the sub-list for extension type_name
+	0,  // [0:0] is the sub-list for extension extendee
+	0,  // [0:0] is the sub-list for field type_name
 }
 
 func init() { file_super_node_proto_init() }
@@ -668,13 +1142,14 @@ func file_super_node_proto_init() {
 		return
 	}
 	file_base_node_proto_init()
+	file_exit_peer_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_super_node_proto_rawDesc), len(file_super_node_proto_rawDesc)),
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   9,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
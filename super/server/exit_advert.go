@@ -0,0 +1,57 @@
+package server
+
+import (
+	"Super_node/pb"
+	"context"
+	"log"
+	"time"
+)
+
+// AdvertiseExit opts a registered peer in as an exit.
+func (s *SuperNodeServer) AdvertiseExit(ctx context.Context, req *pb.ExitAdvertisement) (*pb.Ack, error) {
+	peer, ok := s.registeredPeers.Get(req.PeerId)
+	if !ok {
+		return &pb.Ack{Received: false, Message: "Peer not found"}, nil
+	}
+	if err := checkCaller(ctx, peer.PublicKey); err != nil {
+		log.Printf("❌ Rejected exit advertisement of peer %s: %v", req.PeerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+
+	s.exitPeers.Advertise(&ExitPeerInfo{
+		PeerId:         req.PeerId,
+		PublicKey:      req.PublicKey,
+		EndpointIp:     req.EndpointIp,
+		EndpointPort:   req.EndpointPort,
+		GrpcPort:       req.GrpcPort,
+		AllowedIps:     req.AllowedIps,
+		Region:         peer.Region,
+		BandwidthMbps:  req.BandwidthMbps,
+		MaxSessions:    req.MaxSessions,
+		AllowedRegions: req.AllowedRegions,
+		LastSeen:       time.Now(),
+	})
+
+	log.Printf("🚪 Peer %s advertised as exit at %s:%s | Sessions: %d | BW: %.1f Mbps | Regions: %v",
+		req.PeerId, req.EndpointIp, req.EndpointPort, req.MaxSessions, req.BandwidthMbps, req.AllowedRegions)
+	return &pb.Ack{Received: true, Message: "Exit advertised"}, nil
+}
+
+// WithdrawExit takes a peer out of the exit catalog.
+func (s *SuperNodeServer) WithdrawExit(ctx context.Context, req *pb.ExitWithdrawal) (*pb.Ack, error) {
+	peer, ok := s.registeredPeers.Get(req.PeerId)
+	if !ok {
+		return &pb.Ack{Received: false, Message: "Peer not found"}, nil
+	}
+	if err := checkCaller(ctx, peer.PublicKey); err != nil {
+		log.Printf("❌ Rejected exit withdrawal of peer %s: %v", req.PeerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+
+	if !s.exitPeers.Withdraw(req.PeerId) {
+		return &pb.Ack{Received: false, Message: "Exit not advertised"}, nil
+	}
+
+	log.Printf("🚪 Peer %s withdrew as exit", req.PeerId)
+	return &pb.Ack{Received: true, Message: "Exit withdrawn"}, nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import "sync"
+
+// ExitCatalog is the concurrency-safe set of peers that advertised
+// themselves as exits.
+type ExitCatalog struct {
+	mu    sync.RWMutex
+	exits map[string]*ExitPeerInfo
+}
+
+func NewExitCatalog() *ExitCatalog {
+	return &ExitCatalog{exits: make(map[string]*ExitPeerInfo)}
+}
+
+// Advertise inserts or replaces the exit record of info.PeerId.
+func (c *ExitCatalog) Advertise(info *ExitPeerInfo) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.exits[info.PeerId] = info
+}
+
+// Withdraw removes peerID and reports whether it was advertised.
+func (c *ExitCatalog) Withdraw(peerID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	_, ok := c.exits[peerID]
+	delete(c.exits, peerID)
+	return ok
+}
+
+// Get returns a copy of the exit record of peerID.
+func (c *ExitCatalog) Get(peerID string) (ExitPeerInfo, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	e, ok := c.exits[peerID]
+	if !ok {
+		return ExitPeerInfo{}, false
+	}
+	return *e, true
+}
+
+// List returns copies of every advertised exit.
+func (c *ExitCatalog) List() []*ExitPeerInfo {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	out := make([]*ExitPeerInfo, 0, len(c.exits))
+	for _, e := range c.exits {
+		cp := *e
+		cp.AllowedRegions = append([]string(nil), e.AllowedRegions...)
+		out = append(out, &cp)
+	}
+	return out
+}
+
+// allows reports whether clients from region may use e.
+func (e *ExitPeerInfo) allows(region string) bool {
+	if len(e.AllowedRegions) == 0 {
+		return true
+	}
+	for _, r := range e.AllowedRegions {
+		if r == region {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	super "Super_node/crypto"
+	"Super_node/pb"
+	"fmt"
+)
+
+// checkClientKey checks that the WireGuard key of an exit request is signed
+// by the identity key the requester registered with us.
+func checkClientKey(requester *ClientPeerInfo, req *pb.ExitRequest) error {
+	if req.ClientKeySignature == "" {
+		return fmt.Errorf("client key is not signed")
+	}
+	if req.ClientIdentityKey != requester.PublicKey {
+		return fmt.Errorf("client key is signed by a key peer %s did not register", requester.PeerID)
+	}
+	payload := super.ClientKeyPayload(req.PeerId, req.ClientPublicKey, req.RequestedRegion, req.ClientKeySignedAt)
+	if !super.VerifyKeyPayload(req.ClientIdentityKey, payload, req.ClientKeySignature) {
+		return fmt.Errorf("invalid client key signature")
+	}
+	return nil
+}
+
+// checkExitAnswer checks that the answer of an exit is signed by the
+// identity key it registered with us and covers the session and client key
+// of req.
+func checkExitAnswer(exit *ClientPeerInfo, info *pb.ExitPeerInfoResponse, req *pb.ExitPeerRequest) error {
+	if info.Signature == "" {
+		return fmt.Errorf("exit answer is not signed")
+	}
+	if info.ExitPeerId != exit.PeerID || info.ExitIdentityKey != exit.PublicKey {
+		return fmt.Errorf("exit answer is signed by a key exit %s did not register", exit.PeerID)
+	}
+	payload := super.ExitKeyPayload(info.ExitPeerId, info.PublicKey, info.EndpointIp, info.EndpointPort,
+		info.ClientIp, req.SessionId, req.ClientPublicKey)
+	if !super.VerifyKeyPayload(info.ExitIdentityKey, payload, info.Signature) {
+		return fmt.Errorf("invalid exit signature")
+	}
+	return nil
+}
+
+// checkAdvertised reports an exit whose answer does not describe the
+// WireGuard endpoint it advertised, so a client is never sent somewhere the
+// catalog did not list.
+func checkAdvertised(ad ExitPeerInfo, info *pb.ExitPeerInfoResponse) error {
+	switch {
+	case info.PublicKey != ad.PublicKey:
+		return fmt.Errorf("answered with WireGuard key %s, advertised %s", info.PublicKey, ad.PublicKey)
+	case info.EndpointIp != ad.EndpointIp || info.EndpointPort != ad.EndpointPort:
+		return fmt.Errorf("answered with endpoint %s:%s, advertised %s:%s",
+			info.EndpointIp, info.EndpointPort, ad.EndpointIp, ad.EndpointPort)
+	case info.AllowedIps != ad.AllowedIps:
+		return fmt.Errorf("answered with allowed IPs %s, advertised %s", info.AllowedIps, ad.AllowedIps)
+	}
+	return nil
+}
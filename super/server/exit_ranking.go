@@ -0,0 +1,97 @@
+package server
+
+import (
+	"sort"
+	"time"
+)
+
+// Weights of each factor in an exit peer's score. They sum to 1 so a score
+// is always in [0, 1]. Packet loss is not scored, since peers do not
+// measure it.
+const (
+	exitWeightLoad     = 0.25
+	exitWeightLatency  = 0.30
+	exitWeightSessions = 0.20
+	exitWeightUptime   = 0.15
+	exitWeightFailures = 0.10
+
+	// Reference values at which a factor is considered "good enough", or
+	// for throughput, at which an exit counts as half loaded.
+	exitRefThroughputMbps = 100.0
+	exitRefLatencyMs      = 50.0
+	exitRefSessions       = 10.0
+	exitRefUptimeSecs     = 3600.0
+	exitRefFailures       = 3.0
+
+	// maxExitAttempts caps how many ranked exits RequestExitPeer tries,
+	// each for at most exitAttemptTimeout.
+	maxExitAttempts    = 3
+	exitAttemptTimeout = 2 * time.Second
+
+	// exitFailureWindow is how long a failed GetWireGuardInfo counts
+	// against an exit peer.
+	exitFailureWindow = 10 * time.Minute
+)
+
+// meetsExitConstraints reports whether p satisfies the requester's latency
+// limit. A zero limit means "no limit", and a peer that has not measured its
+// latency yet (zero) is not excluded. The throughput a peer reports is the
+// traffic it carries now, not what it can carry, so bandwidth minimums are
+// checked against the exit's advertised capacity instead.
+func meetsExitConstraints(p *ClientPeerInfo, maxLatency float32) bool {
+	if maxLatency > 0 && float32(p.LatencyMs) > maxLatency {
+		return false
+	}
+	return true
+}
+
+// recentFailures returns how many exit failures of p still count at now.
+func recentFailures(p *ClientPeerInfo, now time.Time) int {
+	if now.Sub(p.LastExitFailure) > exitFailureWindow {
+		return 0
+	}
+	return p.ExitFailures
+}
+
+// scoreExitPeer rates p between 0 and 1; higher is better.
+func scoreExitPeer(p *ClientPeerInfo, now time.Time) float64 {
+	// Traffic through the peer is load: the busier an exit, the lower it scores
+	load := 1 / (1 + float64(p.ThroughputMbps)/exitRefThroughputMbps)
+
+	latency := 0.5
+	if p.LatencyMs > 0 {
+		latency = 1 / (1 + float64(p.LatencyMs)/exitRefLatencyMs)
+	}
+
+	sessions := 1 - clamp01(float64(p.ExitSessions)/exitRefSessions)
+	uptime := clamp01(float64(p.SessionUptime) / exitRefUptimeSecs)
+	failures := 1 - clamp01(float64(recentFailures(p, now))/exitRefFailures)
+
+	return exitWeightLoad*load +
+		exitWeightLatency*latency +
+		exitWeightSessions*sessions +
+		exitWeightUptime*uptime +
+		exitWeightFailures*failures
+}
+
+// rankExitPeers sorts peers best first.
+func rankExitPeers(peers []*ClientPeerInfo, now time.Time) {
+	scores := make(map[string]float64, len(peers))
+	for _, p := range peers {
+		scores[p.PeerID] = scoreExitPeer(p, now)
+	}
+
+	sort.SliceStable(peers, func(i, j int) bool {
+		return scores[peers[i].PeerID] > scores[peers[j].PeerID]
+	})
+}
+
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
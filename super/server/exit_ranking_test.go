@@ -0,0 +1,51 @@
+package server
+
+import (
+	"testing"
+	"time"
+)
+
+func rankedIDs(peers []*ClientPeerInfo) []string {
+	ids := make([]string, len(peers))
+	for i, p := range peers {
+		ids[i] = p.PeerID
+	}
+	return ids
+}
+
+func TestRankExitPeersFollowsMeasuredMetrics(t *testing.T) {
+	now := time.Now()
+	fast := &ClientPeerInfo{PeerID: "fast", LatencyMs: 10, ThroughputMbps: 5, SessionUptime: 3600}
+	slow := &ClientPeerInfo{PeerID: "slow", LatencyMs: 200, ThroughputMbps: 5, SessionUptime: 3600}
+
+	peers := []*ClientPeerInfo{slow, fast}
+	rankExitPeers(peers, now)
+	if got := rankedIDs(peers); got[0] != "fast" {
+		t.Fatalf("expected the lower latency exit first, got %v", got)
+	}
+
+	// The same exits swap once the fast one is measured carrying heavy traffic
+	// and has only just joined.
+	fast.ThroughputMbps = 400
+	fast.SessionUptime = 10
+	slow.LatencyMs = 40
+	rankExitPeers(peers, now)
+	if got := rankedIDs(peers); got[0] != "slow" {
+		t.Fatalf("expected the idle, long running exit first, got %v", got)
+	}
+}
+
+func TestExitConstraintsIgnoreUnmeasuredLatency(t *testing.T) {
+	unmeasured := &ClientPeerInfo{PeerID: "new"}
+	if !meetsExitConstraints(unmeasured, 20) {
+		t.Fatal("a peer without a latency measurement must not be excluded")
+	}
+
+	measured := &ClientPeerInfo{PeerID: "far", LatencyMs: 80}
+	if meetsExitConstraints(measured, 20) {
+		t.Fatal("a peer measured above the latency limit must be excluded")
+	}
+	if !meetsExitConstraints(measured, 0) {
+		t.Fatal("a zero latency limit must not exclude anyone")
+	}
+}
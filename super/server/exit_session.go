@@ -0,0 +1,185 @@
+package server
+
+import (
+	"Super_node/pb"
+	"Super_node/utils"
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// ReleaseExit ends an exit session on behalf of the client peer that holds
+// it. The session is ended here even when the remote super cannot be told.
+func (s *SuperNodeServer) ReleaseExit(ctx context.Context, req *pb.ReleaseExitRequest) (*pb.Ack, error) {
+	peer, ok := s.registeredPeers.Get(req.PeerId)
+	if !ok {
+		return &pb.Ack{Received: false, Message: "Peer not found"}, nil
+	}
+	if err := checkCaller(ctx, peer.PublicKey); err != nil {
+		log.Printf("❌ Rejected exit release of peer %s: %v", req.PeerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+
+	sess, ok := s.sessions.Get(req.SessionId)
+	if !ok || sess.ClientID != req.PeerId {
+		return &pb.Ack{Received: false, Message: "Session not found"}, nil
+	}
+	if sess.State != SessionActive {
+		return &pb.Ack{Received: false, Message: "Session already ended"}, nil
+	}
+
+	if err := s.endClientSession(ctx, req.SessionId, "released by client"); err != nil {
+		return &pb.Ack{Received: true, Message: fmt.Sprintf("Session ended, remote super not reached: %v", err)}, nil
+	}
+	return &pb.Ack{Received: true, Message: "Session ended"}, nil
+}
+
+// super to super, ends a session served by one of our exits. Only the
+// super that requested the session may end it.
+func (s *SuperNodeServer) EndSession(ctx context.Context, req *pb.EndSessionRequest) (*pb.Ack, error) {
+	if err := s.authenticateEndSession(ctx, req); err != nil {
+		log.Printf("❌ Rejected end of session %s from %s: %v", req.SessionId, req.CallerId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+
+	sess, ok := s.served.Get(req.SessionId)
+	if !ok || sess.ClientID != req.RequesterId || sess.SuperID != req.CallerId {
+		return &pb.Ack{Received: false, Message: "Session not found"}, nil
+	}
+	if sess.State != SessionActive {
+		return &pb.Ack{Received: false, Message: "Session already ended"}, nil
+	}
+
+	if err := s.endServedSession(ctx, req.SessionId, req.Reason); err != nil {
+		return &pb.Ack{Received: true, Message: fmt.Sprintf("Session ended, exit peer not reached: %v", err)}, nil
+	}
+	return &pb.Ack{Received: true, Message: "Session ended"}, nil
+}
+
+// endClientSession ends a session of one of our client peers and tells the
+// super serving its exit, which may be this one.
+func (s *SuperNodeServer) endClientSession(ctx context.Context, id, reason string) error {
+	sess, ok := s.sessions.End(id, reason)
+	if !ok {
+		return nil
+	}
+	log.Printf("🔚 Session %s of peer %s via exit %s ended: %s", id, sess.ClientID, sess.ExitID, reason)
+
+	// Served by one of our own exits
+	if sess.SuperAddr == "" {
+		return s.endServedSession(ctx, id, reason)
+	}
+
+	conn, err := grpc.Dial(sess.SuperAddr, utils.DialOption(utils.RoleSuper))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, exitAttemptTimeout)
+	defer cancel()
+
+	endReq := &pb.EndSessionRequest{
+		SessionId:   id,
+		RequesterId: sess.ClientID,
+		Reason:      reason,
+	}
+	if err := s.signEndSession(endReq); err != nil {
+		return err
+	}
+	ack, err := pb.NewSuperNodeServiceClient(conn).EndSession(ctx, endReq)
+	if err != nil {
+		log.Printf("⚠️ Failed to end session %s on Super Node %s: %v", id, sess.SuperID, err)
+		return err
+	}
+	if !ack.Received {
+		log.Printf("⚠️ Super Node %s refused to end session %s: %s", sess.SuperID, id, ack.Message)
+		return fmt.Errorf("super node %s: %s", sess.SuperID, ack.Message)
+	}
+	return nil
+}
+
+// endServedSession ends a session served by one of our exits and has the
+// exit peer drop the client.
+func (s *SuperNodeServer) endServedSession(ctx context.Context, id, reason string) error {
+	sess, ok := s.served.End(id, reason)
+	if !ok {
+		return nil
+	}
+	s.registeredPeers.EndExit(sess.ExitID)
+	log.Printf("🔚 Served session %s of peer %s on exit %s ended: %s", id, sess.ClientID, sess.ExitID, reason)
+
+	conn, err := grpc.Dial(sess.ExitAddr, utils.DialOption(utils.RolePeer))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, exitAttemptTimeout)
+	defer cancel()
+
+	ack, err := pb.NewExitPeerServiceClient(conn).EndSession(ctx, &pb.EndSessionRequest{
+		SessionId:   id,
+		RequesterId: sess.ClientID,
+		Reason:      reason,
+	})
+	if err != nil {
+		log.Printf("⚠️ Failed to end session %s on exit peer %s: %v", id, sess.ExitID, err)
+		return err
+	}
+	if !ack.Received {
+		log.Printf("⚠️ Exit peer %s refused to end session %s: %s", sess.ExitID, id, ack.Message)
+		return fmt.Errorf("exit peer %s: %s", sess.ExitID, ack.Message)
+	}
+	return nil
+}
+
+// monitorSessions ends the sessions of client peers and exits that stopped
+// sending heartbeats and renews the exit tickets of the others, until stop
+// is closed.
+func (s *SuperNodeServer) monitorSessions(stop <-chan struct{}) {
+	ticker := time.NewTicker(sessionCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			s.checkSessions(now)
+		case <-stop:
+			return
+		}
+	}
+}
+
+func (s *SuperNodeServer) checkSessions(now time.Time) {
+	live := make(map[string]bool)
+	for _, peer := range s.registeredPeers.Live() {
+		live[peer.PeerID] = true
+	}
+
+	for _, sess := range s.sessions.Active() {
+		if !live[sess.ClientID] {
+			s.endClientSession(context.Background(), sess.ID, "client heartbeats stopped")
+			continue
+		}
+		if sess.TicketExpires.Sub(now) < ticketRenewBefore {
+			if err := s.grantTicket(context.Background(), sess.ID); err != nil {
+				log.Printf("⚠️ Failed to renew exit ticket of session %s: %v", sess.ID, err)
+			}
+		}
+	}
+	for _, sess := range s.served.Active() {
+		switch {
+		case !live[sess.ExitID]:
+			s.endServedSession(context.Background(), sess.ID, "exit heartbeats stopped")
+		case now.After(sess.TicketExpires):
+			s.endServedSession(context.Background(), sess.ID, "exit ticket expired")
+		}
+	}
+
+	s.sessions.Purge(now)
+	s.served.Purge(now)
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	super "Super_node/crypto"
+	"Super_node/pb"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"testing"
+	"time"
+)
+
+// newCallerSuper returns a super of region with its own identity key, as
+// seen by the other supers.
+func newCallerSuper(t *testing.T, region string) *SuperNodeServer {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewSupreNodeServer(nil, region)
+	s.SetNodeID(super.DeriveNodeID(region, pub))
+	s.SetSigningKey(priv)
+	return s
+}
+
+func TestEndSessionOnlyByRequestingSuper(t *testing.T) {
+	exitSuper := NewSupreNodeServer(nil, "US")
+	owner := newCallerSuper(t, "IN")
+	other := newCallerSuper(t, "EU")
+
+	// Both callers are registered supers; the base is not asked
+	now := time.Now()
+	for _, c := range []*SuperNodeServer{owner, other} {
+		pub := base64.StdEncoding.EncodeToString(c.signKey.Public().(ed25519.PublicKey))
+		exitSuper.verifiedSupers.Remember(c.nodeID, pub, now)
+	}
+
+	exitSuper.served.Start(&Session{
+		ID:            "sess-1",
+		ClientID:      "client-1",
+		ExitID:        "exit-1",
+		SuperID:       owner.nodeID,
+		ExitAddr:      "127.0.0.1:1",
+		TicketExpires: now.Add(time.Minute),
+	})
+
+	endReq := func(from *SuperNodeServer) *pb.EndSessionRequest {
+		req := &pb.EndSessionRequest{SessionId: "sess-1", RequesterId: "client-1", Reason: "test"}
+		if from != nil {
+			if err := from.signEndSession(req); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return req
+	}
+	active := func() bool {
+		sess, ok := exitSuper.served.Get("sess-1")
+		return ok && sess.State == SessionActive
+	}
+
+	if ack, _ := exitSuper.EndSession(context.Background(), endReq(nil)); ack.Received || !active() {
+		t.Fatalf("unsigned request ended the session: %s", ack.Message)
+	}
+	if ack, _ := exitSuper.EndSession(context.Background(), endReq(other)); ack.Received || !active() {
+		t.Fatalf("another super ended the session: %s", ack.Message)
+	}
+
+	forged := endReq(owner)
+	forged.Reason = "changed"
+	if ack, _ := exitSuper.EndSession(context.Background(), forged); ack.Received || !active() {
+		t.Fatalf("altered request ended the session: %s", ack.Message)
+	}
+
+	if ack, _ := exitSuper.EndSession(context.Background(), endReq(owner)); !ack.Received || active() {
+		t.Fatalf("requesting super could not end the session: %s", ack.Message)
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	super "Super_node/crypto"
+	"Super_node/pb"
+	"Super_node/utils"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"log"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Exit tickets are short-lived; the super of the client renews them while
+// the client keeps sending heartbeats.
+const (
+	exitTicketTTL     = 2 * time.Minute
+	ticketRenewBefore = time.Minute
+)
+
+// defaultGrantMbps is granted to a client that asks for no minimum rate on
+// an exit that advertised its capacity.
+const defaultGrantMbps = 5
+
+// grantBandwidth returns the rate to reserve for a client asking for at
+// least minMbps on an exit with capacityMbps, of which grantedMbps is
+// already reserved. It reports false when the exit has too little left. An
+// exit that advertised no capacity is granted what the client asked for.
+func grantBandwidth(capacityMbps, grantedMbps, minMbps float32) (float32, bool) {
+	if capacityMbps <= 0 {
+		return minMbps, true
+	}
+	free := capacityMbps - grantedMbps
+	if free <= 0 || free < minMbps {
+		return 0, false
+	}
+	if minMbps > 0 {
+		return minMbps, true
+	}
+	if free < defaultGrantMbps {
+		return free, true
+	}
+	return defaultGrantMbps, true
+}
+
+// issueTicket signs a ticket that lets the client of sess use its exit for
+// exitTicketTTL.
+func (s *SuperNodeServer) issueTicket(sess Session) (*pb.ExitTicket, error) {
+	if s.signKey == nil {
+		return nil, fmt.Errorf("no signing key set")
+	}
+
+	t := &pb.ExitTicket{
+		SessionId:       sess.ID,
+		ClientId:        sess.ClientID,
+		ClientPublicKey: sess.ClientKey,
+		ExitPeerId:      sess.ExitID,
+		ExpiresAt:       time.Now().Add(exitTicketTTL).Unix(),
+		BandwidthMbps:   sess.BandwidthMbps,
+		IssuerId:        s.nodeID,
+		IssuerRegion:    s.region,
+		IssuerPublicKey: base64.StdEncoding.EncodeToString(s.signKey.Public().(ed25519.PublicKey)),
+	}
+	t.Signature = super.SignExitTicket(s.signKey, super.ExitTicketPayload(
+		t.SessionId, t.ClientId, t.ClientPublicKey, t.ExitPeerId, t.ExpiresAt, t.BandwidthMbps, t.IssuerId, t.IssuerRegion))
+	return t, nil
+}
+
+// grantTicket issues a fresh ticket for one of our client sessions and hands
+// it to the exit, through the super serving it unless that is us.
+func (s *SuperNodeServer) grantTicket(ctx context.Context, id string) error {
+	sess, ok := s.sessions.Get(id)
+	if !ok || sess.State != SessionActive {
+		return fmt.Errorf("session %s is not active", id)
+	}
+
+	ticket, err := s.issueTicket(sess)
+	if err != nil {
+		return err
+	}
+
+	if sess.SuperAddr == "" {
+		err = s.admitClient(ctx, ticket)
+	} else {
+		err = admitClientVia(ctx, sess.SuperAddr, ticket)
+	}
+	if err != nil {
+		return err
+	}
+
+	s.sessions.Renew(id, time.Unix(ticket.ExpiresAt, 0))
+	return nil
+}
+
+func admitClientVia(ctx context.Context, superAddr string, ticket *pb.ExitTicket) error {
+	conn, err := grpc.Dial(superAddr, utils.DialOption(utils.RoleSuper))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, exitAttemptTimeout)
+	defer cancel()
+
+	ack, err := pb.NewSuperNodeServiceClient(conn).AdmitClient(ctx, ticket)
+	if err != nil {
+		return err
+	}
+	if !ack.Received {
+		return fmt.Errorf("exit ticket refused: %s", ack.Message)
+	}
+	return nil
+}
+
+// super to super, passes an exit ticket on to the exit serving its session
+func (s *SuperNodeServer) AdmitClient(ctx context.Context, ticket *pb.ExitTicket) (*pb.Ack, error) {
+	if err := s.authenticateTicket(ctx, ticket); err != nil {
+		log.Printf("❌ Rejected exit ticket for session %s: %v", ticket.SessionId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+	if err := s.admitClient(ctx, ticket); err != nil {
+		log.Printf("❌ Exit ticket for session %s not admitted: %v", ticket.SessionId, err)
+		return &pb.Ack{Received: false, Message: err.Error()}, nil
+	}
+	return &pb.Ack{Received: true, Message: "Client admitted"}, nil
+}
+
+// authenticateTicket checks that the super passing ticket on is its issuer:
+// the ticket must be signed by the issuer's key, a TLS certificate, if any,
+// must be the issuer's, and the base must know the issuer. A ticket carries
+// no nonce; passing the same ticket again only admits the same client.
+func (s *SuperNodeServer) authenticateTicket(ctx context.Context, ticket *pb.ExitTicket) error {
+	msg := super.ExitTicketPayload(ticket.SessionId, ticket.ClientId, ticket.ClientPublicKey, ticket.ExitPeerId,
+		ticket.ExpiresAt, ticket.BandwidthMbps, ticket.IssuerId, ticket.IssuerRegion)
+	return s.verifyCaller(ctx, superCaller{
+		ID:        ticket.IssuerId,
+		Region:    ticket.IssuerRegion,
+		PublicKey: ticket.IssuerPublicKey,
+		Signature: ticket.Signature,
+	}, msg)
+}
+
+// admitClient passes ticket to the exit of the session we serve it for. The
+// ticket must be issued by the super that requested the session, which is
+// us for sessions of our own clients.
+func (s *SuperNodeServer) admitClient(ctx context.Context, ticket *pb.ExitTicket) error {
+	sess, ok := s.served.Get(ticket.SessionId)
+	if !ok || sess.State != SessionActive {
+		return fmt.Errorf("session %s is not active", ticket.SessionId)
+	}
+	owner := sess.SuperID
+	if owner == "" {
+		owner = s.nodeID
+	}
+	if ticket.IssuerId != owner {
+		return fmt.Errorf("ticket for session %s issued by %s, not by its super %s", ticket.SessionId, ticket.IssuerId, owner)
+	}
+	if sess.ClientID != ticket.ClientId || sess.ExitID != ticket.ExitPeerId {
+		return fmt.Errorf("ticket does not match session %s", ticket.SessionId)
+	}
+	if ticket.BandwidthMbps != sess.BandwidthMbps {
+		return fmt.Errorf("ticket asks for %.1f Mbps, session %s was granted %.1f Mbps",
+			ticket.BandwidthMbps, ticket.SessionId, sess.BandwidthMbps)
+	}
+
+	conn, err := grpc.Dial(sess.ExitAddr, utils.DialOption(utils.RolePeer))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, exitAttemptTimeout)
+	defer cancel()
+
+	ack, err := pb.NewExitPeerServiceClient(conn).AdmitClient(ctx, ticket)
+	if err != nil {
+		return err
+	}
+	if !ack.Received {
+		return fmt.Errorf("exit peer %s: %s", sess.ExitID, ack.Message)
+	}
+
+	s.served.Renew(ticket.SessionId, time.Unix(ticket.ExpiresAt, 0))
+	log.Printf("🎫 Exit peer %s admitted %s for session %s until %s at %.1f Mbps",
+		sess.ExitID, sess.ClientID, ticket.SessionId, time.Unix(ticket.ExpiresAt, 0).Format(time.RFC3339), ticket.BandwidthMbps)
+	return nil
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"Super_node/pb"
+)
+
+func TestGrantBandwidth(t *testing.T) {
+	tests := []struct {
+		name                   string
+		capacity, granted, min float32
+		want                   float32
+		ok                     bool
+	}{
+		{"unknown capacity grants the request", 0, 0, 20, 20, true},
+		{"unknown capacity, no minimum", 0, 0, 0, 0, true},
+		{"request fits", 100, 50, 20, 20, true},
+		{"request fills the exit", 100, 80, 20, 20, true},
+		{"request does not fit", 100, 90, 20, 0, false},
+		{"no minimum gets the default", 100, 0, 0, defaultGrantMbps, true},
+		{"no minimum gets what is left", 100, 98, 0, 2, true},
+		{"exit fully granted", 100, 100, 0, 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := grantBandwidth(tt.capacity, tt.granted, tt.min)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("%s: grantBandwidth(%g, %g, %g) = %g, %v, want %g, %v",
+				tt.name, tt.capacity, tt.granted, tt.min, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestGrantedCountsActiveSessionsOfExit(t *testing.T) {
+	table := NewSessionTable()
+	table.Start(&Session{ID: "a", ExitID: "exit-1", BandwidthMbps: 10})
+	table.Start(&Session{ID: "b", ExitID: "exit-1", BandwidthMbps: 15})
+	table.Start(&Session{ID: "c", ExitID: "exit-2", BandwidthMbps: 40})
+	table.End("b", "test")
+
+	if got := table.Granted("exit-1"); got != 10 {
+		t.Fatalf("exit-1 has %g Mbps granted, want 10", got)
+	}
+	if got := table.Granted("exit-3"); got != 0 {
+		t.Fatalf("unknown exit has %g Mbps granted, want 0", got)
+	}
+}
+
+func TestReserveDoesNotOvergrantConcurrentRequests(t *testing.T) {
+	table := NewSessionTable()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var reserved []*Session
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			sess := &Session{ID: fmt.Sprintf("sess-%d", i), ExitID: "exit-1"}
+			if _, err := table.Reserve(sess, 100, 20); err == nil {
+				mu.Lock()
+				reserved = append(reserved, sess)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(reserved) != 5 {
+		t.Fatalf("%d sessions of 20 Mbps reserved on a 100 Mbps exit, want 5", len(reserved))
+	}
+	if got := table.Granted("exit-1"); got != 100 {
+		t.Fatalf("exit-1 has %g Mbps granted, want 100", got)
+	}
+
+	// A failed hand-out gives its grant back
+	table.Release(reserved[0])
+	if _, ok := table.Get(reserved[0].ID); ok {
+		t.Fatal("released session is still recorded")
+	}
+	if _, err := table.Reserve(&Session{ID: "sess-late", ExitID: "exit-1"}, 100, 20); err != nil {
+		t.Fatalf("released grant could not be reserved again: %v", err)
+	}
+}
+
+func TestReserveRefusesSessionIDInUse(t *testing.T) {
+	table := NewSessionTable()
+	owner := &Session{ID: "sess-1", ExitID: "exit-1", SuperID: "super-a"}
+	if _, err := table.Reserve(owner, 100, 60); err != nil {
+		t.Fatal(err)
+	}
+
+	// Another super replaying the ID gets neither the session nor its grant
+	thief := &Session{ID: "sess-1", ExitID: "exit-1", SuperID: "super-b"}
+	if _, err := table.Reserve(thief, 100, 60); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("reused session ID: got %v, want %v", err, ErrSessionExists)
+	}
+	if sess, _ := table.Get("sess-1"); sess.SuperID != "super-a" || sess.BandwidthMbps != 60 {
+		t.Fatalf("session was replaced: %+v", sess)
+	}
+
+	// nor once the session ended, while it is still kept
+	table.End("sess-1", "test")
+	if _, err := table.Reserve(thief, 100, 60); !errors.Is(err, ErrSessionExists) {
+		t.Fatalf("reused ID of ended session: got %v, want %v", err, ErrSessionExists)
+	}
+}
+
+func TestAdmitClientRejectsChangedGrant(t *testing.T) {
+	s := NewSupreNodeServer(nil, "US")
+	s.served.Start(&Session{
+		ID:            "sess-1",
+		ClientID:      "client-1",
+		ExitID:        "exit-1",
+		BandwidthMbps: 10,
+		ExitAddr:      "127.0.0.1:1",
+		TicketExpires: time.Now().Add(time.Minute),
+	})
+
+	err := s.admitClient(context.Background(), &pb.ExitTicket{
+		SessionId:     "sess-1",
+		ClientId:      "client-1",
+		ExitPeerId:    "exit-1",
+		BandwidthMbps: 50,
+	})
+	if err == nil {
+		t.Fatal("ticket granting more than the session was admitted")
+	}
+}
+
+func TestAdmitClientOnlyFromSessionSuper(t *testing.T) {
+	exitSuper := NewSupreNodeServer(nil, "US")
+	owner := newCallerSuper(t, "IN")
+	other := newCallerSuper(t, "EU")
+
+	// Both issuers are registered supers; the base is not asked
+	now := time.Now()
+	for _, c := range []*SuperNodeServer{owner, other} {
+		pub := base64.StdEncoding.EncodeToString(c.signKey.Public().(ed25519.PublicKey))
+		exitSuper.verifiedSupers.Remember(c.nodeID, pub, now)
+	}
+
+	sess := Session{
+		ID:            "sess-1",
+		ClientID:      "client-1",
+		ExitID:        "exit-1",
+		BandwidthMbps: 10,
+		SuperID:       owner.nodeID,
+		ExitAddr:      "127.0.0.1:1",
+		TicketExpires: now.Add(time.Minute),
+	}
+	exitSuper.served.Start(&sess)
+
+	ticket := func(from *SuperNodeServer) *pb.ExitTicket {
+		tk, err := from.issueTicket(sess)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tk
+	}
+
+	if err := exitSuper.authenticateTicket(context.Background(), ticket(owner)); err != nil {
+		t.Fatalf("ticket of the session's super rejected: %v", err)
+	}
+
+	forged := ticket(owner)
+	forged.BandwidthMbps = 100
+	if err := exitSuper.authenticateTicket(context.Background(), forged); err == nil {
+		t.Fatal("ticket changed after signing was accepted")
+	}
+	unsigned := ticket(owner)
+	unsigned.Signature = ""
+	if ack, _ := exitSuper.AdmitClient(context.Background(), unsigned); ack.Received {
+		t.Fatal("unsigned ticket was admitted")
+	}
+
+	// Another super may sign tickets, just not for sessions it did not request
+	foreign := ticket(other)
+	if err := exitSuper.authenticateTicket(context.Background(), foreign); err != nil {
+		t.Fatalf("ticket of a registered super rejected: %v", err)
+	}
+	if err := exitSuper.admitClient(context.Background(), foreign); err == nil {
+		t.Fatal("ticket of another super was admitted for the session")
+	}
+}
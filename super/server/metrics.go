@@ -0,0 +1,53 @@
+package server
+
+import "Super_node/metrics"
+
+// Load summarises the live peer table for the base node heartbeat. Live
+// peers in the exit catalog count as available exits. Latency and
+// throughput come from what peers measured for their session heartbeats:
+// latency is averaged over the peers that have measured one, so it stays
+// zero (unknown) until one has, and throughput is summed. Exit
+// bandwidth is the most any live exit has left to grant, zero without
+// exits, and unknown when a live exit advertised no capacity and so takes
+// any rate.
+func (s *SuperNodeServer) Load() metrics.Load {
+	var load metrics.Load
+	var latencySum float32
+	var latencyCount int
+	var uncapped bool
+
+	exits := make(map[string]*ExitPeerInfo)
+	for _, e := range s.exitPeers.List() {
+		exits[e.PeerId] = e
+	}
+
+	for _, peer := range s.registeredPeers.Live() {
+		load.ActivePeers++
+		if exit, ok := exits[peer.PeerID]; ok {
+			load.ExitPeersAvailable++
+			if exit.BandwidthMbps <= 0 {
+				uncapped = true
+			} else {
+				free := exit.BandwidthMbps - s.served.Granted(exit.PeerId)
+				if free > load.ExitBandwidthMbps {
+					load.ExitBandwidthMbps = free
+				}
+			}
+		}
+		if peer.LatencyMs > 0 {
+			latencySum += float32(peer.LatencyMs)
+			latencyCount++
+		}
+		load.BandwidthMbps += peer.ThroughputMbps
+	}
+
+	if uncapped {
+		load.ExitBandwidthMbps = metrics.ExitBandwidthUnknown
+	}
+	if latencyCount > 0 {
+		load.AvgLatencyMs = latencySum / float32(latencyCount)
+	}
+	load.CPUPercent, load.MemoryMB = s.process.Sample()
+
+	return load
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"Super_node/metrics"
+	"testing"
+	"time"
+)
+
+func TestLoadAggregatesMeasuredHeartbeats(t *testing.T) {
+	s := NewSupreNodeServer(nil, "IN")
+	for _, id := range []string{"a", "b", "c"} {
+		s.registeredPeers.Admit(&ClientPeerInfo{PeerID: id, LastHeartbeat: time.Now()}, 0)
+	}
+	s.exitPeers.Advertise(&ExitPeerInfo{PeerId: "c", Region: "IN"})
+
+	// c has not measured a round trip yet and is left out of the average
+	beat := func(id string, latencyMs int32, mbps float32) {
+		s.registeredPeers.Heartbeat(id, func(p *ClientPeerInfo) {
+			p.LatencyMs = latencyMs
+			p.ThroughputMbps = mbps
+		})
+	}
+	beat("a", 20, 1.5)
+	beat("b", 40, 2.5)
+	beat("c", 0, 6)
+
+	load := s.Load()
+	if load.ActivePeers != 3 || load.ExitPeersAvailable != 1 {
+		t.Fatalf("expected 3 peers and 1 exit, got %d and %d", load.ActivePeers, load.ExitPeersAvailable)
+	}
+	if load.AvgLatencyMs != 30 {
+		t.Fatalf("expected latency averaged over measured peers (30ms), got %v", load.AvgLatencyMs)
+	}
+	if load.BandwidthMbps != 10 {
+		t.Fatalf("expected summed throughput of 10 Mbps, got %v", load.BandwidthMbps)
+	}
+}
+
+func TestLoadWithoutMeasurementsReportsNoLatency(t *testing.T) {
+	s := NewSupreNodeServer(nil, "IN")
+	s.registeredPeers.Admit(&ClientPeerInfo{PeerID: "a", LastHeartbeat: time.Now()}, 0)
+
+	// Zero tells the base the latency is unknown, so its filters skip it
+	if load := s.Load(); load.AvgLatencyMs != 0 || load.BandwidthMbps != 0 {
+		t.Fatalf("expected no latency or throughput before any measurement, got %+v", load)
+	}
+}
+
+func TestLoadReportsExitBandwidthLeft(t *testing.T) {
+	s := NewSupreNodeServer(nil, "IN")
+	s.registeredPeers.Admit(&ClientPeerInfo{PeerID: "exit-1", LastHeartbeat: time.Now()}, 0)
+	s.exitPeers.Advertise(&ExitPeerInfo{PeerId: "exit-1", Region: "IN", BandwidthMbps: 10})
+
+	if got := s.Load().ExitBandwidthMbps; got != 10 {
+		t.Fatalf("expected 10 Mbps left on an idle exit, got %v", got)
+	}
+
+	// Fully granted: known to have nothing left, not unknown
+	s.served.Start(&Session{ID: "sess-1", ExitID: "exit-1", BandwidthMbps: 10})
+	if got := s.Load().ExitBandwidthMbps; got != 0 {
+		t.Fatalf("expected 0 Mbps left on an exhausted exit, got %v", got)
+	}
+
+	// An exit without a capacity takes any rate
+	s.registeredPeers.Admit(&ClientPeerInfo{PeerID: "exit-2", LastHeartbeat: time.Now()}, 0)
+	s.exitPeers.Advertise(&ExitPeerInfo{PeerId: "exit-2", Region: "IN"})
+	if got := s.Load().ExitBandwidthMbps; got != metrics.ExitBandwidthUnknown {
+		t.Fatalf("expected unknown exit bandwidth with an uncapped exit, got %v", got)
+	}
+}
@@ -0,0 +1,196 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// Default lifetimes of a client peer that stops sending session heartbeats.
+const (
+	peerStaleAfter = 2 * time.Minute
+	peerDeadAfter  = 10 * time.Minute
+)
+
+// PeerTable is the concurrency-safe set of client peers registered with
+// this super. A peer is stale as soon as it has missed heartbeats for
+// staleTTL, whether or not a sweep has run yet, and is evicted by the sweep
+// once it has missed them for deadTTL.
+type PeerTable struct {
+	mu       sync.RWMutex
+	peers    map[string]*ClientPeerInfo
+	stale    map[string]bool
+	staleTTL time.Duration
+	deadTTL  time.Duration
+}
+
+func NewPeerTable(staleTTL, deadTTL time.Duration) (*PeerTable, error) {
+	if staleTTL <= 0 || deadTTL <= staleTTL {
+		return nil, fmt.Errorf("invalid TTLs: stale %s, dead %s (need 0 < stale < dead)", staleTTL, deadTTL)
+	}
+	return &PeerTable{
+		peers:    make(map[string]*ClientPeerInfo),
+		stale:    make(map[string]bool),
+		staleTTL: staleTTL,
+		deadTTL:  deadTTL,
+	}, nil
+}
+
+func (t *PeerTable) isLive(p *ClientPeerInfo, now time.Time) bool {
+	return now.Sub(p.LastHeartbeat) <= t.staleTTL
+}
+
+// Admit inserts or replaces a peer record. A peer that is not already in the
+// table is refused when max live peers are registered; max of zero or less
+// means no limit.
+func (t *PeerTable) Admit(peer *ClientPeerInfo, max int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	old, known := t.peers[peer.PeerID]
+	if !known && max > 0 && t.liveCount(time.Now()) >= max {
+		return false
+	}
+	if known {
+		// Exit usage outlives a re-registration.
+		peer.ExitSessions = old.ExitSessions
+		peer.ExitFailures = old.ExitFailures
+		peer.LastExitFailure = old.LastExitFailure
+	}
+	t.peers[peer.PeerID] = peer
+	delete(t.stale, peer.PeerID)
+	return true
+}
+
+// Heartbeat refreshes LastHeartbeat for peerID and applies update to the
+// record under the table lock. It reports whether the peer is known.
+func (t *PeerTable) Heartbeat(peerID string, update func(p *ClientPeerInfo)) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	peer, ok := t.peers[peerID]
+	if !ok {
+		return false
+	}
+
+	peer.LastHeartbeat = time.Now()
+	if update != nil {
+		update(peer)
+	}
+	if t.stale[peerID] {
+		delete(t.stale, peerID)
+		log.Printf("💚 Peer %s recovered", peerID)
+	}
+	return true
+}
+
+// RecordExit notes the outcome of using peerID as an exit: a success adds a
+// session until EndExit is called for it, a failure counts against the peer for exitFailureWindow.
+func (t *PeerTable) RecordExit(peerID string, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	peer, found := t.peers[peerID]
+	if !found {
+		return
+	}
+	if ok {
+		peer.ExitSessions++
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(peer.LastExitFailure) > exitFailureWindow {
+		peer.ExitFailures = 0
+	}
+	peer.ExitFailures++
+	peer.LastExitFailure = now
+}
+
+// EndExit notes that a session using peerID as an exit has ended.
+func (t *PeerTable) EndExit(peerID string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if peer, ok := t.peers[peerID]; ok && peer.ExitSessions > 0 {
+		peer.ExitSessions--
+	}
+}
+
+// Get returns a copy of the record for peerID, stale or not.
+func (t *PeerTable) Get(peerID string) (ClientPeerInfo, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	peer, ok := t.peers[peerID]
+	if !ok {
+		return ClientPeerInfo{}, false
+	}
+	return *peer, true
+}
+
+// Live returns copies of every peer that heard from us within the stale TTL.
+func (t *PeerTable) Live() []*ClientPeerInfo {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	now := time.Now()
+	var out []*ClientPeerInfo
+	for _, p := range t.peers {
+		if t.isLive(p, now) {
+			cp := *p
+			out = append(out, &cp)
+		}
+	}
+	return out
+}
+
+func (t *PeerTable) liveCount(now time.Time) int {
+	n := 0
+	for _, p := range t.peers {
+		if t.isLive(p, now) {
+			n++
+		}
+	}
+	return n
+}
+
+// Sweep logs peers that went stale and evicts the ones that are dead.
+func (t *PeerTable) Sweep(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, p := range t.peers {
+		age := now.Sub(p.LastHeartbeat)
+		switch {
+		case age > t.deadTTL:
+			delete(t.peers, id)
+			delete(t.stale, id)
+			log.Printf("🗑️ Evicted peer %s, last heartbeat: %s", id, p.LastHeartbeat.Format(time.RFC3339))
+		case age > t.staleTTL && !t.stale[id]:
+			t.stale[id] = true
+			log.Printf("❌ Peer %s is stale, last heartbeat: %s", id, p.LastHeartbeat.Format(time.RFC3339))
+		}
+	}
+}
+
+// Run sweeps the table until stop is closed.
+func (t *PeerTable) Run(stop <-chan struct{}) {
+	interval := t.staleTTL / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case now := <-ticker.C:
+			t.Sweep(now)
+		case <-stop:
+			return
+		}
+	}
+}
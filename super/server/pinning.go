@@ -0,0 +1,64 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// peerIDHashBytes is how many bytes of the key hash go into a peer ID.
+const peerIDHashBytes = 10
+
+var (
+	ErrIDMismatch  = errors.New("peer ID is not derived from its public key")
+	ErrKeyMismatch = errors.New("peer ID is pinned to a different public key")
+)
+
+// derivePeerID returns the ID a client peer with the given base64 public key
+// must use: peer-<region>-<hex of the leading bytes of sha256(key)>.
+func derivePeerID(region, pubKeyBase64 string) (string, error) {
+	pub, err := base64.StdEncoding.DecodeString(pubKeyBase64)
+	if err != nil {
+		return "", fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
+	}
+	sum := sha256.Sum256(pub)
+	return fmt.Sprintf("peer-%s-%s", region, hex.EncodeToString(sum[:peerIDHashBytes])), nil
+}
+
+// KeyPins remembers the first public key seen for each peer ID (trust on
+// first use), so a returning ID must prove possession of its original key.
+type KeyPins struct {
+	mu   sync.RWMutex
+	pins map[string]string
+}
+
+func NewKeyPins() *KeyPins {
+	return &KeyPins{pins: make(map[string]string)}
+}
+
+// CheckAndPin verifies that id is derived from pubKeyBase64 and matches any
+// earlier pin, pinning the key on first use.
+func (p *KeyPins) CheckAndPin(id, region, pubKeyBase64 string) error {
+	expected, err := derivePeerID(region, pubKeyBase64)
+	if err != nil {
+		return err
+	}
+	if id != expected {
+		return fmt.Errorf("%w: got %s, want %s", ErrIDMismatch, id, expected)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if pinned, ok := p.pins[id]; ok {
+		if pinned != pubKeyBase64 {
+			return fmt.Errorf("%w: %s", ErrKeyMismatch, id)
+		}
+		return nil
+	}
+	p.pins[id] = pubKeyBase64
+	return nil
+}
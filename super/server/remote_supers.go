@@ -0,0 +1,111 @@
+package server
+
+import (
+	"Super_node/pb"
+	"math/rand"
+	"sort"
+	"sync"
+)
+
+const (
+	// remoteCandidateCount is how many remote supers RequestExit asks the
+	// base for, so it has somewhere to go when one fails.
+	remoteCandidateCount = 5
+
+	// Reference values for scoring a remote super.
+	remoteRefLatencyMs = 50.0
+)
+
+type remoteStat struct {
+	successes int
+	failures  int
+}
+
+// RemoteStats records how often each remote super node served an exit
+// request.
+type RemoteStats struct {
+	mu    sync.Mutex
+	stats map[string]*remoteStat
+}
+
+func NewRemoteStats() *RemoteStats {
+	return &RemoteStats{stats: make(map[string]*remoteStat)}
+}
+
+// Record notes the outcome of one exit request sent to nodeID.
+func (r *RemoteStats) Record(nodeID string, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, found := r.stats[nodeID]
+	if !found {
+		st = &remoteStat{}
+		r.stats[nodeID] = st
+	}
+	if ok {
+		st.successes++
+	} else {
+		st.failures++
+	}
+}
+
+// SuccessRate returns the smoothed share of successful requests to nodeID.
+// A node never tried before rates 0.5.
+func (r *RemoteStats) SuccessRate(nodeID string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	st, found := r.stats[nodeID]
+	if !found {
+		return 0.5
+	}
+	return float64(st.successes+1) / float64(st.successes+st.failures+2)
+}
+
+// scoreRemoteSuper rates n between 0 and 1 from its load, its latency and
+// how reliable it has been for us; higher is better.
+func (r *RemoteStats) scoreRemoteSuper(n *pb.SuperNode) float64 {
+	latency := 0.5
+	if n.AvgLatencyMs > 0 {
+		latency = 1 / (1 + float64(n.AvgLatencyMs)/remoteRefLatencyMs)
+	}
+
+	headroom := 0.5
+	if n.MaxPeers > 0 {
+		headroom = clamp01(float64(n.MaxPeers-n.ActivePeers) / float64(n.MaxPeers))
+	}
+
+	return r.SuccessRate(n.NodeId) * (latency + headroom) / 2
+}
+
+// orderRemoteSupers returns the order in which to try nodes. The first one
+// is picked by power of two choices, the better of two random candidates,
+// so load spreads across supers instead of piling onto the single best.
+// The rest follow best first as fallbacks.
+func (r *RemoteStats) orderRemoteSupers(nodes []*pb.SuperNode) []*pb.SuperNode {
+	ordered := append([]*pb.SuperNode(nil), nodes...)
+	if len(ordered) < 2 {
+		return ordered
+	}
+
+	scores := make(map[string]float64, len(ordered))
+	for _, n := range ordered {
+		scores[n.NodeId] = r.scoreRemoteSuper(n)
+	}
+
+	i, j := rand.Intn(len(ordered)), rand.Intn(len(ordered)-1)
+	if j >= i {
+		j++
+	}
+	first := i
+	if scores[ordered[j].NodeId] > scores[ordered[i].NodeId] {
+		first = j
+	}
+	ordered[0], ordered[first] = ordered[first], ordered[0]
+
+	rest := ordered[1:]
+	sort.SliceStable(rest, func(a, b int) bool {
+		return scores[rest[a].NodeId] > scores[rest[b].NodeId]
+	})
+	return ordered
+}
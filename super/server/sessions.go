@@ -0,0 +1,220 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// How long ended sessions are kept for lookups before they are dropped, and
+// how often sessions are checked against the heartbeats of their peers.
+const (
+	sessionRetention     = 10 * time.Minute
+	sessionCheckInterval = 15 * time.Second
+)
+
+var (
+	ErrSessionExists = errors.New("session ID already in use")
+	ErrNoBandwidth   = errors.New("exit has no bandwidth left")
+)
+
+// SessionState describes where an exit session is in its lifecycle.
+type SessionState int
+
+const (
+	SessionActive SessionState = iota
+	SessionEnded
+)
+
+func (s SessionState) String() string {
+	switch s {
+	case SessionActive:
+		return "active"
+	case SessionEnded:
+		return "ended"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(s))
+	}
+}
+
+// Session is one client using one exit. The super of the client records the
+// remote super that serves the exit and when the exit ticket it issued runs
+// out; the super of the exit records the exit peer's service address and the
+// super that requested the exit, which is empty for our own clients.
+type Session struct {
+	ID            string
+	ClientID      string
+	ClientKey     string
+	ExitID        string
+	Region        string
+	BandwidthMbps float32
+	SuperID       string
+	SuperAddr     string
+	ExitAddr      string
+	StartedAt     time.Time
+	EndedAt       time.Time
+	TicketExpires time.Time
+	State         SessionState
+	EndReason     string
+}
+
+// SessionTable is the concurrency-safe set of exit sessions known to this
+// super.
+type SessionTable struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+func NewSessionTable() *SessionTable {
+	return &SessionTable{sessions: make(map[string]*Session)}
+}
+
+// newSessionID returns a random session ID.
+func newSessionID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic("Failed to generate session ID: " + err.Error())
+	}
+	return hex.EncodeToString(b)
+}
+
+// Start records sess as active.
+func (t *SessionTable) Start(sess *Session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sess.State = SessionActive
+	if sess.StartedAt.IsZero() {
+		sess.StartedAt = time.Now()
+	}
+	t.sessions[sess.ID] = sess
+}
+
+// Reserve grants sess bandwidth on its exit, of capacity Mbps, and starts
+// it in one step, so concurrent requests cannot both take what is left.
+// Nothing is started when the exit has no room for min Mbps, or when a
+// session with the same ID is known: the ID comes from the requesting
+// super, and must not replace another super's session.
+func (t *SessionTable) Reserve(sess *Session, capacity, min float32) (float32, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if _, ok := t.sessions[sess.ID]; ok {
+		return 0, fmt.Errorf("%w: %s", ErrSessionExists, sess.ID)
+	}
+
+	var granted float32
+	for _, other := range t.sessions {
+		if other.State == SessionActive && other.ExitID == sess.ExitID {
+			granted += other.BandwidthMbps
+		}
+	}
+	bw, ok := grantBandwidth(capacity, granted, min)
+	if !ok {
+		return 0, ErrNoBandwidth
+	}
+
+	sess.BandwidthMbps = bw
+	sess.State = SessionActive
+	if sess.StartedAt.IsZero() {
+		sess.StartedAt = time.Now()
+	}
+	t.sessions[sess.ID] = sess
+	return bw, nil
+}
+
+// Release drops sess, reserved but never handed out, unless it was replaced
+// since.
+func (t *SessionTable) Release(sess *Session) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.sessions[sess.ID] == sess {
+		delete(t.sessions, sess.ID)
+	}
+}
+
+// Get returns a copy of the session with id, ended or not.
+func (t *SessionTable) Get(id string) (Session, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sess, ok := t.sessions[id]
+	if !ok {
+		return Session{}, false
+	}
+	return *sess, true
+}
+
+// End marks the session with id ended and returns a copy of it. It reports
+// false when the session is unknown or had already ended.
+func (t *SessionTable) End(id, reason string) (Session, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sess, ok := t.sessions[id]
+	if !ok || sess.State != SessionActive {
+		return Session{}, false
+	}
+	sess.State = SessionEnded
+	sess.EndedAt = time.Now()
+	sess.EndReason = reason
+	return *sess, true
+}
+
+// Renew records that the ticket of the active session with id now runs
+// until expires.
+func (t *SessionTable) Renew(id string, expires time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	sess, ok := t.sessions[id]
+	if !ok || sess.State != SessionActive {
+		return false
+	}
+	sess.TicketExpires = expires
+	return true
+}
+
+// Active returns copies of every active session.
+func (t *SessionTable) Active() []Session {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var out []Session
+	for _, sess := range t.sessions {
+		if sess.State == SessionActive {
+			out = append(out, *sess)
+		}
+	}
+	return out
+}
+
+// Granted returns the bandwidth granted to the active sessions on exitID.
+func (t *SessionTable) Granted(exitID string) float32 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var total float32
+	for _, sess := range t.sessions {
+		if sess.State == SessionActive && sess.ExitID == exitID {
+			total += sess.BandwidthMbps
+		}
+	}
+	return total
+}
+
+// Purge drops sessions that ended more than sessionRetention before now.
+func (t *SessionTable) Purge(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for id, sess := range t.sessions {
+		if sess.State == SessionEnded && now.Sub(sess.EndedAt) > sessionRetention {
+			delete(t.sessions, id)
+		}
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	super "Super_node/crypto"
+	"Super_node/pb"
+	"Super_node/utils"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// verifiedSuperTTL is how long a super confirmed by the base is trusted
+// before the base is asked again.
+const verifiedSuperTTL = time.Minute
+
+var ErrUnauthenticatedSuper = errors.New("caller is not an authenticated super node")
+
+// VerifiedSupers remembers which (node ID, key) pairs the base confirmed
+// recently, so every exit request does not cost a base round trip.
+type VerifiedSupers struct {
+	mu    sync.Mutex
+	until map[string]time.Time
+}
+
+func NewVerifiedSupers() *VerifiedSupers {
+	return &VerifiedSupers{until: make(map[string]time.Time)}
+}
+
+func (v *VerifiedSupers) Known(nodeID, pubKey string, now time.Time) bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return now.Before(v.until[nodeID+"|"+pubKey])
+}
+
+func (v *VerifiedSupers) Remember(nodeID, pubKey string, now time.Time) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for k, t := range v.until {
+		if !now.Before(t) {
+			delete(v.until, k)
+		}
+	}
+	v.until[nodeID+"|"+pubKey] = now.Add(verifiedSuperTTL)
+}
+
+// SetSigningKey sets the identity key this super signs its requests to
+// other supers with.
+func (s *SuperNodeServer) SetSigningKey(priv ed25519.PrivateKey) {
+	s.signKey = priv
+}
+
+// signExitRequest fills in the caller fields of req and signs it.
+func (s *SuperNodeServer) signExitRequest(req *pb.ExitPeerRequest) error {
+	if s.signKey == nil {
+		return fmt.Errorf("no signing key set")
+	}
+	req.CallerId = s.nodeID
+	req.CallerRegion = s.region
+	req.CallerPublicKey = base64.StdEncoding.EncodeToString(s.signKey.Public().(ed25519.PublicKey))
+	req.Nonce = super.GenerateNonce()
+	req.SignedAt = time.Now().Unix()
+	req.Signature = super.SignExitRequest(s.signKey, req.CallerId, req.RequesterId, req.RequesterRegion,
+		req.RequestedRegion, req.ClientPublicKey, req.SessionId, req.Nonce, req.SignedAt)
+	return nil
+}
+
+// signEndSession fills in the caller fields of req and signs it.
+func (s *SuperNodeServer) signEndSession(req *pb.EndSessionRequest) error {
+	if s.signKey == nil {
+		return fmt.Errorf("no signing key set")
+	}
+	req.CallerId = s.nodeID
+	req.CallerRegion = s.region
+	req.CallerPublicKey = base64.StdEncoding.EncodeToString(s.signKey.Public().(ed25519.PublicKey))
+	req.Nonce = super.GenerateNonce()
+	req.SignedAt = time.Now().Unix()
+	req.Signature = super.SignEndSession(s.signKey, req.CallerId, req.SessionId, req.RequesterId,
+		req.Reason, req.Nonce, req.SignedAt)
+	return nil
+}
+
+// superCaller holds the caller fields a super signs its requests to other
+// supers with.
+type superCaller struct {
+	ID        string
+	Region    string
+	PublicKey string
+	Nonce     string
+	SignedAt  int64
+	Signature string
+}
+
+// authenticateSuper checks that req comes from the super it names: the
+// signature must verify under a key that derives the caller ID, a TLS
+// certificate, if any, must be that super's, and the base must know the
+// super as registered in its region.
+func (s *SuperNodeServer) authenticateSuper(ctx context.Context, req *pb.ExitPeerRequest) error {
+	msg := super.ExitRequestPayload(req.CallerId, req.RequesterId, req.RequesterRegion,
+		req.RequestedRegion, req.ClientPublicKey, req.SessionId, req.Nonce, req.SignedAt)
+	return s.authenticateCaller(ctx, superCaller{
+		ID:        req.CallerId,
+		Region:    req.CallerRegion,
+		PublicKey: req.CallerPublicKey,
+		Nonce:     req.Nonce,
+		SignedAt:  req.SignedAt,
+		Signature: req.Signature,
+	}, msg)
+}
+
+// authenticateEndSession is authenticateSuper for requests to end a
+// session.
+func (s *SuperNodeServer) authenticateEndSession(ctx context.Context, req *pb.EndSessionRequest) error {
+	msg := super.EndSessionPayload(req.CallerId, req.SessionId, req.RequesterId, req.Reason, req.Nonce, req.SignedAt)
+	return s.authenticateCaller(ctx, superCaller{
+		ID:        req.CallerId,
+		Region:    req.CallerRegion,
+		PublicKey: req.CallerPublicKey,
+		Nonce:     req.Nonce,
+		SignedAt:  req.SignedAt,
+		Signature: req.Signature,
+	}, msg)
+}
+
+// authenticateCaller checks that msg was signed by the super caller names
+// and was not seen before.
+func (s *SuperNodeServer) authenticateCaller(ctx context.Context, caller superCaller, msg string) error {
+	if err := s.verifyCaller(ctx, caller, msg); err != nil {
+		return err
+	}
+	// Only a super the base vouches for gets nonces remembered
+	return s.superReplay.Check(caller.ID, caller.Nonce, caller.SignedAt)
+}
+
+// verifyCaller checks that msg was signed by the super caller names, that a
+// TLS certificate, if any, is that super's and that the base knows it.
+func (s *SuperNodeServer) verifyCaller(ctx context.Context, caller superCaller, msg string) error {
+	if caller.ID == "" || caller.Signature == "" {
+		return fmt.Errorf("%w: request is not signed", ErrUnauthenticatedSuper)
+	}
+
+	if id, ok := utils.PeerIdentity(ctx); ok {
+		if id.Role != utils.RoleSuper {
+			return fmt.Errorf("%w: %q is not a super node certificate", ErrCertMismatch, id.Name)
+		}
+		if id.PublicKey != caller.PublicKey {
+			return fmt.Errorf("%w: certificate %q carries a different key", ErrCertMismatch, id.Name)
+		}
+	}
+
+	pubKeyBytes, err := base64.StdEncoding.DecodeString(caller.PublicKey)
+	if err != nil || len(pubKeyBytes) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
+	}
+	if super.DeriveNodeID(caller.Region, pubKeyBytes) != caller.ID {
+		return fmt.Errorf("%w: node ID %s does not belong to its key", ErrUnauthenticatedSuper, caller.ID)
+	}
+
+	sigBytes, err := base64.StdEncoding.DecodeString(caller.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
+	}
+	if !ed25519.Verify(ed25519.PublicKey(pubKeyBytes), []byte(msg), sigBytes) {
+		return ErrInvalidSignature
+	}
+
+	now := time.Now()
+	if !s.verifiedSupers.Known(caller.ID, caller.PublicKey, now) {
+		if err := s.verifyWithBase(ctx, caller, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyWithBase asks the base whether caller is a registered super, and
+// remembers a positive answer.
+func (s *SuperNodeServer) verifyWithBase(ctx context.Context, caller superCaller, now time.Time) error {
+	ack, err := s.baseClient.VerifySuperNode(ctx, &pb.SuperNodeIdentity{
+		NodeId:    caller.ID,
+		Region:    caller.Region,
+		PublicKey: caller.PublicKey,
+	})
+	if err != nil {
+		return fmt.Errorf("could not verify super node %s with the base: %w", caller.ID, err)
+	}
+	if !ack.Received {
+		return fmt.Errorf("%w: base rejected %s: %s", ErrUnauthenticatedSuper, caller.ID, ack.Message)
+	}
+	s.verifiedSupers.Remember(caller.ID, caller.PublicKey, now)
+
+	log.Printf("✅ Super Node %s [%s] verified with the base", caller.ID, caller.Region)
+	return nil
+}
@@ -1,13 +1,20 @@
 package server
 
 import (
+	"Dvpn_common/replay"
+	"Super_node/metrics"
 	"Super_node/pb"
+	"Super_node/utils"
 	"context"
+	"crypto/ed25519"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type ClientPeerInfo struct {
@@ -25,54 +32,127 @@ type ClientPeerInfo struct {
 	LatencyMs      int32
 	PacketLoss     float32
 	ThroughputMbps float32
+
+	// Exit usage, kept by this super for ranking.
+	ExitSessions    int
+	ExitFailures    int
+	LastExitFailure time.Time
 }
 
 type ExitPeerInfo struct {
-	PeerId        string
-	PublicKey     string
-	EndpointIp    string
-	EndpointPort  string
-	AllowedIps    string
-	Region        string
-	BandwidthMbps float32
-	LatencyMs     float32
-	LastSeen      time.Time
+	PeerId         string
+	PublicKey      string
+	EndpointIp     string
+	EndpointPort   string
+	GrpcPort       string
+	AllowedIps     string
+	Region         string
+	BandwidthMbps  float32
+	LatencyMs      float32
+	MaxSessions    int32
+	AllowedRegions []string
+	LastSeen       time.Time
 }
 
 type SuperNodeServer struct {
 	pb.UnimplementedSuperNodeServiceServer
-	registeredPeers map[string]*ClientPeerInfo
-	exitPeers       map[string]*ExitPeerInfo
+	registeredPeers *PeerTable
+	exitPeers       *ExitCatalog
+	sessions        *SessionTable
+	served          *SessionTable
 	baseClient      pb.BaseNodeServiceClient
+	peerReplay      *replay.Guard
+	superReplay     *replay.Guard
+	pins            *KeyPins
+	nodeID          string
+	region          string
+	maxPeers        int
+	process         *metrics.Process
+	remotes         *RemoteStats
+	signKey         ed25519.PrivateKey
+	verifiedSupers  *VerifiedSupers
 }
 
 func NewSupreNodeServer(baseClient pb.BaseNodeServiceClient, region string) *SuperNodeServer {
+	peers, _ := NewPeerTable(peerStaleAfter, peerDeadAfter)
 	s := &SuperNodeServer{
-		registeredPeers: make(map[string]*ClientPeerInfo),
-		exitPeers:       make(map[string]*ExitPeerInfo),
+		registeredPeers: peers,
+		exitPeers:       NewExitCatalog(),
+		sessions:        NewSessionTable(),
+		served:          NewSessionTable(),
 		baseClient:      baseClient,
+		peerReplay:      replay.NewGuard(replay.MaxClockSkew, replay.NoncesPerID, replay.MaxSigners),
+		superReplay:     replay.NewGuard(replay.MaxClockSkew, replay.NoncesPerID, replay.MaxSigners),
+		pins:            NewKeyPins(),
+		region:          region,
+		process:         metrics.NewProcess(),
+		remotes:         NewRemoteStats(),
+		verifiedSupers:  NewVerifiedSupers(),
 	}
 	return s
 }
 
+// SetNodeID tells the server its own node ID, so it never redirects peers
+// back to itself.
+func (s *SuperNodeServer) SetNodeID(id string) {
+	s.nodeID = id
+}
+
+// SetPeerTable replaces the default peer table, e.g. to use other TTLs.
+func (s *SuperNodeServer) SetPeerTable(t *PeerTable) {
+	s.registeredPeers = t
+}
+
+// SetMaxPeers caps the number of registered client peers. Zero or less
+// means no limit.
+func (s *SuperNodeServer) SetMaxPeers(n int) {
+	s.maxPeers = n
+}
+
 func (s *SuperNodeServer) RegisterClientPeer(ctx context.Context, req *pb.PeerRegistrationRequest) (*pb.RegisterResponse, error) {
-	if !verifyClientPeer(
+	if err := checkCaller(ctx, req.PublicKey); err != nil {
+		log.Printf("❌ Rejected registration of peer %s: %v", req.PeerId, err)
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	if err := verifyClientPeer(
 		req.PeerId,
 		req.Region,
 		req.Os,
 		req.NatType,
 		req.Nonce,
+		req.SignedAt,
 		req.PublicKey,
 		req.Signature,
-	) {
-		log.Printf("❌ Signature verification failed for peer %s", req.PeerId)
+	); err != nil {
+		log.Printf("❌ Rejected registration of peer %s: %v", req.PeerId, err)
 		return &pb.RegisterResponse{
 			Success: false,
-			Message: "Invalid signature",
+			Message: err.Error(),
 		}, nil
 	}
 
-	s.registeredPeers[req.PeerId] = &ClientPeerInfo{
+	if err := s.pins.CheckAndPin(req.PeerId, req.Region, req.PublicKey); err != nil {
+		log.Printf("❌ Rejected registration of peer %s: %v", req.PeerId, err)
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	// Only a pinned identity gets nonces remembered
+	if err := s.peerReplay.Check(req.PeerId, req.Nonce, req.SignedAt); err != nil {
+		log.Printf("❌ Rejected registration of peer %s: %v", req.PeerId, err)
+		return &pb.RegisterResponse{
+			Success: false,
+			Message: err.Error(),
+		}, nil
+	}
+
+	peer := &ClientPeerInfo{
 		PeerID:        req.PeerId,
 		PublicKey:     req.PublicKey,
 		Version:       req.Version,
@@ -84,6 +164,9 @@ func (s *SuperNodeServer) RegisterClientPeer(ctx context.Context, req *pb.PeerRe
 		RegisteredAt:  time.Now().Format(time.RFC3339),
 		LastHeartbeat: time.Now(),
 	}
+	if !s.registeredPeers.Admit(peer, s.maxPeers) {
+		return s.redirectPeer(ctx, req.PeerId), nil
+	}
 
 	log.Printf("👤 Registered Peer: %s [%s] OS: %s NAT: %s", req.PeerId, req.Region, req.Os, req.NatType)
 
@@ -95,11 +178,41 @@ func (s *SuperNodeServer) RegisterClientPeer(ctx context.Context, req *pb.PeerRe
 	}, nil
 }
 
+// redirectPeer refuses a registration because this super is full, pointing
+// the peer at the least loaded other super of the region when the base knows
+// one.
+func (s *SuperNodeServer) redirectPeer(ctx context.Context, peerID string) *pb.RegisterResponse {
+	res := &pb.RegisterResponse{
+		Success: false,
+		Message: fmt.Sprintf("super node is at capacity (%d peers)", s.maxPeers),
+	}
+
+	list, err := s.baseClient.RequestExitRegion(ctx, &pb.ExitRegionRequest{DesiredRegion: s.region})
+	if err != nil {
+		log.Printf("⚠️ At capacity, could not ask base for another Super Node: %v", err)
+		return res
+	}
+	for _, n := range list.Nodes {
+		if n.NodeId != s.nodeID {
+			res.Redirect = n
+			break
+		}
+	}
+
+	if res.Redirect == nil {
+		log.Printf("🚫 At capacity, refused peer %s with nowhere to redirect", peerID)
+		return res
+	}
+	log.Printf("↪️ At capacity, redirected peer %s to Super Node %s", peerID, res.Redirect.NodeId)
+	res.Message = fmt.Sprintf("%s, use %s", res.Message, res.Redirect.NodeId)
+	return res
+}
+
 func (s *SuperNodeServer) PeerSessionHeartbeat(ctx context.Context, req *pb.PeerSessionHeartbeatRequest) (*pb.Ack, error) {
 	log.Printf("💓 Heartbeat from %s (exit: %s) — latency: %dms, loss: %.1f%%, throughput: %.2f Mbps, uptime: %ds",
 		req.PeerId, req.ExitPeerId, req.LatencyMs, req.PacketLoss, req.ThroughputMbps, req.SessionUptimeSecs)
 
-	peer, ok := s.registeredPeers[req.PeerId]
+	peer, ok := s.registeredPeers.Get(req.PeerId)
 	if !ok {
 		log.Printf("Heartbeat from unknown peer: %s", req.PeerId)
 		return &pb.Ack{
@@ -108,11 +221,20 @@ func (s *SuperNodeServer) PeerSessionHeartbeat(ctx context.Context, req *pb.Peer
 		}, nil
 	}
 
-	peer.LatencyMs = req.LatencyMs
-	peer.PacketLoss = req.PacketLoss
-	peer.ThroughputMbps = req.ThroughputMbps
-	peer.SessionUptime = req.SessionUptimeSecs
-	peer.LastHeartbeat = time.Now()
+	if err := checkCaller(ctx, peer.PublicKey); err != nil {
+		log.Printf("❌ Rejected heartbeat for peer %s: %v", req.PeerId, err)
+		return &pb.Ack{
+			Received: false,
+			Message:  err.Error(),
+		}, nil
+	}
+
+	s.registeredPeers.Heartbeat(req.PeerId, func(p *ClientPeerInfo) {
+		p.LatencyMs = req.LatencyMs
+		p.PacketLoss = req.PacketLoss
+		p.ThroughputMbps = req.ThroughputMbps
+		p.SessionUptime = req.SessionUptimeSecs
+	})
 
 	return &pb.Ack{
 		Received: true,
@@ -120,82 +242,184 @@ func (s *SuperNodeServer) PeerSessionHeartbeat(ctx context.Context, req *pb.Peer
 	}, nil
 }
 
+// StartPeerMonitoring runs the peer table sweeper, which marks silent peers
+// stale and evicts dead ones, and ends the sessions of peers that went
+// silent.
 func (s *SuperNodeServer) StartPeerMonitoring() {
-	go func() {
-		ticker := time.NewTicker(1 * time.Minute)
-		defer ticker.Stop()
-
-		for range ticker.C {
-			now := time.Now()
-			for peerID, info := range s.registeredPeers {
-				age := now.Sub(info.LastHeartbeat)
-				if age > 2*time.Minute {
-					log.Printf("❌ Peer %s is stale, last heartbeat: %s", peerID, info.LastHeartbeat.Format(time.RFC3339))
-				}
-			}
-		}
-	}()
+	go s.registeredPeers.Run(nil)
+	go s.monitorSessions(nil)
 }
 
 // super to super for exit peer
 func (s *SuperNodeServer) RequestExitPeer(ctx context.Context, req *pb.ExitPeerRequest) (*pb.ExitPeerResponse, error) {
+	if err := s.authenticateSuper(ctx, req); err != nil {
+		log.Printf("❌ Rejected exit request from %s for peer %s: %v", req.CallerId, req.RequesterId, err)
+		return nil, status.Errorf(codes.PermissionDenied, "exit request rejected: %v", err)
+	}
+	return s.serveExit(ctx, req)
+}
+
+// serveExit hands out one of our exits to req.RequesterId, trying the best
+// ranked candidates in turn.
+func (s *SuperNodeServer) serveExit(ctx context.Context, req *pb.ExitPeerRequest) (*pb.ExitPeerResponse, error) {
 	log.Printf("📞 Dynamically searching for exit peer in region: %s", req.RequestedRegion)
 
-	var chosen *ClientPeerInfo
-	for _, peer := range s.registeredPeers {
-		if peer.Region == req.RequestedRegion &&
-			peer.ThroughputMbps >= req.MinBandwidthMbps &&
-			float32(peer.LatencyMs) <= req.MaxLatencyMs {
+	if req.SessionId == "" {
+		req.SessionId = newSessionID()
+	}
+
+	candidates := s.exitCandidates(req)
+	if len(candidates) == 0 {
+		log.Printf("❌ No suitable exit peer found in the exit catalog")
+		return nil, fmt.Errorf("no suitable exit peer found in the exit catalog")
+	}
+
+	rankExitPeers(candidates, time.Now())
+	if len(candidates) > maxExitAttempts {
+		candidates = candidates[:maxExitAttempts]
+	}
 
-			log.Printf("✅ Candidate: %s | IP: %s:%s | Latency: %dms | BW: %.2f Mbps",
-				peer.PeerID, peer.Ip, peer.GrpcPort, peer.LatencyMs, peer.ThroughputMbps)
+	var lastErr error
+	for _, chosen := range candidates {
+		log.Printf("✅ Candidate: %s | IP: %s:%s | Latency: %dms | BW: %.2f Mbps | Uptime: %ds | Sessions: %d",
+			chosen.PeerID, chosen.Ip, chosen.GrpcPort, chosen.LatencyMs, chosen.ThroughputMbps, chosen.SessionUptime, chosen.ExitSessions)
+
+		// The grant is held while the exit answers; the exit also checks
+		// it against its capacity on admission. The session lapses unless
+		// the client's super sends a ticket in time.
+		ad, _ := s.exitPeers.Get(chosen.PeerID)
+		sess := &Session{
+			ID:            req.SessionId,
+			ClientID:      req.RequesterId,
+			ExitID:        chosen.PeerID,
+			Region:        req.RequestedRegion,
+			SuperID:       req.CallerId,
+			ExitAddr:      fmt.Sprintf("%s:%s", chosen.Ip, chosen.GrpcPort),
+			TicketExpires: time.Now().Add(exitTicketTTL),
+		}
+		granted, err := s.served.Reserve(sess, ad.BandwidthMbps, req.MinBandwidthMbps)
+		if errors.Is(err, ErrSessionExists) {
+			log.Printf("❌ Refusing exit request of %s: %v", req.CallerId, err)
+			return nil, err
+		}
+		if err != nil {
+			log.Printf("⚠️ Exit peer %s has no bandwidth left for %.1f Mbps", chosen.PeerID, req.MinBandwidthMbps)
+			lastErr = fmt.Errorf("exit %s has no bandwidth left", chosen.PeerID)
+			continue
+		}
 
-			chosen = peer
-			break // for now, pick the first match — later apply ranking logic
+		owner := sess.SuperID
+		if owner == "" {
+			owner = s.nodeID
+		}
+		infoRes, err := fetchWireGuardInfo(ctx, chosen, req, owner)
+		if err == nil {
+			err = checkExitAnswer(chosen, infoRes, req)
+		}
+		if err == nil {
+			if ad, ok := s.exitPeers.Get(chosen.PeerID); !ok {
+				err = fmt.Errorf("exit withdrew while answering")
+			} else {
+				err = checkAdvertised(ad, infoRes)
+			}
+		}
+		if err != nil {
+			log.Printf("❌ Failed to fetch WireGuard info from Exit Peer %s: %v", chosen.PeerID, err)
+			s.served.Release(sess)
+			s.registeredPeers.RecordExit(chosen.PeerID, false)
+			lastErr = err
+			continue
 		}
+		s.registeredPeers.RecordExit(chosen.PeerID, true)
+
+		log.Printf("✅ WireGuard info received from exit peer %s: %s:%s | Session: %s | Granted: %.1f Mbps",
+			chosen.PeerID, infoRes.EndpointIp, infoRes.EndpointPort, req.SessionId, granted)
+
+		return &pb.ExitPeerResponse{
+			PublicKey:            infoRes.PublicKey,
+			EndpointIp:           infoRes.EndpointIp,
+			EndpointPort:         infoRes.EndpointPort,
+			AllowedIps:           infoRes.AllowedIps,
+			PeerId:               chosen.PeerID,
+			Region:               req.RequestedRegion,
+			ClientIp:             infoRes.ClientIp,
+			SessionId:            req.SessionId,
+			ExitIdentityKey:      infoRes.ExitIdentityKey,
+			ExitSignature:        infoRes.Signature,
+			GrantedBandwidthMbps: granted,
+		}, nil
 	}
 
-	if chosen == nil {
-		log.Printf("❌ No suitable exit peer found in registered peers")
-		return nil, fmt.Errorf("no suitable exit peer found in registered peers")
+	return nil, fmt.Errorf("no exit peer answered, last error: %w", lastErr)
+}
+
+// exitCandidates returns the advertised exits of the requested region that
+// are live, have session and bandwidth capacity left, accept the
+// requester's region and meet its limits. Each is returned as its peer record with GrpcPort set to
+// the advertised exit service port.
+func (s *SuperNodeServer) exitCandidates(req *pb.ExitPeerRequest) []*ClientPeerInfo {
+	live := make(map[string]*ClientPeerInfo)
+	for _, peer := range s.registeredPeers.Live() {
+		live[peer.PeerID] = peer
 	}
 
-	exitPeerAddr := fmt.Sprintf("%s:%s", chosen.Ip, chosen.GrpcPort)
-	log.Printf("🔁 Connecting to exit peer %s at %s", chosen.PeerID, exitPeerAddr)
+	var candidates []*ClientPeerInfo
+	for _, exit := range s.exitPeers.List() {
+		peer, ok := live[exit.PeerId]
+		if !ok {
+			if _, known := s.registeredPeers.Get(exit.PeerId); !known {
+				s.exitPeers.Withdraw(exit.PeerId) // evicted without withdrawing
+			}
+			continue
+		}
 
-	conn, err := grpc.Dial(exitPeerAddr, grpc.WithInsecure())
-	if err != nil {
-		log.Printf("❌ Failed to connect to Exit Peer at %s: %v", exitPeerAddr, err)
-		return nil, err
+		if exit.Region != req.RequestedRegion ||
+			exit.PeerId == req.RequesterId ||
+			!exit.allows(req.RequesterRegion) ||
+			(exit.MaxSessions > 0 && peer.ExitSessions >= int(exit.MaxSessions)) ||
+			!meetsExitConstraints(peer, req.MaxLatencyMs) {
+			continue
+		}
+		if _, ok := grantBandwidth(exit.BandwidthMbps, s.served.Granted(exit.PeerId), req.MinBandwidthMbps); !ok {
+			continue
+		}
+
+		if exit.GrpcPort != "" {
+			peer.GrpcPort = exit.GrpcPort
+		}
+		candidates = append(candidates, peer)
 	}
-	defer conn.Close()
+	return candidates
+}
 
-	exitClient := pb.NewExitPeerServiceClient(conn)
+// fetchWireGuardInfo asks the exit peer for its WireGuard endpoint, passing
+// on the requester's signed key and the super whose tickets admit it.
+func fetchWireGuardInfo(ctx context.Context, exit *ClientPeerInfo, req *pb.ExitPeerRequest, superID string) (*pb.ExitPeerInfoResponse, error) {
+	exitPeerAddr := fmt.Sprintf("%s:%s", exit.Ip, exit.GrpcPort)
+	log.Printf("🔁 Connecting to exit peer %s at %s", exit.PeerID, exitPeerAddr)
 
-	infoRes, err := exitClient.GetWireGuardInfo(ctx, &pb.ExitPeerInfoRequest{
-		RequesterId:      req.RequesterId,
-		ClientPublicKey:  req.ClientPublicKey,
-		Region:           req.RequestedRegion,
-		MinBandwidthMbps: req.MinBandwidthMbps,
-		MaxLatencyMs:     req.MaxLatencyMs,
-	})
+	conn, err := grpc.Dial(exitPeerAddr, utils.DialOption(utils.RolePeer))
 	if err != nil {
-		log.Printf("❌ Failed to fetch WireGuard info from Exit Peer %s: %v", chosen.PeerID, err)
 		return nil, err
 	}
+	defer conn.Close()
 
-	log.Printf("✅ WireGuard info received from exit peer %s: %s:%s",
-		chosen.PeerID, infoRes.EndpointIp, infoRes.EndpointPort)
-
-	return &pb.ExitPeerResponse{
-		PublicKey:    infoRes.PublicKey,
-		EndpointIp:   infoRes.EndpointIp,
-		EndpointPort: infoRes.EndpointPort,
-		AllowedIps:   infoRes.AllowedIps,
-		PeerId:       chosen.PeerID,
-		Region:       req.RequestedRegion,
-		ClientIp:     infoRes.ClientIp,
-	}, nil
+	ctx, cancel := context.WithTimeout(ctx, exitAttemptTimeout)
+	defer cancel()
+
+	return pb.NewExitPeerServiceClient(conn).GetWireGuardInfo(ctx, &pb.ExitPeerInfoRequest{
+		RequesterId:        req.RequesterId,
+		ClientPublicKey:    req.ClientPublicKey,
+		Region:             req.RequestedRegion,
+		MinBandwidthMbps:   req.MinBandwidthMbps,
+		MaxLatencyMs:       req.MaxLatencyMs,
+		SessionId:          req.SessionId,
+		RequesterRegion:    req.RequesterRegion,
+		ClientIdentityKey:  req.ClientIdentityKey,
+		ClientKeySignedAt:  req.ClientKeySignedAt,
+		ClientKeySignature: req.ClientKeySignature,
+		SuperId:            superID,
+	})
 }
 
 // client and super
@@ -203,65 +427,160 @@ func (s *SuperNodeServer) RequestExit(ctx context.Context, req *pb.ExitRequest)
 	log.Printf("📨 Exit request from Peer %s for region %s", req.PeerId, req.RequestedRegion)
 
 	// HACK: find the client public key locally
-	_, ok := s.registeredPeers[req.PeerId]
+	requester, ok := s.registeredPeers.Get(req.PeerId)
 	if !ok {
 		return nil, fmt.Errorf("unknown requesting peer %s", req.PeerId)
 	}
+	if err := checkClientKey(&requester, req); err != nil {
+		log.Printf("❌ Rejected exit request from peer %s: %v", req.PeerId, err)
+		return nil, fmt.Errorf("client key not verified: %w", err)
+	}
 
+	remoteReq := &pb.ExitPeerRequest{
+		RequesterId:        req.PeerId,
+		MinBandwidthMbps:   req.MinBandwidthMbps,
+		MaxLatencyMs:       req.MaxLatencyMs,
+		RequestedRegion:    req.RequestedRegion,
+		ClientPublicKey:    req.ClientPublicKey,
+		RequesterRegion:    requester.Region,
+		SessionId:          newSessionID(),
+		ClientIdentityKey:  req.ClientIdentityKey,
+		ClientKeySignedAt:  req.ClientKeySignedAt,
+		ClientKeySignature: req.ClientKeySignature,
+	}
+
+	var exitRes *pb.ExitPeerResponse
+	var superID, superAddr string
+	var err error
+
+	// Same region: serve from our own exit catalog, skipping the base and
+	// the super to super hop
+	if req.RequestedRegion == s.region {
+		exitRes, err = s.serveExit(ctx, remoteReq)
+		if err != nil {
+			log.Printf("🏠 No local exit for peer %s, asking sibling Super Nodes: %v", req.PeerId, err)
+		} else {
+			superID = s.nodeID
+		}
+	}
+
+	if exitRes == nil {
+		var server *pb.SuperNode
+		exitRes, server, err = s.requestRemoteExit(ctx, remoteReq)
+		if err != nil {
+			return nil, err
+		}
+		superID = server.NodeId
+		superAddr = fmt.Sprintf("%s:%s", server.Ip, server.Port)
+	}
+
+	sessionID := exitRes.SessionId
+	if sessionID == "" {
+		sessionID = remoteReq.SessionId
+	}
+	s.sessions.Start(&Session{
+		ID:            sessionID,
+		ClientID:      req.PeerId,
+		ClientKey:     req.ClientPublicKey,
+		ExitID:        exitRes.PeerId,
+		Region:        req.RequestedRegion,
+		BandwidthMbps: exitRes.GrantedBandwidthMbps,
+		SuperID:       superID,
+		SuperAddr:     superAddr,
+	})
+
+	// The exit only adds the client once it holds a ticket from us
+	if err := s.grantTicket(ctx, sessionID); err != nil {
+		log.Printf("❌ Exit %s did not accept the ticket for session %s: %v", exitRes.PeerId, sessionID, err)
+		s.endClientSession(ctx, sessionID, "exit ticket not accepted")
+		return nil, fmt.Errorf("exit %s did not accept the session ticket: %w", exitRes.PeerId, err)
+	}
+
+	config := &pb.WireguardConfig{
+		InterfacePrivateKey: "", // HACK: generate private key
+		InterfaceAddress:    exitRes.ClientIp,
+		Dns:                 "1.1.1.1",
+		PeerPublicKey:       exitRes.PublicKey,
+		PeerEndpoint:        fmt.Sprintf("%s:%s", exitRes.EndpointIp, exitRes.EndpointPort),
+		AllowedIps:          "0.0.0.0/0",
+		Keepalive:           25,
+		SessionId:           sessionID,
+		ExitPeerId:          exitRes.PeerId,
+		ExitIdentityKey:     exitRes.ExitIdentityKey,
+		ExitSignature:       exitRes.ExitSignature,
+	}
+
+	log.Printf("🎯 Prepared WireGuard config for peer %s to exit via %s | Session: %s", req.PeerId, exitRes.PeerId, sessionID)
+	return config, nil
+}
+
+// requestRemoteExit asks the base for the supers of the requested region,
+// other than this one, and requests an exit peer from them in turn.
+func (s *SuperNodeServer) requestRemoteExit(ctx context.Context, req *pb.ExitPeerRequest) (*pb.ExitPeerResponse, *pb.SuperNode, error) {
 	exitReq := &pb.ExitRegionRequest{
 		DesiredRegion:    req.RequestedRegion,
 		MinBandwidthMbps: req.MinBandwidthMbps,
 		MaxLatencyMs:     req.MaxLatencyMs,
-		Count:            1,
+		Count:            remoteCandidateCount,
 	}
 
 	superList, err := s.baseClient.RequestExitRegion(ctx, exitReq) //requesting to the local basenode for remote supernodes
 	if err != nil {
 		log.Printf("❌ Failed to request remote SuperNodes: %v", err)
-		return nil, err
+		return nil, nil, err
+	}
+
+	var nodes []*pb.SuperNode
+	for _, n := range superList.Nodes {
+		if n.NodeId != s.nodeID {
+			nodes = append(nodes, n)
+		}
 	}
-	if len(superList.Nodes) == 0 {
+	if len(nodes) == 0 {
 		log.Printf("No SuperNodes returened for region %s", req.RequestedRegion)
-		return nil, fmt.Errorf("no SuperNodes available for region %s", req.RequestedRegion)
+		return nil, nil, fmt.Errorf("no SuperNodes available for region %s", req.RequestedRegion)
 	}
 
-	chosen := superList.Nodes[0] // TODO: implement load balancing logic for fairer distribution
-	log.Printf("🛰 Chosen remote super: %s (%s:%s)", chosen.NodeId, chosen.Ip, chosen.Port)
-	addr := fmt.Sprintf("%s:%s", chosen.Ip, chosen.Port)
-	conn, err := grpc.Dial(addr, grpc.WithInsecure())
-	if err != nil {
-		log.Printf("❌ Failed to connect to remote SuperNode: %v", err)
-		return nil, err
+	if err := s.signExitRequest(req); err != nil {
+		return nil, nil, err
 	}
-	println("Connected to remote SuperNode")
-	defer conn.Close()
 
-	RemoteSuperNode := pb.NewSuperNodeServiceClient(conn)
+	candidates := s.remotes.orderRemoteSupers(nodes)
+	for i, chosen := range candidates {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			break
+		}
 
-	remoteReq := &pb.ExitPeerRequest{
-		RequesterId:      req.PeerId,
-		MinBandwidthMbps: req.MinBandwidthMbps,
-		MaxLatencyMs:     req.MaxLatencyMs,
-		RequestedRegion:  req.RequestedRegion,
-		ClientPublicKey:  req.ClientPublicKey,
+		log.Printf("🛰 Chosen remote super: %s (%s:%s) | success rate: %.2f",
+			chosen.NodeId, chosen.Ip, chosen.Port, s.remotes.SuccessRate(chosen.NodeId))
+		var exitRes *pb.ExitPeerResponse
+		exitRes, err = requestExitPeerFrom(ctx, chosen, req, len(candidates)-i)
+		s.remotes.Record(chosen.NodeId, err == nil)
+		if err == nil {
+			return exitRes, chosen, nil
+		}
+		log.Printf("❌ Failed to request exit peer from %s: %v", chosen.NodeId, err)
 	}
+	return nil, nil, fmt.Errorf("no remote SuperNode in %s could provide an exit: %w", req.RequestedRegion, err)
+}
 
-	exitRes, err := RemoteSuperNode.RequestExitPeer(ctx, remoteReq)
+// requestExitPeerFrom asks a remote super for an exit peer. When the caller
+// set a deadline and more candidates remain, the attempt gets half of the
+// time left, so a hanging super cannot use it all.
+func requestExitPeerFrom(ctx context.Context, node *pb.SuperNode, req *pb.ExitPeerRequest, attemptsLeft int) (*pb.ExitPeerResponse, error) {
+	addr := fmt.Sprintf("%s:%s", node.Ip, node.Port)
+	conn, err := grpc.Dial(addr, utils.DialOption(utils.RoleSuper))
 	if err != nil {
-		log.Printf("❌ Failed to request exit peer: %v", err)
 		return nil, err
 	}
+	defer conn.Close()
 
-	config := &pb.WireguardConfig{
-		InterfacePrivateKey: "", // HACK: generate private key
-		InterfaceAddress:    exitRes.ClientIp,
-		Dns:                 "1.1.1.1",
-		PeerPublicKey:       exitRes.PublicKey,
-		PeerEndpoint:        fmt.Sprintf("%s:%s", exitRes.EndpointIp, exitRes.EndpointPort),
-		AllowedIps:          "0.0.0.0/0",
-		Keepalive:           25,
+	if deadline, ok := ctx.Deadline(); ok && attemptsLeft > 1 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Until(deadline)/2)
+		defer cancel()
 	}
 
-	log.Printf("🎯 Prepared WireGuard config for peer %s to exit via %s", req.PeerId, exitRes.PeerId)
-	return config, nil
+	return pb.NewSuperNodeServiceClient(conn).RequestExitPeer(ctx, req)
 }
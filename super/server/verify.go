@@ -1,44 +1,66 @@
 package server
 
 import (
+	"Super_node/utils"
+	"context"
 	"crypto/ed25519"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"log"
 )
 
+var (
+	ErrInvalidSignature = errors.New("invalid signature")
+	ErrCertMismatch     = errors.New("TLS certificate does not match the claimed identity")
+)
+
 func verifyClientPeer(
 	peerID string,
 	region string,
 	os string,
 	natType string,
 	nonce string,
+	signedAt int64,
 	pubKeyBase64 string,
 	signatureBase64 string,
-) bool {
+) error {
 	// 1. Rebuild the signed message
-	msg := fmt.Sprintf("%s|%s|%s|%s|%s", peerID, region, os, natType, nonce)
+	msg := fmt.Sprintf("%s|%s|%s|%s|%s|%d", peerID, region, os, natType, nonce, signedAt)
 
 	// 2. Decode public key
 	pubKeyBytes, err := base64.StdEncoding.DecodeString(pubKeyBase64)
-	if err != nil {
-		log.Printf("❌ Failed to decode public key: %v", err)
-		return false
+	if err != nil || len(pubKeyBytes) != ed25519.PublicKeySize {
+		return fmt.Errorf("%w: malformed public key", ErrInvalidSignature)
 	}
 
 	// 3. Decode signature
 	sigBytes, err := base64.StdEncoding.DecodeString(signatureBase64)
 	if err != nil {
-		log.Printf("❌ Failed to decode signature: %v", err)
-		return false
+		return fmt.Errorf("%w: malformed signature", ErrInvalidSignature)
 	}
 
 	// 4. Verify signature
-	valid := ed25519.Verify(ed25519.PublicKey(pubKeyBytes), []byte(msg), sigBytes)
-	if !valid {
-		log.Printf("❌ Invalid signature for peer %s", peerID)
-	} else {
-		log.Printf("✅ Signature verified for peer %s", peerID)
+	if !ed25519.Verify(ed25519.PublicKey(pubKeyBytes), []byte(msg), sigBytes) {
+		return ErrInvalidSignature
+	}
+
+	log.Printf("✅ Signature verified for peer %s", peerID)
+	return nil
+}
+
+// checkCaller ensures the caller's TLS certificate was issued to a client
+// peer holding pubKeyBase64. Without mutual TLS it always passes.
+func checkCaller(ctx context.Context, pubKeyBase64 string) error {
+	id, ok := utils.PeerIdentity(ctx)
+	if !ok {
+		return nil
+	}
+	if id.Role != utils.RolePeer {
+		return fmt.Errorf("%w: %q is not a client peer certificate", ErrCertMismatch, id.Name)
+	}
+	if id.PublicKey != pubKeyBase64 {
+		return fmt.Errorf("%w: certificate %q carries a different key", ErrCertMismatch, id.Name)
 	}
-	return valid
+	return nil
 }
@@ -0,0 +1,187 @@
+package utils
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// Transport credentials used by every gRPC listener in this process, and
+// the CA and certificate every dial presents. They stay insecure until
+// LoadMutualTLS succeeds.
+var (
+	serverCreds credentials.TransportCredentials = insecure.NewCredentials()
+	clientPool  *x509.CertPool
+	clientCert  tls.Certificate
+)
+
+// LoadMutualTLS switches all gRPC links to mutual TLS. Both sides must
+// present a certificate issued by the network CA at caPath.
+//
+// Nodes are dialed by whatever address the registry holds, so the server
+// name is not checked; identity comes from the certificate's key instead
+// (see PeerIdentity).
+func LoadMutualTLS(caPath string, cert tls.Certificate) error {
+	caPEM, err := os.ReadFile(caPath)
+	if err != nil {
+		return fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return fmt.Errorf("no certificates found in %s", caPath)
+	}
+
+	serverCreds = credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+		MinVersion:   tls.VersionTLS13,
+	})
+	clientPool = pool
+	clientCert = cert
+	return nil
+}
+
+// verifyServer checks that the server certificate chains to the network CA
+// and carries role, so a certificate issued for another role cannot stand
+// in for the node being dialed.
+func verifyServer(pool *x509.CertPool, role string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errors.New("peer presented no certificate")
+		}
+		opts := x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: x509.NewCertPool(),
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		}
+		for _, c := range cs.PeerCertificates[1:] {
+			opts.Intermediates.AddCert(c)
+		}
+		cert := cs.PeerCertificates[0]
+		if _, err := cert.Verify(opts); err != nil {
+			return err
+		}
+		if len(cert.Subject.OrganizationalUnit) == 0 || cert.Subject.OrganizationalUnit[0] != role {
+			return fmt.Errorf("server certificate %q is not a %s certificate", cert.Subject.CommonName, role)
+		}
+		return nil
+	}
+}
+
+// ServerOption returns the credentials option for grpc.NewServer.
+func ServerOption() grpc.ServerOption {
+	return grpc.Creds(serverCreds)
+}
+
+// DialOption returns the credentials option for grpc.Dial to a node whose
+// certificate must carry role (RoleBase, RoleSuper or RolePeer).
+func DialOption(role string) grpc.DialOption {
+	if clientPool == nil {
+		return grpc.WithTransportCredentials(insecure.NewCredentials())
+	}
+	return grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+		Certificates:       []tls.Certificate{clientCert},
+		InsecureSkipVerify: true, // chain and role are verified below, host name is not
+		VerifyConnection:   verifyServer(clientPool, role),
+		MinVersion:         tls.VersionTLS13,
+	}))
+}
+
+// Roles a node certificate can carry in its organizational unit.
+const (
+	RoleBase  = "base"
+	RoleSuper = "super"
+	RolePeer  = "peer"
+)
+
+// Identity is who the verified client certificate on a connection says the
+// caller is.
+type Identity struct {
+	Name      string
+	Role      string
+	PublicKey string // base64 ed25519 key, empty for other key types
+}
+
+// PeerIdentity returns the identity from the verified client certificate
+// on ctx. ok is false when the link is not mutual TLS.
+func PeerIdentity(ctx context.Context) (Identity, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return Identity{}, false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.PeerCertificates) == 0 {
+		return Identity{}, false
+	}
+
+	cert := info.State.PeerCertificates[0]
+	id := Identity{Name: cert.Subject.CommonName}
+	if len(cert.Subject.OrganizationalUnit) > 0 {
+		id.Role = cert.Subject.OrganizationalUnit[0]
+	}
+	if pub, ok := cert.PublicKey.(ed25519.PublicKey); ok {
+		id.PublicKey = base64.StdEncoding.EncodeToString(pub)
+	}
+	return id, true
+}
+
+// RequireRole returns an interceptor that only lets callers whose
+// certificate carries role use the given services (full names, e.g.
+// "dvpn.BaseFederationService") or single methods (e.g.
+// "dvpn.SuperNodeService/EndSession"). It is a no-op without mutual TLS.
+func RequireRole(role string, services ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		for _, svc := range services {
+			if info.FullMethod != "/"+svc && !strings.HasPrefix(info.FullMethod, "/"+svc+"/") {
+				continue
+			}
+			if id, ok := PeerIdentity(ctx); ok && id.Role != role {
+				return nil, status.Errorf(codes.PermissionDenied, "%s requires a %s certificate, caller %q has role %q", info.FullMethod, role, id.Name, id.Role)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// LoadMutualTLSWithKey is LoadMutualTLS for a certificate issued to this
+// node's ed25519 identity key, so the key pair never leaves .keys/.
+func LoadMutualTLSWithKey(caPath, certPath string, priv ed25519.PrivateKey) error {
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return fmt.Errorf("failed to read node certificate: %w", err)
+	}
+	block, _ := pem.Decode(certPEM)
+	if block == nil {
+		return fmt.Errorf("no certificate found in %s", certPath)
+	}
+	leaf, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return err
+	}
+
+	pub, ok := leaf.PublicKey.(ed25519.PublicKey)
+	if !ok || !pub.Equal(priv.Public()) {
+		return fmt.Errorf("certificate %s was not issued for this node's identity key", certPath)
+	}
+
+	return LoadMutualTLS(caPath, tls.Certificate{
+		Certificate: [][]byte{block.Bytes},
+		PrivateKey:  priv,
+		Leaf:        leaf,
+	})
+}